@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bookmark is a directory in the hotlist. key is the digit that jumps to it
+// with Alt, or "" if none is assigned.
+type bookmark struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Key  string `json:"key,omitempty"`
+}
+
+// configDir returns the directory of the configuration files,
+// $XDG_CONFIG_HOME/min-commander
+func configDir() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "min-commander"), nil
+}
+
+func bookmarksFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bookmarks.json"), nil
+}
+
+// loadBookmarks reads the hotlist
+func (m *model) loadBookmarks() error {
+	path, err := bookmarksFile()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var bookmarks []bookmark
+	if err := json.Unmarshal(data, &bookmarks); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	m.bookmarks = bookmarks
+	return nil
+}
+
+// saveBookmarks writes the hotlist, reporting errors in the status line
+func (m *model) saveBookmarks() {
+	path, err := bookmarksFile()
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(m.bookmarks, "", "  "); err == nil {
+			err = writeFileAtomic(path, data)
+		}
+	}
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Cannot save bookmarks: %v", err)
+	}
+}
+
+// jumpToBookmark loads the bookmark with the given key into the active panel
+func (m *model) jumpToBookmark(key string) tea.Cmd {
+	for _, b := range m.bookmarks {
+		if b.Key == key {
+			return m.goToBookmark(b)
+		}
+	}
+	m.statusMsg = fmt.Sprintf("No bookmark on Alt+%s", key)
+	return nil
+}
+
+func (m *model) goToBookmark(b bookmark) tea.Cmd {
+	if info, err := os.Stat(b.Path); err != nil || !info.IsDir() {
+		m.statusMsg = fmt.Sprintf("Bookmark %s: %s is not a directory", b.Name, b.Path)
+		return nil
+	}
+	p := &m.panels[m.activePanel]
+	p.closeSearch()
+	p.trash = nil
+	m.statusMsg = ""
+	return m.changeDir(m.activePanel, b.Path, "")
+}
+
+// bookmarkItems renders the hotlist for the popup
+func (m *model) bookmarkItems() []string {
+	items := make([]string, len(m.bookmarks))
+	for i, b := range m.bookmarks {
+		key := "   "
+		if b.Key != "" {
+			key = "[" + b.Key + "]"
+		}
+		items[i] = fmt.Sprintf("%s %-16s %s", key, b.Name, b.Path)
+	}
+	return items
+}
+
+// bookmarkHints are the editing keys listed in the hotlist
+var bookmarkHints = []keyHint{
+	{[]action{actionAdd}, "Add"},
+	{[]action{actionRename}, "Rename"},
+	{[]action{actionDelete}, "Remove"},
+	{[]action{actionMoveUp, actionMoveDown}, "Move"},
+}
+
+// openBookmarks shows the hotlist
+func (m *model) openBookmarks() {
+	m.popup = newPopup("Bookmarks", m.bookmarkItems(), func(m *model, index int) tea.Cmd {
+		return m.goToBookmark(m.bookmarks[index])
+	})
+	m.popup.help = m.keys.hints(scopePopup, []keyHint{{[]action{actionOpen}, "Go"}}) + " | " +
+		m.keys.hints(scopeBookmarks, bookmarkHints) + " | 0-9: Key"
+	m.popup.onKey = (*model).updateBookmarks
+}
+
+// updateBookmarks handles the editing keys of the hotlist popup
+func (m *model) updateBookmarks(pp *popup, msg tea.KeyMsg) (bool, tea.Cmd) {
+	key := msg.String()
+	var current *bookmark
+	if pp.cursor < len(m.bookmarks) {
+		current = &m.bookmarks[pp.cursor]
+	}
+
+	a := m.keys.lookupIn(scopeBookmarks, key)
+	switch {
+	case a == actionAdd:
+		m.addBookmarkPrompt(pp)
+	case a == actionRename && current != nil:
+		index := pp.cursor
+		m.prompt = newPrompt("Bookmark name:", current.Name, func(m *model, name string) tea.Cmd {
+			if name = strings.TrimSpace(name); name != "" {
+				m.bookmarks[index].Name = name
+				m.bookmarksChanged(pp)
+			}
+			return nil
+		})
+	case len(key) == 1 && key >= "0" && key <= "9" && current != nil:
+		assign := key
+		if current.Key == key {
+			// Pressing the assigned key again removes it
+			assign = ""
+		}
+		for i := range m.bookmarks {
+			if m.bookmarks[i].Key == key {
+				m.bookmarks[i].Key = ""
+			}
+		}
+		current.Key = assign
+		m.bookmarksChanged(pp)
+	case a == actionDelete && current != nil:
+		index := pp.cursor
+		m.dialog = newConfirmDialog("Remove bookmark", fmt.Sprintf("Remove %s (%s)?", current.Name, current.Path), func(m *model) tea.Cmd {
+			m.bookmarks = append(m.bookmarks[:index], m.bookmarks[index+1:]...)
+			m.bookmarksChanged(pp)
+			return nil
+		})
+	case a == actionMoveUp && current != nil && pp.cursor > 0:
+		i := pp.cursor
+		m.bookmarks[i-1], m.bookmarks[i] = m.bookmarks[i], m.bookmarks[i-1]
+		pp.cursor--
+		m.bookmarksChanged(pp)
+	case a == actionMoveDown && current != nil && pp.cursor < len(m.bookmarks)-1:
+		i := pp.cursor
+		m.bookmarks[i+1], m.bookmarks[i] = m.bookmarks[i], m.bookmarks[i+1]
+		pp.cursor++
+		m.bookmarksChanged(pp)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// addBookmarkPrompt asks for the name of a bookmark for the active panel's
+// directory
+func (m *model) addBookmarkPrompt(pp *popup) {
+	path := m.panels[m.activePanel].path
+	m.prompt = newPrompt("Bookmark "+path+" as:", filepath.Base(path), func(m *model, name string) tea.Cmd {
+		if name = strings.TrimSpace(name); name == "" {
+			name = path
+		}
+		m.bookmarks = append(m.bookmarks, bookmark{Name: name, Path: path})
+		if pp != nil {
+			pp.cursor = len(m.bookmarks) - 1
+		}
+		m.statusMsg = fmt.Sprintf("Bookmarked %s", path)
+		m.bookmarksChanged(pp)
+		return nil
+	})
+}
+
+// bookmarksChanged saves the hotlist and updates the popup showing it
+func (m *model) bookmarksChanged(pp *popup) {
+	if pp != nil {
+		pp.setItems(m.bookmarkItems())
+	}
+	m.saveBookmarks()
+}
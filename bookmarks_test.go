@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBookmarks_AddAssignAndJump(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	project := t.TempDir()
+	m := initialModel()
+	m.panels[0].path = project
+
+	// Add with the suggested name, assign key 3
+	m = pressKeys(t, m, "b", "a", "enter", "3", "esc")
+	if len(m.bookmarks) != 1 || m.bookmarks[0].Path != project || m.bookmarks[0].Key != "3" {
+		t.Fatalf("Unexpected bookmarks %+v", m.bookmarks)
+	}
+	if m.bookmarks[0].Name != filepath.Base(project) {
+		t.Errorf("Expected name %s, got %s", filepath.Base(project), m.bookmarks[0].Name)
+	}
+
+	m.panels[0].path = "/"
+	m = pressKeys(t, m, "alt+3")
+	if m.panels[0].path != project {
+		t.Errorf("Expected Alt+3 to go to %s, got %s", project, m.panels[0].path)
+	}
+	m = pressKeys(t, m, "alt+4")
+	if !strings.Contains(m.statusMsg, "No bookmark") {
+		t.Errorf("Expected a message for an unassigned key, got %q", m.statusMsg)
+	}
+
+	// The hotlist is saved and can be loaded again
+	loaded := initialModel()
+	if err := loaded.loadBookmarks(); err != nil {
+		t.Fatalf("Failed to load bookmarks: %v", err)
+	}
+	if len(loaded.bookmarks) != 1 || loaded.bookmarks[0] != m.bookmarks[0] {
+		t.Errorf("Unexpected loaded bookmarks %+v", loaded.bookmarks)
+	}
+}
+
+func TestBookmarks_EditInPopup(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := initialModel()
+	m.bookmarks = []bookmark{
+		{Name: "logs", Path: "/var/log", Key: "1"},
+		{Name: "tmp", Path: "/tmp"},
+		{Name: "etc", Path: "/etc"},
+	}
+
+	// Key 1 moves from logs to tmp, etc moves to the top
+	m = pressKeys(t, m, "b", "down", "1", "down", "shift+up", "shift+up")
+	names := []string{}
+	for _, b := range m.bookmarks {
+		names = append(names, b.Name+b.Key)
+	}
+	if got := strings.Join(names, ","); got != "etc,logs,tmp1" {
+		t.Errorf("Expected etc,logs,tmp1, got %s", got)
+	}
+	if m.popup.cursor != 0 || !strings.Contains(m.popup.items[2], "[1]") {
+		t.Errorf("Popup out of sync: cursor %d, items %v", m.popup.cursor, m.popup.items)
+	}
+
+	// Rename and remove
+	m = pressKeys(t, m, "n")
+	m.prompt.value = []rune("config")
+	m = pressKeys(t, m, "enter")
+	if m.bookmarks[0].Name != "config" {
+		t.Errorf("Expected renamed bookmark, got %s", m.bookmarks[0].Name)
+	}
+	m = pressKeys(t, m, "x", "y")
+	if len(m.bookmarks) != 2 || m.bookmarks[0].Name != "logs" {
+		t.Errorf("Expected the first bookmark to be removed, got %+v", m.bookmarks)
+	}
+	if m.popup == nil || len(m.popup.items) != 2 {
+		t.Error("Expected the popup to stay open with two items")
+	}
+
+	// Enter goes to the bookmark and closes the popup
+	m = pressKeys(t, m, "down", "enter")
+	if m.popup != nil || m.panels[0].path != "/tmp" {
+		t.Errorf("Expected to be in /tmp, got %s", m.panels[0].path)
+	}
+}
+
+func TestBookmarks_MissingDirectory(t *testing.T) {
+	m := initialModel()
+	start := m.panels[0].path
+	m.bookmarks = []bookmark{{Name: "gone", Path: filepath.Join(t.TempDir(), "gone"), Key: "1"}}
+	m = pressKeys(t, m, "alt+1")
+	if m.panels[0].path != start || !strings.Contains(m.statusMsg, "not a directory") {
+		t.Errorf("Expected to stay in %s with an error, got %s (%q)", start, m.panels[0].path, m.statusMsg)
+	}
+}
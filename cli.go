@@ -0,0 +1,135 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+//go:embed VERSION
+var versionFile string
+
+// version is the release of the program, from the VERSION file
+func version() string {
+	return strings.TrimSpace(versionFile)
+}
+
+// options are the command-line arguments
+type options struct {
+	left, right string // Start directories, "" for the default
+	configPath  string // Configuration file, "" for the default
+	lastDirFile string // File to write the final directory to, "" for none
+	initShell   string // Shell to print the wrapper function for, "" for none
+	version     bool
+	noColor     bool
+	showHidden  bool
+}
+
+// parseArgs parses the command-line arguments without the program name.
+// Usage and errors are written to output.
+func parseArgs(args []string, output io.Writer) (*options, error) {
+	opts := &options{}
+	flags := flag.NewFlagSet("min-commander", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.configPath, "config", "", "read the configuration from `file`")
+	flags.StringVar(&opts.lastDirFile, "print-last-dir", "", "write the active panel's directory to `file` on exit")
+	flags.StringVar(&opts.initShell, "init", "", "print the wrapper function for `shell` (bash, zsh or fish) and exit")
+	flags.BoolVar(&opts.version, "version", false, "print the version and exit")
+	flags.BoolVar(&opts.noColor, "no-color", false, "do not use colours")
+	flags.BoolVar(&opts.showHidden, "show-hidden", false, "show hidden files in both panels")
+	flags.Usage = func() {
+		fmt.Fprintf(output, "Usage: min-commander [options] [left-dir [right-dir]]\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+
+	// Flags may follow the directories
+	var paths []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		paths = append(paths, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if opts.initShell != "" {
+		if _, err := shellInit(opts.initShell); err != nil {
+			return nil, err
+		}
+	}
+	if len(paths) > 2 {
+		return nil, fmt.Errorf("too many directories: %s", strings.Join(paths[2:], " "))
+	}
+
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", path)
+		}
+		if i == 0 {
+			opts.left = abs
+		} else {
+			opts.right = abs
+		}
+	}
+	return opts, nil
+}
+
+// loadConfig reads the configuration file given with --config, or the
+// default one. Unlike the default, a given file must exist.
+func (opts *options) loadConfig() (*config, error) {
+	if opts.configPath == "" {
+		return loadConfigFile()
+	}
+	data, err := os.ReadFile(opts.configPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(opts.configPath, data)
+}
+
+// apply sets up the model from the options, after the configuration
+func (opts *options) apply(m *model) {
+	if opts.left != "" {
+		m.panels[0].path = opts.left
+	}
+	if opts.right != "" {
+		m.panels[1].path = opts.right
+	}
+	if opts.showHidden {
+		m.panels[0].showHidden = true
+		m.panels[1].showHidden = true
+	}
+	if opts.noColor {
+		// Bold and reverse video still mark the cursor and the selection
+		applyTheme(themes["mono"])
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// writeLastDir writes the active panel's directory for a shell wrapper to
+// change to
+func (m model) writeLastDir(path string) error {
+	return os.WriteFile(path, []byte(m.panels[m.activePanel].path+"\n"), 0644)
+}
+
+// exitCode is the status for an argument error, flag.ErrHelp exits cleanly
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	file := filepath.Join(left, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    options
+		wantErr string
+	}{
+		{"No arguments", nil, options{}, ""},
+		{"Left only", []string{left}, options{left: left}, ""},
+		{"Both with flags after", []string{left, right, "--show-hidden", "--no-color"},
+			options{left: left, right: right, showHidden: true, noColor: true}, ""},
+		{"Flags between", []string{"--config", "c.toml", left, "--print-last-dir=/tmp/x", right},
+			options{left: left, right: right, configPath: "c.toml", lastDirFile: "/tmp/x"}, ""},
+		{"Version", []string{"-version"}, options{version: true}, ""},
+		{"Too many", []string{left, right, left}, options{}, "too many directories"},
+		{"Not a directory", []string{file}, options{}, "is not a directory"},
+		{"Missing", []string{filepath.Join(left, "gone")}, options{}, "is not a directory"},
+		{"Unknown flag", []string{"--colour"}, options{}, "flag provided but not defined"},
+		{"Missing value", []string{"--config"}, options{}, "flag needs an argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			opts, err := parseArgs(tt.args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				if exitCode(err) != 2 {
+					t.Errorf("Expected exit code 2 for %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if *opts != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *opts)
+			}
+		})
+	}
+}
+
+func TestParseArgs_RelativeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	t.Chdir(dir)
+	opts, err := parseArgs([]string{"sub"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.left != filepath.Join(dir, "sub") {
+		t.Errorf("Expected an absolute path, got %s", opts.left)
+	}
+}
+
+func TestParseArgs_Help(t *testing.T) {
+	var out bytes.Buffer
+	_, err := parseArgs([]string{"--help"}, &out)
+	if !errors.Is(err, flag.ErrHelp) || exitCode(err) != 0 {
+		t.Errorf("Expected flag.ErrHelp with exit code 0, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Usage: min-commander") || !strings.Contains(out.String(), "-print-last-dir") {
+		t.Errorf("Expected the usage, got %q", out.String())
+	}
+}
+
+func TestVersion(t *testing.T) {
+	data, err := os.ReadFile("VERSION")
+	if err != nil {
+		t.Fatalf("Failed to read VERSION: %v", err)
+	}
+	if version() == "" || version() != strings.TrimSpace(string(data)) {
+		t.Errorf("Expected version %q, got %q", strings.TrimSpace(string(data)), version())
+	}
+}
+
+func TestOptions_Apply(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	m := initialModel()
+	cfg, err := parseConfig("config.toml", []byte("[panels]\nleft = '/'\nright = '/'\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	m.applyConfig(cfg)
+
+	// Directories on the command line win over the configuration
+	opts := &options{left: left, showHidden: true}
+	opts.apply(&m)
+	if m.panels[0].path != left || m.panels[1].path != "/" {
+		t.Errorf("Unexpected panel paths %s and %s", m.panels[0].path, m.panels[1].path)
+	}
+	if !m.panels[0].showHidden || !m.panels[1].showHidden {
+		t.Error("Expected hidden files in both panels")
+	}
+
+	m.panels[1].path = right
+	m.activePanel = 1
+	file := filepath.Join(t.TempDir(), "lastdir")
+	if err := m.writeLastDir(file); err != nil {
+		t.Fatalf("Failed to write last directory: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(data) != right+"\n" {
+		t.Errorf("Expected %s, got %q", right, data)
+	}
+}
+
+func TestOptions_LoadConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "custom.toml")
+	opts := &options{configPath: path}
+	if _, err := opts.loadConfig(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected an error for a missing --config file, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("theme = 'mono'\nbogus = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if _, err := opts.loadConfig(); err == nil || !strings.HasPrefix(err.Error(), path+":2:") {
+		t.Errorf("Expected an error on line 2 of %s, got %v", path, err)
+	}
+	if _, err := (&options{}).loadConfig(); err != nil {
+		t.Errorf("Expected defaults without a file, got %v", err)
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// listingMode selects the columns shown next to the names in a panel
+type listingMode int
+
+const (
+	listingFull   listingMode = iota // Size and modification time
+	listingBrief                     // Names only
+	listingCustom                    // The panel's own column list
+)
+
+func (l listingMode) String() string {
+	switch l {
+	case listingBrief:
+		return "brief"
+	case listingCustom:
+		return "custom"
+	}
+	return "full"
+}
+
+// minNameWidth is the narrowest name column before columns are dropped
+const minNameWidth = 8
+
+var columnHeaderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+
+// column is a field of the panel listing next to the name
+type column struct {
+	title string
+	width int
+	right bool // Right-aligned
+	value func(entry fs.FileEntry) string
+}
+
+// columns are the available columns by the name used in column lists
+var columns = map[string]column{
+	"size":  {title: "Size", width: 6, right: true, value: formatSizeColumn},
+	"mtime": {title: "Modified", width: 12, value: func(e fs.FileEntry) string { return formatModTime(e.ModTime, time.Now()) }},
+	"mode":  {title: "Mode", width: 10, value: func(e fs.FileEntry) string { return metadataOnly(e, formatMode(e.Mode)) }},
+	"owner": {title: "Owner", width: 8, value: func(e fs.FileEntry) string { return metadataOnly(e, userName(e.Uid)) }},
+	"group": {title: "Group", width: 8, value: func(e fs.FileEntry) string { return metadataOnly(e, groupName(e.Gid)) }},
+}
+
+var (
+	fullColumns          = []string{"size", "mtime"}
+	defaultCustomColumns = []string{"size", "mtime", "mode", "owner", "group"}
+)
+
+// parseColumns parses a comma or space separated column list. "name" is
+// accepted for readability, the name column is always shown first.
+func parseColumns(spec string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.FieldsFunc(strings.ToLower(spec), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if name == "name" {
+			continue
+		}
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// columnNames returns the columns the panel's listing mode shows
+func (p *panel) columnNames() []string {
+	switch p.listing {
+	case listingBrief:
+		return nil
+	case listingCustom:
+		if p.customColumns == nil {
+			return defaultCustomColumns
+		}
+		return p.customColumns
+	}
+	return fullColumns
+}
+
+// layoutColumns fits the named columns into width, dropping columns from the
+// right until the name column is at least minNameWidth wide. The width of the
+// name column includes the icon.
+func layoutColumns(names []string, width int) (cols []column, nameWidth int) {
+	for _, name := range names {
+		cols = append(cols, columns[name])
+	}
+	for {
+		// One cell for the mark and a space before each column
+		nameWidth = width - 1
+		for _, col := range cols {
+			nameWidth -= col.width + 1
+		}
+		if nameWidth >= minNameWidth || len(cols) == 0 {
+			return cols, max(nameWidth, 0)
+		}
+		cols = cols[:len(cols)-1]
+	}
+}
+
+// renderColumnHeader renders the column titles aligned with the entries
+func renderColumnHeader(cols []column, nameWidth int) string {
+	var b strings.Builder
+	b.WriteString(" " + padCell("Name", nameWidth, false))
+	for _, col := range cols {
+		b.WriteString(" " + padCell(col.title, col.width, col.right))
+	}
+	return columnHeaderStyle.Render(b.String())
+}
+
+// renderEntryLine renders the mark, icon, name and columns of an entry in
+// style. The characters of the name at the rune indices in match are
+// highlighted.
+func renderEntryLine(entry fs.FileEntry, marked bool, cols []column, nameWidth int, match []int, style lipgloss.Style) string {
+	var b strings.Builder
+	if marked {
+		b.WriteString("*")
+	} else {
+		b.WriteString(" ")
+	}
+	name, highlight := nameCell(entry, nameWidth, match)
+	b.WriteString(name)
+	for _, col := range cols {
+		b.WriteString(" " + padCell(ansi.Truncate(col.value(entry), col.width, ""), col.width, col.right))
+	}
+	// The name cell follows the single mark cell
+	for i := range highlight {
+		highlight[i]++
+	}
+	return renderHighlighted(b.String(), highlight, style)
+}
+
+// renderHighlighted renders line in style, with the runes at the indices in
+// highlight (ascending) also in matchStyle
+func renderHighlighted(line string, highlight []int, style lipgloss.Style) string {
+	if len(highlight) == 0 {
+		return style.Render(line)
+	}
+	var b strings.Builder
+	var run []rune
+	runHighlighted := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if runHighlighted {
+			b.WriteString(style.Inherit(matchStyle).Render(string(run)))
+		} else {
+			b.WriteString(style.Render(string(run)))
+		}
+		run = run[:0]
+	}
+	next := 0
+	for i, r := range []rune(line) {
+		on := next < len(highlight) && highlight[next] == i
+		if on {
+			next++
+		}
+		if on != runHighlighted {
+			flush()
+			runHighlighted = on
+		}
+		run = append(run, r)
+	}
+	flush()
+	return b.String()
+}
+
+// nameCell renders the icon and name, plus the target for links, in exactly
+// width cells. The name is shortened before the link target is shown. It
+// also returns the rune indices in the cell of the name characters in match.
+func nameCell(entry fs.FileEntry, width int, match []int) (string, []int) {
+	var icon string
+	switch {
+	case entry.Link != fs.NoLink:
+		icon = "🔗 "
+	case entry.IsDir:
+		icon = "📁 "
+	default:
+		icon = "📄 "
+	}
+	if width < ansi.StringWidth(icon) {
+		return strings.Repeat(" ", width), nil
+	}
+
+	avail := width - ansi.StringWidth(icon)
+	text, sources := truncateNameSources(entry.Name, avail)
+	var highlight []int
+	if len(match) > 0 {
+		matched := make(map[int]bool, len(match))
+		for _, i := range match {
+			matched[i] = true
+		}
+		offset := len([]rune(icon))
+		for i, source := range sources {
+			if matched[source] {
+				highlight = append(highlight, offset+i)
+			}
+		}
+	}
+	if entry.Link != fs.NoLink {
+		suffix := " -> " + entry.LinkTarget
+		if entry.Link == fs.BrokenLink {
+			suffix += " (broken)"
+		}
+		text += ansi.Truncate(suffix, avail-ansi.StringWidth(text), "…")
+	}
+	return padCell(icon+text, width, false), highlight
+}
+
+// truncateName shortens name to width cells, keeping the extension where
+// possible: "a-very-long-name.txt" becomes "a-very-l~.txt"
+func truncateName(name string, width int) string {
+	text, _ := truncateNameSources(name, width)
+	return text
+}
+
+// truncateNameSources implements truncateName and also returns, for each
+// rune of the result, its rune index in name, or -1 for the "~"
+func truncateNameSources(name string, width int) (string, []int) {
+	runes := []rune(name)
+	if ansi.StringWidth(name) <= width {
+		return name, runeRange(0, len(runes))
+	}
+	if width <= 1 {
+		text := ansi.Truncate(name, width, "")
+		return text, runeRange(0, len([]rune(text)))
+	}
+	ext := filepath.Ext(name)
+	if ext == name || ansi.StringWidth(ext) > width/2 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	head := ansi.Truncate(base, width-1-ansi.StringWidth(ext), "")
+	extRunes := len([]rune(ext))
+	sources := runeRange(0, len([]rune(head)))
+	sources = append(sources, -1)
+	sources = append(sources, runeRange(len(runes)-extRunes, len(runes))...)
+	return head + "~" + ext, sources
+}
+
+// runeRange returns the indices from start up to end
+func runeRange(start, end int) []int {
+	indices := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// padCell pads s with spaces to width cells
+func padCell(s string, width int, right bool) string {
+	padding := width - ansi.StringWidth(s)
+	if padding <= 0 {
+		return s
+	}
+	if right {
+		return strings.Repeat(" ", padding) + s
+	}
+	return s + strings.Repeat(" ", padding)
+}
+
+// metadataOnly hides value for entries without file metadata, such as
+// trash items
+func metadataOnly(entry fs.FileEntry, value string) string {
+	if entry.ModTime.IsZero() {
+		return ""
+	}
+	return value
+}
+
+// formatSizeColumn shows <DIR> for directories and a compact size otherwise
+func formatSizeColumn(entry fs.FileEntry) string {
+	switch {
+	case entry.IsDir:
+		return "<DIR>"
+	case entry.Link == fs.BrokenLink:
+		return ""
+	}
+	return formatSizeShort(entry.Size)
+}
+
+// formatSizeShort formats a size in at most 6 cells, e.g. 512, 1.5K or 23M
+func formatSizeShort(size int64) string {
+	if size < 1024 {
+		return strconv.FormatInt(size, 10)
+	}
+	value := float64(size)
+	for _, unit := range []string{"K", "M", "G", "T", "P"} {
+		value /= 1024
+		if value < 1024 || unit == "P" {
+			if value < 10 {
+				return fmt.Sprintf("%.1f%s", value, unit)
+			}
+			return fmt.Sprintf("%.0f%s", value, unit)
+		}
+	}
+	return ""
+}
+
+// formatModTime formats like ls: the time for the last six months, the year
+// for older or future dates
+func formatModTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if t.After(now.AddDate(0, -6, 0)) && !t.After(now) {
+		return t.Format("Jan _2 15:04")
+	}
+	return t.Format("Jan _2  2006")
+}
+
+// formatMode formats permissions like ls, e.g. drwxr-xr-x or -rwsr-xr-x
+func formatMode(mode os.FileMode) string {
+	b := []byte("----------")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		b[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b[i+1] = rwx[i]
+		}
+	}
+	special := func(i int, set bool, execChar, noExecChar byte) {
+		if !set {
+			return
+		}
+		if b[i] == 'x' {
+			b[i] = execChar
+		} else {
+			b[i] = noExecChar
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's', 'S')
+	special(6, mode&os.ModeSetgid != 0, 's', 'S')
+	special(9, mode&os.ModeSticky != 0, 't', 'T')
+	return string(b)
+}
+
+// User and group names by id, looked up once per id
+var (
+	userNames  = map[int]string{}
+	groupNames = map[int]string{}
+)
+
+func userName(uid int) string {
+	name, ok := userNames[uid]
+	if !ok {
+		name = strconv.Itoa(uid)
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		userNames[uid] = name
+	}
+	return name
+}
+
+func groupName(gid int) string {
+	name, ok := groupNames[gid]
+	if !ok {
+		name = strconv.Itoa(gid)
+		if g, err := user.LookupGroupId(name); err == nil {
+			name = g.Name
+		}
+		groupNames[gid] = name
+	}
+	return name
+}
+
+// cycleListing switches the active panel to the next listing mode
+func (m *model) cycleListing() {
+	p := &m.panels[m.activePanel]
+	p.listing = (p.listing + 1) % 3
+	m.statusMsg = fmt.Sprintf("Listing: %s", p.listing)
+}
+
+// openColumnsPrompt asks for the column list of the custom listing mode
+func (m *model) openColumnsPrompt() {
+	p := &m.panels[m.activePanel]
+	initial := strings.Join(p.columnNames(), ",")
+	if p.listing != listingCustom {
+		initial = strings.Join(defaultCustomColumns, ",")
+		if p.customColumns != nil {
+			initial = strings.Join(p.customColumns, ",")
+		}
+	}
+	m.prompt = newPrompt("Columns (size,mtime,mode,owner,group):", initial, func(m *model, value string) tea.Cmd {
+		names, err := parseColumns(value)
+		if err != nil {
+			m.statusMsg = err.Error()
+			return nil
+		}
+		p := &m.panels[m.activePanel]
+		p.customColumns = names
+		p.listing = listingCustom
+		m.statusMsg = "Listing: custom"
+		return nil
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestTruncateName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		width    int
+		expected string
+	}{
+		{"short.txt", 20, "short.txt"},
+		{"a-very-long-name.txt", 13, "a-very-l~.txt"},
+		{"no-extension-at-all", 10, "no-extens~"},
+		{"name.verylongextension", 10, "name.very~"},
+		{".bashrc", 5, ".bas~"},
+		{"x", 0, ""},
+	}
+	for _, tc := range testCases {
+		if got := truncateName(tc.name, tc.width); got != tc.expected {
+			t.Errorf("truncateName(%q, %d) = %q, expected %q", tc.name, tc.width, got, tc.expected)
+		}
+	}
+}
+
+func TestFormatMode(t *testing.T) {
+	testCases := map[os.FileMode]string{
+		0644:                              "-rw-r--r--",
+		os.ModeDir | 0755:                 "drwxr-xr-x",
+		os.ModeSymlink | 0777:             "lrwxrwxrwx",
+		os.ModeSetuid | 0755:              "-rwsr-xr-x",
+		os.ModeSetgid | 0640:              "-rw-r-S---",
+		os.ModeDir | os.ModeSticky | 0777: "drwxrwxrwt",
+	}
+	for mode, expected := range testCases {
+		if got := formatMode(mode); got != expected {
+			t.Errorf("formatMode(%v) = %q, expected %q", mode, got, expected)
+		}
+	}
+}
+
+func TestFormatSizeShort(t *testing.T) {
+	testCases := map[int64]string{
+		0:         "0",
+		1023:      "1023",
+		1536:      "1.5K",
+		20 * 1024: "20K",
+		5 << 30:   "5.0G",
+		999 << 20: "999M",
+	}
+	for size, expected := range testCases {
+		if got := formatSizeShort(size); got != expected {
+			t.Errorf("formatSizeShort(%d) = %q, expected %q", size, got, expected)
+		}
+	}
+}
+
+func TestFormatModTime(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	if got := formatModTime(now.Add(-time.Hour), now); got != "Oct 17 11:00" {
+		t.Errorf("Recent time: got %q", got)
+	}
+	if got := formatModTime(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), now); got != "Mar  5  2024" {
+		t.Errorf("Old time: got %q", got)
+	}
+	if got := formatModTime(time.Time{}, now); got != "" {
+		t.Errorf("Zero time: got %q", got)
+	}
+}
+
+func TestLayoutColumns_DropsColumnsWhenNarrow(t *testing.T) {
+	cols, nameWidth := layoutColumns(defaultCustomColumns, 80)
+	if len(cols) != len(defaultCustomColumns) {
+		t.Fatalf("All columns should fit in 80 cells, got %d", len(cols))
+	}
+
+	cols, nameWidth = layoutColumns(defaultCustomColumns, 25)
+	if nameWidth < minNameWidth {
+		t.Errorf("Name column too narrow: %d", nameWidth)
+	}
+	if len(cols) != 1 || cols[0].title != "Size" {
+		t.Errorf("Expected only the size column at 25 cells, got %d columns", len(cols))
+	}
+}
+
+func TestRenderEntryLine_ExactWidth(t *testing.T) {
+	cols, nameWidth := layoutColumns(fullColumns, 38)
+	entries := []fs.FileEntry{
+		{Name: "dir", IsDir: true, ModTime: time.Now()},
+		{Name: "a-file-with-a-name-far-too-long-for-the-panel.tar.gz", Size: 123456, ModTime: time.Now()},
+		{Name: "link", Link: fs.BrokenLink, LinkTarget: "/nowhere/at/all"},
+	}
+	for _, entry := range entries {
+		line := renderEntryLine(entry, false, cols, nameWidth, nil, lipgloss.NewStyle())
+		if width := ansi.StringWidth(line); width != 38 {
+			t.Errorf("%s: expected 38 cells, got %d: %q", entry.Name, width, line)
+		}
+	}
+	if line := renderEntryLine(entries[0], true, cols, nameWidth, nil, lipgloss.NewStyle()); !strings.HasPrefix(line, "*") || !strings.Contains(line, "<DIR>") {
+		t.Errorf("Expected marked directory line, got %q", line)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	names, err := parseColumns("name, Size,mode owner")
+	if err != nil {
+		t.Fatalf("parseColumns failed: %v", err)
+	}
+	if strings.Join(names, ",") != "size,mode,owner" {
+		t.Errorf("Unexpected columns %v", names)
+	}
+	if _, err := parseColumns("size,colour"); err == nil {
+		t.Error("Unknown column should be rejected")
+	}
+}
+
+func TestCycleListing(t *testing.T) {
+	m := initialModel()
+	for _, expected := range []listingMode{listingBrief, listingCustom, listingFull} {
+		m.cycleListing()
+		if m.panels[0].listing != expected {
+			t.Errorf("Expected %v, got %v", expected, m.panels[0].listing)
+		}
+	}
+	if m.panels[1].listing != listingFull {
+		t.Error("Listing mode should be per panel")
+	}
+}
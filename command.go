@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxCommands is the number of command lines kept in the history
+const maxCommands = 100
+
+// commandLine is the shell command line below the panels. The zero value is
+// closed with an empty history.
+type commandLine struct {
+	input   *prompt  // Open command line, nil when closed
+	history []string // Oldest first
+	pos     int      // Entry of the history being edited, len(history) for a new line
+	draft   []rune   // New line while browsing the history
+}
+
+// commandDoneMsg reports the end of a command run from the command line
+type commandDoneMsg struct {
+	err error
+}
+
+// openCommandLine starts editing a command in the active panel's directory
+func (m *model) openCommandLine() {
+	c := &m.commands
+	c.input = newPrompt(m.commandLabel(), "", nil)
+	c.pos = len(c.history)
+	c.draft = nil
+}
+
+// commandLabel is the prompt of the command line, the active directory with
+// the home directory abbreviated
+func (m *model) commandLabel() string {
+	dir := m.panels[m.activePanel].path
+	if home, err := os.UserHomeDir(); err == nil && home != "/" {
+		if dir == home {
+			dir = "~"
+		} else if rest, ok := strings.CutPrefix(dir, home+"/"); ok {
+			dir = "~/" + rest
+		}
+	}
+	return dir + "$"
+}
+
+// updateCommandLine handles the keys while the command line is open
+func (m *model) updateCommandLine(msg tea.KeyMsg) tea.Cmd {
+	c := &m.commands
+	switch m.keys.lookupIn(scopeCommand, msg.String()) {
+	case actionUp:
+		c.browse(-1)
+		return nil
+	case actionDown:
+		c.browse(1)
+		return nil
+	case actionCancel:
+		switch {
+		case m.outputShown && len(c.input.value) == 0:
+			// Cancelling an empty line returns from the output to the panels
+			return m.toggleOutput()
+		case m.outputShown:
+			// The output stays, with an empty line
+			m.openCommandLine()
+		default:
+			c.input = nil
+		}
+		return nil
+	case actionRun:
+		return m.runCommandLine()
+	case actionQuit:
+		return m.quit()
+	}
+	if m.keys.lookup(msg.String()) == actionOutput {
+		return m.toggleOutput()
+	}
+	// Enter and Esc only run or cancel through their bindings
+	c.input.update(msg)
+	return nil
+}
+
+// runCommandLine runs the line being edited and adds it to the history
+func (m *model) runCommandLine() tea.Cmd {
+	c := &m.commands
+	line := strings.TrimSpace(c.input.text())
+	if line == "" {
+		return nil
+	}
+	c.add(line)
+	command, err := m.expandCommand(line)
+	if err != nil {
+		m.statusMsg = err.Error()
+		return nil
+	}
+	m.statusMsg = ""
+	if m.outputShown {
+		// Stay on the output, ready for the next command
+		m.openCommandLine()
+	} else {
+		c.input = nil
+	}
+	return runCommandCmd(m.panels[m.activePanel].path, m.commandLabel()+" "+line, command)
+}
+
+// browse replaces the input with an older (-1) or newer (1) history entry
+func (c *commandLine) browse(step int) {
+	pos := c.pos + step
+	if pos < 0 || pos > len(c.history) {
+		return
+	}
+	if c.pos == len(c.history) {
+		c.draft = c.input.value
+	}
+	c.pos = pos
+	value := c.draft
+	if pos < len(c.history) {
+		value = []rune(c.history[pos])
+	}
+	c.input.value = append([]rune(nil), value...)
+	c.input.pos = len(c.input.value)
+}
+
+// add appends line to the history, moving a repeated line to the end
+func (c *commandLine) add(line string) {
+	for i, old := range c.history {
+		if old == line {
+			c.history = append(c.history[:i], c.history[i+1:]...)
+			break
+		}
+	}
+	c.history = append(c.history, line)
+	if len(c.history) > maxCommands {
+		c.history = c.history[len(c.history)-maxCommands:]
+	}
+	c.pos = len(c.history)
+}
+
+// expandCommand replaces the placeholders of a command line with quoted
+// names: %f the entry under the cursor, %s the marked entries (or the one
+// under the cursor), %D the other panel's directory and %% a percent sign.
+// Other % sequences are kept, e.g. for date +%Y, but %f, %s and %D need %%
+// to reach the command, as in date +%%s.
+func (m *model) expandCommand(line string) (string, error) {
+	p := &m.panels[m.activePanel]
+	other := &m.panels[1-m.activePanel]
+	var s strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] != '%' || i == len(line)-1 {
+			s.WriteByte(line[i])
+			continue
+		}
+		switch line[i+1] {
+		case 'f':
+			if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+				return "", errors.New("%f: no entry under the cursor")
+			}
+			s.WriteString(shellQuote(p.entries[p.cursor].Name))
+		case 's':
+			targets := p.operationTargets()
+			if len(targets) == 0 {
+				return "", errors.New("%s: no entries marked or under the cursor")
+			}
+			for j, entry := range targets {
+				if j > 0 {
+					s.WriteByte(' ')
+				}
+				s.WriteString(shellQuote(entry.Name))
+			}
+		case 'D':
+			s.WriteString(shellQuote(other.path))
+		case '%':
+			s.WriteByte('%')
+		default:
+			s.WriteByte('%')
+			continue
+		}
+		i++
+	}
+	return s.String(), nil
+}
+
+// shellQuote quotes s for a POSIX shell if it contains special characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-+/,:@=", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellCommand runs a command line through the user's shell. It echoes the
+// line first, if given, so the output shown with Ctrl+O reads like a terminal
+// session.
+type shellCommand struct {
+	*exec.Cmd
+	echo string
+}
+
+func newShellCommand(dir, echo, command string) *shellCommand {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell, "-c", command)
+	cmd.Dir = dir
+	return &shellCommand{Cmd: cmd, echo: echo}
+}
+
+func (c *shellCommand) SetStdin(r io.Reader)  { c.Stdin = r }
+func (c *shellCommand) SetStdout(w io.Writer) { c.Stdout = w }
+func (c *shellCommand) SetStderr(w io.Writer) { c.Stderr = w }
+
+func (c *shellCommand) Run() error {
+	if c.echo != "" && c.Stdout != nil {
+		fmt.Fprintln(c.Stdout, c.echo)
+	}
+	return c.Cmd.Run()
+}
+
+// runCommandCmd runs command in dir with the terminal handed over to it
+func runCommandCmd(dir, echo, command string) tea.Cmd {
+	return tea.Exec(newShellCommand(dir, echo, command), func(err error) tea.Msg {
+		return commandDoneMsg{err: err}
+	})
+}
+
+// handleCommandDone reports a failed command and refreshes both panels,
+// which the command may have changed
+func (m *model) handleCommandDone(msg commandDoneMsg) tea.Cmd {
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(msg.err, &exitErr):
+		m.statusMsg = fmt.Sprintf("Command exited with status %d", exitErr.ExitCode())
+	case msg.err != nil:
+		m.statusMsg = fmt.Sprintf("Cannot run command: %v", msg.err)
+	}
+	return tea.Batch(m.refreshCmd(0), m.refreshCmd(1))
+}
+
+// toggleOutput switches between the panels and the terminal screen with the
+// output of the commands run so far
+func (m *model) toggleOutput() tea.Cmd {
+	m.outputShown = !m.outputShown
+	if m.outputShown {
+		if m.commands.input == nil {
+			m.openCommandLine()
+		}
+		return tea.ExitAltScreen
+	}
+	m.commands.input = nil
+	return tea.EnterAltScreen
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"report.txt":   "report.txt",
+		"/usr/lib":     "/usr/lib",
+		"my file":      "'my file'",
+		"it's":         `'it'\''s'`,
+		"$HOME":        "'$HOME'",
+		"":             "''",
+		"a;rm -rf x":   "'a;rm -rf x'",
+		"name-1_2.tar": "name-1_2.tar",
+	}
+	for s, want := range tests {
+		if got := shellQuote(s); got != want {
+			t.Errorf("shellQuote(%q) = %s, expected %s", s, got, want)
+		}
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	m := model{panels: [2]panel{
+		{path: "/work", entries: []fs.FileEntry{{Name: "a.txt"}, {Name: "b c.txt"}}},
+		{path: "/other dir"},
+	}}
+	m.panels[0].cursor = 1
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"ls -l", "ls -l"},
+		{"cat %f", "cat 'b c.txt'"},
+		{"cp %s %D", "cp 'b c.txt' '/other dir'"},
+		{"date +%Y-%m", "date +%Y-%m"},
+		{"echo 100%%", "echo 100%"},
+		{"date +%%s", "date +%s"},
+		{"echo %", "echo %"},
+	}
+	for _, tt := range tests {
+		got, err := m.expandCommand(tt.line)
+		if err != nil || got != tt.want {
+			t.Errorf("expandCommand(%q) = %q, %v, expected %q", tt.line, got, err, tt.want)
+		}
+	}
+
+	// Marked entries replace the cursor entry for %s only
+	m.panels[0].selected = map[string]bool{"a.txt": true, "b c.txt": true}
+	if got, _ := m.expandCommand("tar cf x.tar %s # %f"); got != "tar cf x.tar a.txt 'b c.txt' # 'b c.txt'" {
+		t.Errorf("Unexpected expansion %q", got)
+	}
+
+	// An empty panel has nothing for %f and %s
+	m.activePanel = 1
+	for _, line := range []string{"cat %f", "rm %s"} {
+		if _, err := m.expandCommand(line); err == nil {
+			t.Errorf("Expected an error for %q in an empty panel", line)
+		}
+	}
+}
+
+func TestCommandLine_History(t *testing.T) {
+	m := model{panels: [2]panel{{path: "/"}, {path: "/"}}}
+	m.commands.add("make")
+	m.commands.add("ls")
+	m.commands.add("make")
+	if strings.Join(m.commands.history, ",") != "ls,make" {
+		t.Fatalf("Expected the repeated command at the end, got %v", m.commands.history)
+	}
+
+	m.openCommandLine()
+	m.commands.input.insert([]rune("gi"))
+	keys := []struct {
+		key  tea.KeyType
+		want string
+	}{
+		{tea.KeyUp, "make"},
+		{tea.KeyUp, "ls"},
+		{tea.KeyUp, "ls"}, // Oldest entry
+		{tea.KeyDown, "make"},
+		{tea.KeyDown, "gi"}, // Back to the line being typed
+		{tea.KeyDown, "gi"},
+	}
+	for i, k := range keys {
+		m.updateCommandLine(tea.KeyMsg{Type: k.key})
+		if got := m.commands.input.text(); got != k.want {
+			t.Errorf("Step %d: expected %q, got %q", i, k.want, got)
+		}
+	}
+
+	for i := 0; i < maxCommands+5; i++ {
+		m.commands.add(strings.Repeat("x", i+1))
+	}
+	if len(m.commands.history) != maxCommands {
+		t.Errorf("Expected %d commands, got %d", maxCommands, len(m.commands.history))
+	}
+}
+
+func TestCommandLine_RunAndRefresh(t *testing.T) {
+	dir := t.TempDir()
+	m := initialModel()
+	m.panels[0].path = dir
+	m = readPanel(m, 0)
+
+	m, _ = pressKey(m, ":")
+	if m.commands.input == nil {
+		t.Fatal("Expected : to open the command line")
+	}
+	m.commands.input.insert([]rune("touch new.txt"))
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if cmd == nil || m.commands.input != nil {
+		t.Fatal("Expected Enter to run the command and close the command line")
+	}
+	if m.commands.history[len(m.commands.history)-1] != "touch new.txt" {
+		t.Errorf("Expected the command in the history, got %v", m.commands.history)
+	}
+
+	// bubbletea runs the command with the terminal; run it directly instead
+	var out bytes.Buffer
+	c := newShellCommand(dir, "~$ touch new.txt", "touch new.txt")
+	c.SetStdout(&out)
+	c.SetStderr(&out)
+	err := c.Run()
+	if out.String() != "~$ touch new.txt\n" {
+		t.Errorf("Expected the echoed command line, got %q", out.String())
+	}
+
+	updated, cmd = m.Update(commandDoneMsg{err: err})
+	m = runCmd(updated.(model), cmd)
+	if _, ok := m.panels[0].lookup("new.txt"); !ok {
+		t.Error("Expected the panel to show the new file after the command")
+	}
+}
+
+func TestCommandLine_ExitStatus(t *testing.T) {
+	c := newShellCommand(t.TempDir(), "", "exit 3")
+	c.SetStdout(&bytes.Buffer{})
+	err := c.Run()
+	m := model{panels: [2]panel{{path: "/"}, {path: "/"}}}
+	m.handleCommandDone(commandDoneMsg{err: err})
+	if m.statusMsg != "Command exited with status 3" {
+		t.Errorf("Expected the exit status, got %q", m.statusMsg)
+	}
+}
+
+func TestCommandLine_RunsInPanelDirectory(t *testing.T) {
+	t.Setenv("SHELL", "")
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	var out bytes.Buffer
+	c := newShellCommand(dir, "$ pwd", "pwd")
+	c.SetStdout(&out)
+	if err := c.Run(); err != nil {
+		t.Fatalf("Failed to run command: %v", err)
+	}
+	if out.String() != "$ pwd\n"+dir+"\n" {
+		t.Errorf("Expected the output of pwd in %s, got %q", dir, out.String())
+	}
+	if c.Path != "/bin/sh" {
+		t.Errorf("Expected /bin/sh without $SHELL, got %s", c.Path)
+	}
+}
+
+func TestCommandLine_Output(t *testing.T) {
+	m := model{panels: [2]panel{{path: "/"}, {path: "/"}}}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	m = updated.(model)
+	if !m.outputShown || m.commands.input == nil || cmd == nil {
+		t.Fatal("Expected Ctrl+O to show the output with the command line")
+	}
+	if view := m.View(); strings.Contains(view, "Min Commander") || !strings.Contains(view, "/$") {
+		t.Errorf("Expected only the command line, got %q", view)
+	}
+
+	// Esc with text clears the line, on an empty line returns to the panels
+	m.commands.input.insert([]rune("ls"))
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if !m.outputShown || m.commands.input == nil || m.commands.input.text() != "" {
+		t.Fatal("Expected Esc to clear the line and keep the output")
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if m.outputShown || m.commands.input != nil {
+		t.Error("Expected Esc on an empty line to return to the panels")
+	}
+}
+
+func TestCommandLine_HistorySaved(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := initialModel()
+	m.commands.add("make test")
+	if err := m.saveHistory(); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+	loaded := initialModel()
+	if err := loaded.loadHistory(); err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	if len(loaded.commands.history) != 1 || loaded.commands.history[0] != "make test" {
+		t.Errorf("Expected the command history, got %v", loaded.commands.history)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config is the content of config.toml. Everything missing from the file
+// keeps its default.
+type config struct {
+	leftPath  string // Start directories, "" for the default
+	rightPath string
+	theme     theme
+	keys      keymap
+	openers   []opener // In the order of the file, the first match wins
+	editor    string   // Editor command, "" for $VISUAL or $EDITOR
+}
+
+// configError is a problem in the configuration file, with the line it was
+// found on
+type configError struct {
+	file string
+	line int
+	msg  string
+}
+
+func (e *configError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.file, e.line, e.msg)
+}
+
+// configFile returns the path of the configuration file
+func configFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// loadConfigFile reads the configuration file from the configuration
+// directory
+func loadConfigFile() (*config, error) {
+	path, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+	return loadConfig(path)
+}
+
+// loadConfig reads the configuration file at path. A missing file gives the
+// default configuration.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return parseConfig(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(path, data)
+}
+
+// parseConfig interprets the configuration file content. name is used in
+// error messages.
+func parseConfig(name string, data []byte) (*config, error) {
+	cfg := &config{theme: themes["default"], keys: defaultKeymap()}
+	entries, err := parseTOML(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	fail := func(e tomlEntry, format string, args ...any) error {
+		return &configError{file: name, line: e.line, msg: fmt.Sprintf(format, args...)}
+	}
+
+	// User themes start from the default theme
+	userThemes := map[string]*theme{}
+	themeName, themeLine := "default", 0
+	boundKeys := map[string]tomlEntry{}
+	for _, e := range entries {
+		switch {
+		case e.table == "" && e.key == "theme":
+			if themeName, err = e.value.str(); err != nil {
+				return nil, fail(e, "theme: %v", err)
+			}
+			themeLine = e.line
+
+		case e.table == "" && e.key == "editor":
+			if cfg.editor, err = e.value.str(); err != nil {
+				return nil, fail(e, "editor: %v", err)
+			}
+
+		case e.table == "panels":
+			path, err := e.value.str()
+			if err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+			if path, err = filepath.Abs(expandHome(path)); err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				return nil, fail(e, "%s: %s is not a directory", e.key, path)
+			}
+			switch e.key {
+			case "left":
+				cfg.leftPath = path
+			case "right":
+				cfg.rightPath = path
+			default:
+				return nil, fail(e, "unknown panel %q, expected left or right", e.key)
+			}
+
+		case strings.HasPrefix(e.table, "themes."):
+			themeKey := strings.TrimPrefix(e.table, "themes.")
+			field, ok := themeFields[e.key]
+			if !ok {
+				return nil, fail(e, "unknown theme colour %q", e.key)
+			}
+			value, err := e.value.str()
+			if err == nil {
+				value, err = parseColor(value)
+			}
+			if err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+			t, ok := userThemes[themeKey]
+			if !ok {
+				base := themes["default"]
+				t = &base
+				userThemes[themeKey] = t
+			}
+			*field(t) = value
+
+		case e.table == "keys" || strings.HasPrefix(e.table, "keys."):
+			// [keys] binds the panel keys, [keys.viewer] those of the viewer
+			scopeName := strings.TrimPrefix(e.table, "keys")
+			if scopeName != "" {
+				scopeName = strings.TrimPrefix(scopeName, ".")
+				if scopeName == "" || !isScope(scopeName) {
+					return nil, fail(e, "unknown section [%s]", e.table)
+				}
+			}
+			scope := keyScope(scopeName)
+			if !isAction(scope, e.key) {
+				return nil, fail(e, "unknown action %q", e.key)
+			}
+			names, err := e.value.strs()
+			if err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+			var keys []string
+			for _, name := range names {
+				key, err := parseKey(name)
+				if err != nil {
+					return nil, fail(e, "%s: %v", e.key, err)
+				}
+				if other, ok := boundKeys[e.table+" "+key]; ok {
+					return nil, fail(e, "%s: key %q is already bound to %s on line %d", e.key, name, other.key, other.line)
+				}
+				boundKeys[e.table+" "+key] = e
+				keys = append(keys, key)
+			}
+			// Keys from the file take precedence over the default bindings
+			for _, key := range keys {
+				cfg.keys.release(scope, key)
+			}
+			if err := cfg.keys.bind(scope, action(e.key), keys); err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+
+		case e.table == "open":
+			command, err := e.value.str()
+			if err != nil {
+				return nil, fail(e, "%s: %v", e.key, err)
+			}
+			if strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(command), "&")) == "" {
+				return nil, fail(e, "%s: empty command", e.key)
+			}
+			cfg.openers = append(cfg.openers, opener{pattern: e.key, command: command})
+
+		case e.table == "":
+			return nil, fail(e, "unknown setting %q", e.key)
+		default:
+			return nil, fail(e, "unknown section [%s]", e.table)
+		}
+	}
+
+	if t, ok := userThemes[themeName]; ok {
+		cfg.theme = *t
+	} else if t, ok := themes[themeName]; ok {
+		cfg.theme = t
+	} else {
+		return nil, &configError{file: name, line: themeLine, msg: fmt.Sprintf("unknown theme %q", themeName)}
+	}
+	return cfg, nil
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// applyConfig sets up the model and the styles from cfg
+func (m *model) applyConfig(cfg *config) {
+	if cfg.leftPath != "" {
+		m.panels[0].path = cfg.leftPath
+	}
+	if cfg.rightPath != "" {
+		m.panels[1].path = cfg.rightPath
+	}
+	m.keys = cfg.keys
+	m.openers = cfg.openers
+	m.editor = cfg.editor
+	applyTheme(cfg.theme)
+}
+
+// tomlEntry is a key/value pair of a TOML file with its table
+type tomlEntry struct {
+	line  int
+	table string
+	key   string
+	value tomlValue
+}
+
+// tomlValue is a decoded TOML value
+type tomlValue struct {
+	v any
+}
+
+func (v tomlValue) str() (string, error) {
+	s, ok := v.v.(string)
+	if !ok {
+		return "", errors.New("expected a string")
+	}
+	return s, nil
+}
+
+// strs accepts a string or an array of strings
+func (v tomlValue) strs() ([]string, error) {
+	array, ok := v.v.([]any)
+	if !ok {
+		s, err := v.str()
+		if err != nil {
+			return nil, errors.New("expected a string or an array of strings")
+		}
+		return []string{s}, nil
+	}
+	var list []string
+	for _, item := range array {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("expected an array of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// parseTOML decodes a TOML file into its values in the order of the file,
+// each with its table and line. Tables themselves are left out.
+func parseTOML(name string, data []byte) ([]tomlEntry, error) {
+	fail := func(err error) error {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return &configError{file: name, line: parseErr.Position.Line, msg: parseErr.Message}
+		}
+		return err
+	}
+
+	var root map[string]toml.Primitive
+	md, err := toml.Decode(string(data), &root)
+	if err != nil {
+		return nil, fail(err)
+	}
+	values := map[string]toml.Primitive{}
+	if err := collectTOML(&md, nil, root, values); err != nil {
+		return nil, fail(err)
+	}
+
+	var entries []tomlEntry
+	for _, key := range md.Keys() {
+		prim, ok := values[key.String()]
+		if !ok {
+			// A table, or a key inside an array of tables, which the
+			// configuration has no use for and reports on the array itself
+			continue
+		}
+		e := tomlEntry{table: strings.Join(key[:len(key)-1], "."), key: key[len(key)-1]}
+		if err := md.PrimitiveDecode(prim, &e.value.v); err != nil {
+			return nil, fail(err)
+		}
+		// The decoder keeps the positions of keys to itself and only reports
+		// them with errors, so a value that refuses to decode gets the line
+		var parseErr toml.ParseError
+		if errors.As(md.PrimitiveDecode(prim, lineProbe{}), &parseErr) {
+			e.line = parseErr.Position.Line
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// collectTOML adds the values of table, whose key is parent, and of the
+// tables nested in it to values by their full key
+func collectTOML(md *toml.MetaData, parent toml.Key, table map[string]toml.Primitive, values map[string]toml.Primitive) error {
+	for name, prim := range table {
+		key := append(parent[:len(parent):len(parent)], name)
+		// Tables created implicitly by [a.b] have no type of their own, so
+		// tables are told apart by their value
+		var value any
+		if err := md.PrimitiveDecode(prim, &value); err != nil {
+			return err
+		}
+		if _, ok := value.(map[string]any); !ok {
+			values[key.String()] = prim
+			continue
+		}
+		var nested map[string]toml.Primitive
+		if err := md.PrimitiveDecode(prim, &nested); err != nil {
+			return err
+		}
+		if err := collectTOML(md, key, nested, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineProbe refuses to decode any value, see parseTOML
+type lineProbe struct{}
+
+func (lineProbe) UnmarshalTOML(any) error {
+	return errors.New("line probe")
+}
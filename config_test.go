@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseConfig(t *testing.T) {
+	left := t.TempDir()
+	data := `# Min Commander settings
+theme = "dark"
+
+[panels]
+left = "` + left + `"
+right = '/'
+
+[themes.dark]
+border = "#444"
+cursor_bg = "blue"   # Named colours are allowed
+directory = "33"
+
+[keys]
+copy = "F5"
+move = [
+  "F6",
+  "Ctrl+R",   # Arrays may span lines
+]
+quit = "F10"
+view = "q"
+
+[keys.jobs]
+retry = ["r", "F5"]   # Another scope, no conflict with copy
+`
+	cfg, err := parseConfig("config.toml", []byte(data))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if cfg.leftPath != left || cfg.rightPath != "/" {
+		t.Errorf("Unexpected panel paths %q and %q", cfg.leftPath, cfg.rightPath)
+	}
+
+	// User themes start from the default theme
+	want := themes["default"]
+	want.border, want.cursorBg, want.directory = "#444", "4", "33"
+	if cfg.theme != want {
+		t.Errorf("Expected theme %+v, got %+v", want, cfg.theme)
+	}
+
+	tests := []struct {
+		key  string
+		want action
+	}{
+		{"f5", actionCopy},
+		{"c", ""},
+		{"f6", actionMove},
+		{"ctrl+r", actionMove},
+		{"r", ""},
+		{"f10", actionQuit},
+		{"q", actionView},
+		{"v", ""},
+		{"f8", actionTrash},
+	}
+	for _, tt := range tests {
+		if got := cfg.keys.lookup(tt.key); got != tt.want {
+			t.Errorf("Key %q: expected %q, got %q", tt.key, tt.want, got)
+		}
+	}
+	if cfg.keys.lookupIn(scopeJobs, "f5") != actionRetryJob {
+		t.Error("Expected F5 to retry in the jobs panel")
+	}
+}
+
+func TestParseConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		line int
+		msg  string
+	}{
+		{"Unknown setting", "\ncolor = true", 2, `unknown setting "color"`},
+		{"Unknown section", "[panel]\nleft = '/'", 2, "unknown section [panel]"},
+		{"Unquoted string", "[keys]\ncopy = F5", 2, `expected value but found "F" instead`},
+		{"Unterminated string", "theme = \"mono", 1, `expected '"'`},
+		{"Missing value", "\n\ntheme =", 3, "expected value"},
+		{"No equals sign", "[keys]\n\ncopy", 3, "expected key separator '='"},
+		{"Duplicate key", "[keys]\ncopy = 'F5'\ncopy = 'c'", 3, "Key 'keys.copy' has already been defined"},
+		{"Duplicate table", "[keys]\n[panels]\n[keys]", 3, "Key 'keys' has already been defined"},
+		{"Unknown action", "[keys]\nexplode = 'x'", 2, `unknown action "explode"`},
+		{"Unknown key", "[keys]\ncopy = 'F99'", 2, `unknown key "F99"`},
+		{"Key conflict", "[keys]\ncopy = 'x'\n\nview = ['F3', 'x']", 4, `key "x" is already bound to copy on line 2`},
+		{"Scope key conflict", "[keys.jobs]\nretry = 'x'\npause = 'x'", 3, `key "x" is already bound to retry on line 2`},
+		{"Unknown scope", "[keys.editor]\nclose = 'x'", 2, "unknown section [keys.editor]"},
+		{"Action of another scope", "[keys.viewer]\ncopy = 'c'", 2, `unknown action "copy"`},
+		{"Unknown colour", "[themes.x]\nborder = 'purplish'", 2, `unknown colour "purplish"`},
+		{"Invalid hex colour", "[themes.x]\n\nmarked = '#12345'", 3, "expected #RGB or #RRGGBB"},
+		{"Unknown theme field", "[themes.x]\nbackground = 'red'", 2, `unknown theme colour "background"`},
+		{"Unknown theme", "\n\ntheme = 'solarized'", 3, `unknown theme "solarized"`},
+		{"Missing directory", "[panels]\nright = '/does/not/exist'", 2, "is not a directory"},
+		{"Unknown panel", "[panels]\nmiddle = '/'", 2, `unknown panel "middle"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfig("config.toml", []byte(tt.data))
+			var cfgErr *configError
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("Expected a configError, got %v", err)
+			}
+			if cfgErr.line != tt.line || !strings.Contains(cfgErr.msg, tt.msg) {
+				t.Errorf("Expected line %d with %q, got %v", tt.line, tt.msg, err)
+			}
+			if !strings.HasPrefix(err.Error(), "config.toml:") {
+				t.Errorf("Expected the file name in %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	// Without a file everything is default
+	cfg, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("Failed to load missing config: %v", err)
+	}
+	if cfg.theme != themes["default"] || cfg.keys.lookup("c") != actionCopy {
+		t.Error("Expected the default configuration without a file")
+	}
+
+	path := filepath.Join(dir, "min-commander", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("theme = \"mono\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if cfg, err = loadConfigFile(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.theme != themes["mono"] {
+		t.Errorf("Expected the mono theme, got %+v", cfg.theme)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"#fff", "#fff", true},
+		{"#00AAAA", "#00AAAA", true},
+		{"#00AAA", "", false},
+		{"#GGGGGG", "", false},
+		{"208", "208", true},
+		{"256", "", false},
+		{"red", "1", true},
+		{"Bright-Blue", "12", true},
+		{"teal", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parseColor(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseColor(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
+
+func TestApplyConfig_RemapsKeys(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	cfg, err := parseConfig("config.toml", []byte("[keys]\nview = 'F3'\nhidden = '.'\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	m := initialModel()
+	m.applyConfig(cfg)
+	defer applyTheme(themes["default"])
+	m.panels[0].path = dir
+	m = readPanel(m, 0)
+
+	// The old key does nothing, the new one works
+	m, _ = pressKey(m, "h")
+	if m.panels[0].showHidden {
+		t.Error("Expected h to be unbound")
+	}
+	m, _ = pressKey(m, ".")
+	if !m.panels[0].showHidden {
+		t.Error("Expected . to toggle hidden files")
+	}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyF3}); cmd == nil {
+		t.Error("Expected F3 to open the viewer")
+	}
+	if help := m.keys.helpLine(); !strings.Contains(help, ".: Hidden") || !strings.Contains(help, "F3: View") {
+		t.Errorf("Expected the help line to show the new keys, got %q", help)
+	}
+}
+
+func TestApplyConfig_RemapsDialogKeys(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	config := "[keys]\nbookmark_1 = 'Alt+B'\n" +
+		"[keys.rename]\nnext_field = 'Ctrl+N'\ncancel = 'Ctrl+G'\n" +
+		"[keys.command]\nup = 'Ctrl+P'\n"
+	cfg, err := parseConfig("config.toml", []byte(config))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	m, _ := fileOpsTestModel(t, "a.txt")
+	m.applyConfig(cfg)
+	defer applyTheme(themes["default"])
+
+	src, project := m.panels[0].path, m.panels[1].path
+	m.bookmarks = []bookmark{{Name: "project", Path: project, Key: "1"}}
+	m = pressKeys(t, m, "alt+1")
+	if m.panels[0].path == project {
+		t.Error("Expected Alt+1 to be unbound")
+	}
+	m = pressKeys(t, m, "alt+B")
+	if m.panels[0].path != project {
+		t.Errorf("Expected Alt+B to go to %s, got %s", project, m.panels[0].path)
+	}
+
+	m.panels[0].path = src
+	m = readPanel(m, 0)
+	m = pressKeys(t, m, "alt+r", "tab", "esc")
+	if m.rename == nil || m.rename.focus != renameFieldTemplate {
+		t.Fatal("Expected Tab and Esc to be unbound in the rename dialog")
+	}
+	if help := m.rename.view(); !strings.Contains(help, "Ctrl+N: Field") || !strings.Contains(help, "Ctrl+G: Cancel") {
+		t.Errorf("Expected the dialog to show the new keys:\n%s", help)
+	}
+	m = pressKeys(t, m, "ctrl+n")
+	if m.rename.focus != renameFieldSearch {
+		t.Errorf("Expected Ctrl+N to move to the next field, got %d", m.rename.focus)
+	}
+	m = pressKeys(t, m, "ctrl+g")
+	if m.rename != nil {
+		t.Fatal("Expected Ctrl+G to cancel the rename")
+	}
+
+	m.commands.add("ls")
+	m.openCommandLine()
+	m = pressKeys(t, m, "up")
+	if got := m.commands.input.text(); got != "" {
+		t.Errorf("Expected Up to be unbound on the command line, got %q", got)
+	}
+	m = pressKeys(t, m, "ctrl+p")
+	if got := m.commands.input.text(); got != "ls" {
+		t.Errorf("Expected Ctrl+P to recall ls, got %q", got)
+	}
+}
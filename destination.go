@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// openDestinationPrompt asks where to copy or move the entries of fo, starting
+// with the other panel's directory
+func (m *model) openDestinationPrompt(fo *fileOperation) {
+	verb := "Copy"
+	if fo.op == "move" {
+		verb = "Move"
+	}
+	srcDir := fo.srcDir
+	initial := fo.dstDir
+	if !strings.HasSuffix(initial, string(filepath.Separator)) {
+		initial += string(filepath.Separator)
+	}
+	m.prompt = newPrompt(fmt.Sprintf("%s %s to:", verb, fo.name), initial, func(m *model, value string) tea.Cmd {
+		return m.transferTo(fo, value)
+	})
+	m.prompt.complete = func(value string) (string, []string) {
+		return completeDir(srcDir, value)
+	}
+}
+
+// transferTo sets the destination of fo from the prompt's value and asks
+// about conflicts. Relative paths start at the source directory. The value
+// names a directory if it ends in a separator, exists as one, or there are
+// several entries, and a new name for a single entry otherwise. Missing
+// directories are created once the operation runs.
+func (m *model) transferTo(fo *fileOperation, value string) tea.Cmd {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		m.statusMsg = "Operation cancelled"
+		return nil
+	}
+	dest := resolvePath(fo.srcDir, value)
+	isDir := strings.HasSuffix(value, string(filepath.Separator)) || len(fo.items) > 1
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		isDir = true
+	}
+	fo.dstDir = dest
+	if !isDir {
+		fo.dstDir = filepath.Dir(dest)
+		fo.items[0].dstName = filepath.Base(dest)
+	}
+
+	for _, item := range fo.items {
+		src := filepath.Join(fo.srcDir, item.entry.Name)
+		dst := filepath.Join(fo.dstDir, item.dstName)
+		if item.entry.IsDir && fs.Contains(src, dst) {
+			m.statusMsg = fmt.Sprintf("Cannot %s %s into itself", fo.op, item.entry.Name)
+			return nil
+		}
+		// Overwriting a directory that contains the source would delete it
+		if fs.Contains(dst, src) {
+			m.statusMsg = fmt.Sprintf("Cannot %s %s onto a directory that contains it", fo.op, item.entry.Name)
+			return nil
+		}
+	}
+
+	if info, err := os.Stat(fo.dstDir); err == nil && !info.IsDir() {
+		m.statusMsg = fmt.Sprintf("Cannot %s to %s: not a directory", fo.op, fo.dstDir)
+		return nil
+	}
+
+	return m.resolveConflicts(fo, 0)
+}
+
+// resolvePath makes value absolute, relative to base, with ~ for the home
+// directory
+func resolvePath(base, value string) string {
+	path := expandHome(value)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
+
+// completeDir completes the last element of value to the name of a
+// directory, relative to base for relative paths. With several matches it
+// completes their common prefix and returns the names as candidates. Hidden
+// directories are only offered once the element starts with a dot.
+func completeDir(base, value string) (string, []string) {
+	if value == "~" {
+		return "~" + string(filepath.Separator), nil
+	}
+	sep := strings.LastIndex(value, string(filepath.Separator))
+	dirPart, prefix := value[:sep+1], value[sep+1:]
+	dir := base
+	if dirPart != "" {
+		dir = resolvePath(base, dirPart)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return value, nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		// Links to directories count as directories
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.IsDir() {
+			names = append(names, name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return value, nil
+	case 1:
+		return dirPart + names[0] + string(filepath.Separator), nil
+	}
+	common := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	// Do not end in the middle of a character
+	for !utf8.ValidString(common) {
+		common = common[:len(common)-1]
+	}
+	candidates := make([]string, len(names))
+	for i, name := range names {
+		candidates[i] = name + string(filepath.Separator)
+	}
+	return dirPart + common, candidates
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestCompleteDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha/sub", "alps", "beta", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "alpine.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Symlink("beta", filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+
+	tests := []struct {
+		value      string
+		want       string
+		candidates []string
+	}{
+		{"al", "alp", []string{"alpha/", "alps/"}},
+		{"alph", "alpha/", nil},
+		{"alpha/", "alpha/sub/", nil},
+		{"li", "link/", nil}, // Links to directories
+		{".", ".hidden/", nil},
+		{"alpi", "alpi", nil}, // Files are not offered
+		{"x", "x", nil},
+		{"missing/a", "missing/a", nil},
+		{dir + "/b", dir + "/beta/", nil},
+		{"~", "~/", nil},
+	}
+	for _, tt := range tests {
+		got, candidates := completeDir(dir, tt.value)
+		if got != tt.want || !slices.Equal(candidates, tt.candidates) {
+			t.Errorf("completeDir(%q) = %q, %q, expected %q, %q", tt.value, got, candidates, tt.want, tt.candidates)
+		}
+	}
+}
+
+// destinationTestModel has a.txt, b.txt and the directory d in the left
+// panel and an empty directory in the right one
+func destinationTestModel(t *testing.T) (model, string) {
+	t.Helper()
+	m, dstDir := fileOpsTestModel(t, "a.txt", "b.txt")
+	if err := os.Mkdir(filepath.Join(m.panels[0].path, "d"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	m = readPanel(m, 0)
+	return m, dstDir
+}
+
+// transfer presses key on the entry name and submits value as destination
+func transfer(t *testing.T, m model, key, name, value string) (model, tea.Cmd) {
+	t.Helper()
+	p := &m.panels[0]
+	p.cursor, _ = p.lookup(name)
+	m, _ = pressKey(m, key)
+	if m.prompt == nil || m.prompt.text() != m.panels[1].path+"/" {
+		t.Fatalf("Expected the prompt with the other panel's directory, got %v", m.prompt)
+	}
+	m.prompt = newPrompt("", value, m.prompt.onSubmit)
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return updated.(model), cmd
+}
+
+func TestTransferTo(t *testing.T) {
+	m, dstDir := destinationTestModel(t)
+	srcDir := m.panels[0].path
+
+	tests := []struct {
+		key   string
+		name  string
+		value string
+		want  string // Created path
+		gone  string // Moved away path
+	}{
+		{"c", "a.txt", dstDir + "/copy.txt", dstDir + "/copy.txt", ""},
+		{"c", "a.txt", "new/deeper/", srcDir + "/new/deeper/a.txt", ""},
+		{"c", "d", dstDir + "/tree", dstDir + "/tree", ""},
+		{"r", "b.txt", "../" + filepath.Base(dstDir) + "/moved.txt", dstDir + "/moved.txt", srcDir + "/b.txt"},
+	}
+	if filepath.Dir(srcDir) != filepath.Dir(dstDir) {
+		t.Fatalf("Expected sibling temporary directories: %s, %s", srcDir, dstDir)
+	}
+	for _, tt := range tests {
+		var cmd tea.Cmd
+		m, cmd = transfer(t, m, tt.key, tt.name, tt.value)
+		if cmd == nil {
+			t.Fatalf("%s: expected the operation to start, got %q", tt.value, m.statusMsg)
+		}
+		var msg fileOpResultMsg
+		if m, msg = finishOperation(t, m, cmd); msg.err != nil {
+			t.Fatalf("%s: %v", tt.value, msg.err)
+		}
+		if _, err := os.Stat(tt.want); err != nil {
+			t.Errorf("%s: expected %s: %v", tt.value, tt.want, err)
+		}
+		if _, err := os.Stat(tt.gone); tt.gone != "" && err == nil {
+			t.Errorf("%s: expected %s to be moved", tt.value, tt.gone)
+		}
+		m = readPanel(m, 0)
+	}
+}
+
+func TestTransferTo_SeveralEntries(t *testing.T) {
+	m, dstDir := destinationTestModel(t)
+	m.panels[0].setSelected("a.txt", true)
+	m.panels[0].setSelected("d", true)
+
+	// Several entries always go into a directory, which is created when the
+	// copy runs
+	m, cmd := transfer(t, m, "c", "a.txt", dstDir+"/batch")
+	if m.statusMsg != "Copying: 2 entries -> "+dstDir+"/batch" {
+		t.Errorf("Unexpected status %q", m.statusMsg)
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "batch")); err == nil {
+		t.Error("Expected no directory before the copy runs")
+	}
+	if _, msg := finishOperation(t, m, cmd); msg.err != nil {
+		t.Fatalf("Copy failed: %v", msg.err)
+	}
+	for _, name := range []string{"a.txt", "d"} {
+		if _, err := os.Stat(filepath.Join(dstDir, "batch", name)); err != nil {
+			t.Errorf("Expected %s in the new directory: %v", name, err)
+		}
+	}
+}
+
+func TestTransferTo_Errors(t *testing.T) {
+	m, dstDir := destinationTestModel(t)
+	if err := os.WriteFile(filepath.Join(dstDir, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"a.txt", dstDir + "/file/a.txt", "Cannot copy to " + dstDir + "/file: not a directory"},
+		{"d", "d/inner/", "Cannot copy d into itself"},
+		{"d", "d", "Cannot copy d into itself"},
+		{"a.txt", "  ", "Operation cancelled"},
+	}
+	for _, tt := range tests {
+		m, cmd := transfer(t, m, "c", tt.name, tt.value)
+		if cmd != nil || m.dialog != nil || m.statusMsg != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.value, tt.want, m.statusMsg)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(m.panels[0].path, "d", "inner")); err == nil {
+		t.Error("Expected no directory created for a refused copy")
+	}
+}
+
+func TestDestinationPrompt_Tab(t *testing.T) {
+	m, dstDir := destinationTestModel(t)
+	if err := os.Mkdir(filepath.Join(dstDir, "target"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	m, _ = pressKey(m, "c")
+	m = pressKeys(t, m, "t")
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(model)
+	if got := m.prompt.text(); got != dstDir+"/target/" {
+		t.Errorf("Expected Tab to complete the directory, got %q", got)
+	}
+}
+
+func TestTransferTo_DestinationContainsSource(t *testing.T) {
+	m, _ := destinationTestModel(t)
+	srcDir := m.panels[0].path
+	if err := os.MkdirAll(filepath.Join(srcDir, "d", "a"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "d", "a", "a"), []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Search results carry relative paths, so d/a/a lands on d/a, which holds it
+	m.panels[0].entries = []fs.FileEntry{{Name: "d/a/a"}}
+	m, cmd := transfer(t, m, "c", "d/a/a", srcDir+"/d/")
+	if cmd != nil || m.dialog != nil || m.statusMsg != "Cannot copy d/a/a onto a directory that contains it" {
+		t.Errorf("Expected the copy refused, got %q", m.statusMsg)
+	}
+	if data, err := os.ReadFile(filepath.Join(srcDir, "d", "a", "a")); err != nil || string(data) != "keep" {
+		t.Errorf("Expected the source untouched, got %q, %v", data, err)
+	}
+}
+
+func TestTransferTo_IntoItselfThroughLink(t *testing.T) {
+	m, dstDir := destinationTestModel(t)
+	if err := os.Symlink(filepath.Join(m.panels[0].path, "d"), filepath.Join(dstDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	m, cmd := transfer(t, m, "r", "d", dstDir+"/link/")
+	if cmd != nil || m.statusMsg != "Cannot move d into itself" {
+		t.Errorf("Expected the move refused, got %q", m.statusMsg)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var (
+	dialogStyle = lipgloss.NewStyle().
+			Border(lipgloss.DoubleBorder()).
+			BorderForeground(lipgloss.Color("#FF5555")).
+			Padding(0, 2)
+
+	dialogTitleStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("#FF5555"))
+)
+
+// dialogOption is a button of a dialog, key chooses it directly
+type dialogOption struct {
+	label string
+	key   string
+}
+
+// dialog is a modal box rendered over the panels. onChoose is called with the
+// index of the chosen option.
+type dialog struct {
+	title    string
+	message  string
+	options  []dialogOption
+	focus    int
+	cancel   int // Option chosen by Esc
+	onChoose func(m *model, choice int) tea.Cmd
+}
+
+func newDialog(title, message string, options []dialogOption, cancel int, onChoose func(m *model, choice int) tea.Cmd) *dialog {
+	return &dialog{title: title, message: message, options: options, cancel: cancel, onChoose: onChoose}
+}
+
+// newConfirmDialog asks a yes/no question; onYes runs only if confirmed.
+// No is focused so that a stray Enter does not confirm.
+func newConfirmDialog(title, message string, onYes func(m *model) tea.Cmd) *dialog {
+	options := []dialogOption{{label: "[Y]es", key: "y"}, {label: "[N]o", key: "n"}}
+	d := newDialog(title, message, options, 1, func(m *model, choice int) tea.Cmd {
+		if choice != 0 {
+			return nil
+		}
+		return onYes(m)
+	})
+	d.focus = 1
+	return d
+}
+
+// update handles a key press. It returns the chosen option or -1 if the
+// dialog stays open.
+func (d *dialog) update(msg tea.KeyMsg) int {
+	switch msg.String() {
+	case "left", "shift+tab":
+		d.focus = (d.focus + len(d.options) - 1) % len(d.options)
+	case "right", "tab":
+		d.focus = (d.focus + 1) % len(d.options)
+	case "enter":
+		return d.focus
+	case "esc":
+		return d.cancel
+	default:
+		key := strings.ToLower(msg.String())
+		for i, option := range d.options {
+			if option.key == key {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (d *dialog) view() string {
+	var buttons []string
+	for i, option := range d.options {
+		label := " " + option.label + " "
+		if i == d.focus {
+			label = selectedStyle.Render(label)
+		}
+		buttons = append(buttons, label)
+	}
+	content := lipgloss.JoinVertical(lipgloss.Center,
+		dialogTitleStyle.Render(d.title),
+		"",
+		d.message,
+		"",
+		strings.Join(buttons, " "),
+	)
+	return dialogStyle.Render(content)
+}
+
+// overlay draws fg centered on top of bg, keeping the bg content left and
+// right of it visible
+func overlay(bg, fg string) string {
+	bgLines := strings.Split(bg, "\n")
+	fgLines := strings.Split(fg, "\n")
+	width := lipgloss.Width(bg)
+	fgWidth := lipgloss.Width(fg)
+
+	top := max(0, (len(bgLines)-len(fgLines))/2)
+	left := max(0, (width-fgWidth)/2)
+	for i, line := range fgLines {
+		row := top + i
+		if row >= len(bgLines) {
+			bgLines = append(bgLines, "")
+		}
+		bgLine := bgLines[row]
+		prefix := ansi.Truncate(bgLine, left, "")
+		if w := ansi.StringWidth(prefix); w < left {
+			prefix += strings.Repeat(" ", left-w)
+		}
+		suffix := ansi.TruncateLeft(bgLine, left+ansi.StringWidth(line), "")
+		// Styles cut off in the prefix must not bleed into the dialog
+		bgLines[row] = prefix + ansi.ResetStyle + line + suffix
+	}
+	return strings.Join(bgLines, "\n")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDialog_Keys(t *testing.T) {
+	options := []dialogOption{{label: "[A]", key: "a"}, {label: "[B]", key: "b"}, {label: "[C]", key: "c"}}
+	d := newDialog("Title", "Message", options, 2, nil)
+
+	if choice := d.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}}); choice != 1 {
+		t.Errorf("Expected hotkey to choose option 1, got %d", choice)
+	}
+	if choice := d.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'B'}}); choice != 1 {
+		t.Errorf("Hotkeys should ignore case, got %d", choice)
+	}
+	if choice := d.update(tea.KeyMsg{Type: tea.KeyEsc}); choice != 2 {
+		t.Errorf("Expected Esc to choose the cancel option, got %d", choice)
+	}
+
+	d.update(tea.KeyMsg{Type: tea.KeyRight})
+	d.update(tea.KeyMsg{Type: tea.KeyRight})
+	if choice := d.update(tea.KeyMsg{Type: tea.KeyEnter}); choice != 2 {
+		t.Errorf("Expected Enter to choose the focused option 2, got %d", choice)
+	}
+	d.update(tea.KeyMsg{Type: tea.KeyRight})
+	if d.focus != 0 {
+		t.Errorf("Focus should wrap around, got %d", d.focus)
+	}
+	if choice := d.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}}); choice != -1 {
+		t.Errorf("Unknown keys should keep the dialog open, got %d", choice)
+	}
+}
+
+func TestConfirmDialog_DefaultsToNo(t *testing.T) {
+	confirmed := false
+	d := newConfirmDialog("Delete", "Sure?", func(m *model) tea.Cmd {
+		confirmed = true
+		return nil
+	})
+
+	m := model{}
+	d.onChoose(&m, d.update(tea.KeyMsg{Type: tea.KeyEnter}))
+	if confirmed {
+		t.Error("Enter without moving focus should not confirm")
+	}
+	d.onChoose(&m, d.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}}))
+	if !confirmed {
+		t.Error("y should confirm")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	bg := strings.Join([]string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"}, "\n")
+	result := overlay(bg, "XX")
+
+	lines := strings.Split(result, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	if lines[0] != "aaaaaaaaaa" || lines[2] != "cccccccccc" {
+		t.Errorf("Lines outside the dialog should be unchanged: %q", lines)
+	}
+	if !strings.HasPrefix(lines[1], "bbbb") || !strings.Contains(lines[1], "XX") || !strings.HasSuffix(lines[1], "bbbb") {
+		t.Errorf("Dialog should be centered in the middle line: %q", lines[1])
+	}
+}
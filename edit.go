@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// fallbackEditors are tried in order when no editor is configured
+var fallbackEditors = []string{"nano", "vim", "vi"}
+
+// editDoneMsg reports that the editor exited
+type editDoneMsg struct {
+	index int    // Panel the file was edited from
+	name  string // Entry to put the cursor on
+	err   error
+}
+
+// editorCommand returns the editor: the one from the configuration, $VISUAL,
+// $EDITOR or the first of fallbackEditors that is installed, or "" if there
+// is none
+func (m *model) editorCommand() string {
+	for _, editor := range []string{m.editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if strings.TrimSpace(editor) != "" {
+			return editor
+		}
+	}
+	for _, editor := range fallbackEditors {
+		if _, err := exec.LookPath(editor); err == nil {
+			return editor
+		}
+	}
+	return ""
+}
+
+// editFile edits the entry under the cursor
+func (m *model) editFile() tea.Cmd {
+	p := &m.panels[m.activePanel]
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+		m.statusMsg = "No file selected"
+		return nil
+	}
+	if p.entries[p.cursor].IsDir {
+		m.statusMsg = "Cannot edit a directory"
+		return nil
+	}
+	return m.editPath(m.activePanel, p.entries[p.cursor].Name)
+}
+
+// openEditNewPrompt asks for the name of a file to edit, which is created if
+// it does not exist
+func (m *model) openEditNewPrompt() {
+	p := &m.panels[m.activePanel]
+	if p.isVirtual() {
+		m.statusMsg = "Not available here"
+		return
+	}
+	index := m.activePanel
+	m.prompt = newPrompt("Edit file:", "", func(m *model, name string) tea.Cmd {
+		if name = strings.TrimSpace(name); name == "" {
+			return nil
+		}
+		path := filepath.Join(m.panels[index].path, name)
+		err := fs.CreateFile(path)
+		if errors.Is(err, os.ErrExist) {
+			if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+				m.statusMsg = fmt.Sprintf("Cannot edit a directory: %s", name)
+				return nil
+			}
+			err = nil
+		}
+		if err != nil {
+			m.statusMsg = fmt.Sprintf("Cannot create %s: %v", name, err)
+			return nil
+		}
+		return m.editPath(index, name)
+	})
+}
+
+// editPath runs the editor on name, relative to the directory of the panel at
+// index, with the terminal handed over to it
+func (m *model) editPath(index int, name string) tea.Cmd {
+	editor := m.editorCommand()
+	if editor == "" {
+		m.statusMsg = "No editor found, set editor in the configuration or $EDITOR"
+		return nil
+	}
+	m.statusMsg = ""
+	dir := m.panels[index].path
+	cmd := newShellCommand(dir, "", editor+" "+shellQuote(filepath.Join(dir, name))).Cmd
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editDoneMsg{index: index, name: name, err: err}
+	})
+}
+
+// handleEditDone puts the cursor on the edited file and refreshes both
+// panels, as the editor may have saved other files too
+func (m *model) handleEditDone(msg editDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		m.statusMsg = fmt.Sprintf("Editor failed: %v", msg.err)
+	}
+	p := &m.panels[msg.index]
+	if !p.isVirtual() {
+		// Names of search results are relative paths, not entries
+		p.selectName = msg.name
+	}
+	return tea.Batch(m.refreshCmd(0), m.refreshCmd(1))
+}
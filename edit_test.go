@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	noDesktop(t)
+	bin := os.Getenv("PATH")
+	m := model{}
+	if got := m.editorCommand(); got != "" {
+		t.Errorf("Expected no editor, got %q", got)
+	}
+
+	// The fallback chain takes the first installed editor
+	for _, name := range []string{"vi", "vim"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if got := m.editorCommand(); got != "vim" {
+		t.Errorf("Expected vim, got %q", got)
+	}
+
+	t.Setenv("EDITOR", "emacs -nw")
+	if got := m.editorCommand(); got != "emacs -nw" {
+		t.Errorf("Expected $EDITOR, got %q", got)
+	}
+	t.Setenv("VISUAL", "code -w")
+	if got := m.editorCommand(); got != "code -w" {
+		t.Errorf("Expected $VISUAL, got %q", got)
+	}
+
+	cfg, err := parseConfig("config.toml", []byte("editor = 'hx'\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	m.applyConfig(cfg)
+	defer applyTheme(themes["default"])
+	if got := m.editorCommand(); got != "hx" {
+		t.Errorf("Expected the configured editor, got %q", got)
+	}
+}
+
+// editTestModel is the file operations fixture with b.txt and the directory
+// a, and a stub editor that appends to the file it is given
+func editTestModel(t *testing.T) model {
+	t.Helper()
+	m, _ := fileOpsTestModel(t, "b.txt")
+	if err := os.Mkdir(filepath.Join(m.panels[0].path, "a"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	m.editor = "sh -c 'echo edited >> \"$1\"' editor"
+	return readPanel(m, 0)
+}
+
+func TestEditFile(t *testing.T) {
+	m := editTestModel(t)
+
+	// The directory comes first
+	m, cmd := pressKey(m, "e")
+	if cmd != nil || m.statusMsg != "Cannot edit a directory" {
+		t.Errorf("Expected a message for a directory, got %q", m.statusMsg)
+	}
+
+	m = pressKeys(t, m, "down")
+	updated, cmd := m.Update(keyMsg("f4"))
+	m = updated.(model)
+	if cmd == nil {
+		t.Fatal("Expected F4 to start the editor")
+	}
+	m.statusMsg = ""
+
+	// A failed editor is reported, the panels are refreshed either way
+	updated, cmd = m.Update(editDoneMsg{index: 0, name: "b.txt", err: os.ErrPermission})
+	m = updated.(model)
+	if !strings.Contains(m.statusMsg, "Editor failed") || cmd == nil {
+		t.Errorf("Expected the error and a refresh, got %q", m.statusMsg)
+	}
+}
+
+func TestEditNew_CreatesAndSelectsFile(t *testing.T) {
+	m := editTestModel(t)
+	dir := m.panels[0].path
+
+	m, _ = pressKey(m, "E")
+	if m.prompt == nil {
+		t.Fatal("Expected E to ask for a file name")
+	}
+	m.prompt.insert([]rune("new.txt"))
+	updated, cmd := m.Update(keyMsg("enter"))
+	m = updated.(model)
+	if cmd == nil {
+		t.Fatalf("Expected the editor to start, got %q", m.statusMsg)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "new.txt")); err != nil || info.Size() != 0 {
+		t.Fatalf("Expected an empty new file: %v", err)
+	}
+
+	// Run what bubbletea would run, then report the exit
+	c := newShellCommand(dir, "", m.editor+" "+shellQuote(filepath.Join(dir, "new.txt")))
+	if err := c.Run(); err != nil {
+		t.Fatalf("Failed to run editor: %v", err)
+	}
+	updated, cmd = m.Update(editDoneMsg{index: 0, name: "new.txt"})
+	m = runCmd(updated.(model), cmd)
+	p := m.panels[0]
+	if p.entries[p.cursor].Name != "new.txt" {
+		t.Errorf("Expected the cursor on new.txt, got %s", p.entries[p.cursor].Name)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "new.txt")); string(data) != "edited\n" {
+		t.Errorf("Expected the edited content, got %q", data)
+	}
+
+	// Existing files are edited as they are, directories are refused
+	for _, tt := range []struct {
+		name   string
+		starts bool
+	}{{"b.txt", true}, {"a", false}} {
+		m, _ = pressKey(m, "E")
+		m.prompt.insert([]rune(tt.name))
+		updated, cmd = m.Update(keyMsg("enter"))
+		m = updated.(model)
+		if (cmd != nil) != tt.starts {
+			t.Errorf("%s: expected editor %v, got %q", tt.name, tt.starts, m.statusMsg)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "b.txt")); string(data) != "new b.txt" {
+		t.Errorf("Expected b.txt to be unchanged, got %q", data)
+	}
+}
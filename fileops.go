@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// fileOperation is a copy, move or delete of one or more entries that may
+// still be waiting for the user to confirm it or resolve conflicts
+type fileOperation struct {
+	op     string
+	name   string // Description of the entries for status messages
+	srcDir string
+	dstDir string
+	items  []fileOpItem
+	// Decision applied to all remaining conflicts, "" asks for each one
+	conflictPolicy string
+	symlinks       fs.SymlinkPolicy // How copies treat symbolic links
+}
+
+// fileOpItem is a single entry of a fileOperation
+type fileOpItem struct {
+	entry     fs.FileEntry
+	dstName   string
+	overwrite bool
+	skip      bool
+	done      bool // Completed by an earlier run of the job
+}
+
+// fileOpResultMsg is sent when a file operation is completed
+type fileOpResultMsg struct {
+	jobID             int // Job that ran the operation, 0 for direct actions
+	op                string
+	entryName         string
+	inactivePanelPath string
+	err               error
+}
+
+// handleFileOperation handles file operations (copy, move, delete) on the
+// marked entries, or on the cursor entry if nothing is marked. Deletes and
+// moves to the trash are confirmed first; copies and moves ask for the
+// destination and how to handle existing ones.
+func (m *model) handleFileOperation(op string) tea.Cmd {
+	p := &m.panels[m.activePanel]
+	inactivePanel := &m.panels[(m.activePanel+1)%2]
+
+	targets := p.operationTargets()
+	if len(targets) == 0 {
+		m.statusMsg = "No file selected"
+		return nil
+	}
+
+	fo := &fileOperation{op: op, name: targets[0].Name, srcDir: p.path, dstDir: inactivePanel.path, symlinks: m.symlinks}
+	if len(targets) > 1 {
+		fo.name = fmt.Sprintf("%d entries", len(targets))
+	}
+	for _, entry := range targets {
+		// Entries of a search results panel carry a relative path
+		fo.items = append(fo.items, fileOpItem{entry: entry, dstName: filepath.Base(entry.Name)})
+	}
+
+	switch op {
+	case "trash":
+		m.dialog = newConfirmDialog("Move to trash", fmt.Sprintf("Move %s to trash?", fo.name), func(m *model) tea.Cmd {
+			return m.executeFileOperation(fo)
+		})
+		return nil
+	case "delete":
+		m.dialog = newConfirmDialog("Delete permanently", fmt.Sprintf("Permanently delete %s?", fo.name), func(m *model) tea.Cmd {
+			return m.executeFileOperation(fo)
+		})
+		return nil
+	}
+	m.openDestinationPrompt(fo)
+	return nil
+}
+
+// resolveConflicts asks how to handle every item from index from on whose
+// destination already exists and dispatches the operation once all are decided
+func (m *model) resolveConflicts(fo *fileOperation, from int) tea.Cmd {
+	for i := from; i < len(fo.items); i++ {
+		item := &fo.items[i]
+		if item.skip || item.overwrite {
+			continue
+		}
+		srcPath := filepath.Join(fo.srcDir, item.entry.Name)
+		dstPath := filepath.Join(fo.dstDir, item.dstName)
+		if srcPath == dstPath || fs.SameFile(srcPath, dstPath) {
+			// Refused when the operation runs
+			continue
+		}
+		if _, err := os.Lstat(dstPath); err != nil {
+			continue
+		}
+
+		switch fo.conflictPolicy {
+		case "overwrite":
+			if replacesNonEmptyDir(item.entry, dstPath) {
+				m.dialog = newReplaceDirDialog(fo, i)
+				return nil
+			}
+			item.overwrite = true
+		case "skip":
+			item.skip = true
+		default:
+			m.dialog = newConflictDialog(fo, i)
+			return nil
+		}
+	}
+	return m.executeFileOperation(fo)
+}
+
+// newConflictDialog asks what to do about the existing destination of item i
+func newConflictDialog(fo *fileOperation, i int) *dialog {
+	item := &fo.items[i]
+	message := fmt.Sprintf("%s already exists in\n%s", item.dstName, fo.dstDir)
+	if mergesDir(item.entry, filepath.Join(fo.dstDir, item.dstName)) {
+		message += "\nOverwrite merges the directories"
+	}
+	options := []dialogOption{
+		{label: "[O]verwrite", key: "o"},
+		{label: "[S]kip", key: "s"},
+		{label: "[R]ename", key: "r"},
+		{label: "Overwrite [A]ll", key: "a"},
+		{label: "S[k]ip all", key: "k"},
+		{label: "[C]ancel", key: "c"},
+	}
+	return newDialog("File exists", message, options, len(options)-1, func(m *model, choice int) tea.Cmd {
+		switch choice {
+		case 0:
+			if replacesNonEmptyDir(item.entry, filepath.Join(fo.dstDir, item.dstName)) {
+				m.dialog = newReplaceDirDialog(fo, i)
+				return nil
+			}
+			item.overwrite = true
+		case 1:
+			item.skip = true
+		case 2:
+			m.prompt = newPrompt("New name:", freeName(fo.dstDir, item.dstName), func(m *model, name string) tea.Cmd {
+				if name == "" || strings.ContainsRune(name, filepath.Separator) {
+					m.statusMsg = "Invalid name, operation cancelled"
+					return nil
+				}
+				item.dstName = name
+				return m.resolveConflicts(fo, i)
+			})
+			return nil
+		case 3:
+			fo.conflictPolicy = "overwrite"
+			return m.resolveConflicts(fo, i)
+		case 4:
+			item.skip = true
+			fo.conflictPolicy = "skip"
+		default:
+			m.statusMsg = "Operation cancelled"
+			return nil
+		}
+		return m.resolveConflicts(fo, i+1)
+	})
+}
+
+// newReplaceDirDialog asks before overwriting deletes the non-empty
+// directory in the way of item i, which cannot be merged with it. No skips
+// the item.
+func newReplaceDirDialog(fo *fileOperation, i int) *dialog {
+	item := &fo.items[i]
+	message := fmt.Sprintf("%s in\n%s is a directory that is not empty.\nDelete it with all its contents?", item.dstName, fo.dstDir)
+	options := []dialogOption{{label: "[Y]es", key: "y"}, {label: "[N]o, skip", key: "n"}}
+	d := newDialog("Replace directory", message, options, 1, func(m *model, choice int) tea.Cmd {
+		if choice == 0 {
+			item.overwrite = true
+		} else {
+			item.skip = true
+		}
+		return m.resolveConflicts(fo, i+1)
+	})
+	d.focus = 1
+	return d
+}
+
+// mergesDir reports whether overwriting dstPath with src merges two
+// directories
+func mergesDir(src fs.FileEntry, dstPath string) bool {
+	info, err := os.Lstat(dstPath)
+	return err == nil && info.IsDir() && src.IsDir && src.Link == fs.NoLink
+}
+
+// replacesNonEmptyDir reports whether overwriting dstPath with src deletes a
+// directory that is not empty
+func replacesNonEmptyDir(src fs.FileEntry, dstPath string) bool {
+	info, err := os.Lstat(dstPath)
+	if err != nil || !info.IsDir() || mergesDir(src, dstPath) {
+		return false
+	}
+	entries, err := os.ReadDir(dstPath)
+	return err != nil || len(entries) > 0
+}
+
+// freeName suggests a name like "file (1).txt" that does not exist in dir
+func freeName(dir, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, n, ext)
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// executeFileOperation queues the operation as a background job
+func (m *model) executeFileOperation(fo *fileOperation) tea.Cmd {
+	dest := fo.dstDir
+	if len(fo.items) == 1 && fo.items[0].dstName != filepath.Base(fo.items[0].entry.Name) {
+		dest = filepath.Join(fo.dstDir, fo.items[0].dstName)
+	}
+	switch fo.op {
+	case "copy":
+		m.statusMsg = fmt.Sprintf("Copying: %s -> %s", fo.name, dest)
+	case "move":
+		m.statusMsg = fmt.Sprintf("Moving: %s -> %s", fo.name, dest)
+	case "trash":
+		m.statusMsg = fmt.Sprintf("Moving to trash: %s", fo.name)
+	case "delete":
+		m.statusMsg = fmt.Sprintf("Deleting: %s", fo.name)
+	}
+	m.panels[m.activePanel].clearSelection()
+	return m.enqueueJob(fo)
+}
+
+// runFileOperationItems runs all items of fo that are not skipped or already
+// done and returns the result message. Copies and moves are measured first so
+// that progress can be reported. pauser holds the operation between items
+// and, for copies, between reads.
+func runFileOperationItems(ctx context.Context, fo *fileOperation, pauser *fs.Pauser, report fs.ProgressFunc) fileOpResultMsg {
+	result := fileOpResultMsg{op: fo.op, entryName: fo.name, inactivePanelPath: fo.dstDir}
+
+	var tracker *fs.Tracker
+	if fo.op == "copy" || fo.op == "move" {
+		var paths []string
+		for _, item := range fo.items {
+			if !item.skip && !item.done {
+				paths = append(paths, filepath.Join(fo.srcDir, item.entry.Name))
+			}
+		}
+		var err error
+		if tracker, err = fs.NewMeasuredTracker(ctx, paths, report); err != nil {
+			result.err = err
+			return result
+		}
+		tracker.Pauser = pauser
+		tracker.Flush()
+
+		// The destination prompt may name directories that do not exist yet
+		if err := fs.Mkdir(fo.dstDir); err != nil && !errors.Is(err, os.ErrExist) {
+			result.err = err
+			return result
+		}
+	}
+
+	var errs []error
+	for i := range fo.items {
+		item := &fo.items[i]
+		if item.skip || item.done {
+			continue
+		}
+		err := pauser.Wait(ctx)
+		if err == nil {
+			err = runFileOperation(ctx, fo, *item, tracker)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				result.err = ctx.Err()
+				return result
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", item.entry.Name, err))
+			continue
+		}
+		// A retry of the job leaves out what already succeeded
+		item.done = true
+	}
+	result.err = errors.Join(errs...)
+	return result
+}
+
+// runFileOperation applies the operation to a single item of fo
+func runFileOperation(ctx context.Context, fo *fileOperation, item fileOpItem, tracker *fs.Tracker) error {
+	srcPath := filepath.Join(fo.srcDir, item.entry.Name)
+	dstPath := filepath.Join(fo.dstDir, item.dstName)
+
+	op := fo.op
+	if op == "copy" || op == "move" {
+		// Checked again, the paths may have changed since the conflicts were
+		// resolved
+		if srcPath == dstPath || fs.SameFile(srcPath, dstPath) {
+			return fs.ErrSameFile
+		}
+		if item.overwrite {
+			if err := clearDestination(item.entry, dstPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch op {
+	case "copy":
+		opts := fs.PreserveAll
+		opts.Symlinks = fo.symlinks
+		opts.Merge = item.overwrite
+		return fs.CopyContext(ctx, srcPath, dstPath, tracker, opts)
+	case "move":
+		return fs.MoveContext(ctx, srcPath, dstPath, tracker)
+	case "trash":
+		return fs.MoveToTrash(srcPath)
+	case "delete":
+		if item.entry.IsDir {
+			return fs.DeleteDir(srcPath)
+		}
+		return fs.Delete(srcPath)
+	}
+	return fmt.Errorf("unknown operation: %s", op)
+}
+
+// clearDestination prepares an existing destination that is about to be
+// overwritten. A directory is merged into a directory, and a file replacing
+// a file is truncated by the copy itself. Anything else is removed: types
+// that differ cannot be merged, and writing through an existing link would
+// change its target instead. Non-empty directories have been confirmed by
+// the user.
+func clearDestination(src fs.FileEntry, dstPath string) error {
+	info, err := os.Lstat(dstPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case mergesDir(src, dstPath):
+		return nil
+	case info.IsDir():
+		return fs.DeleteDir(dstPath)
+	case src.IsDir || src.Link != fs.NoLink || info.Mode()&os.ModeSymlink != 0:
+		return fs.Delete(dstPath)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// fileOpsTestModel creates a model with the given files in the left panel
+// directory and returns it together with the right panel directory
+func fileOpsTestModel(t *testing.T, files ...string) (model, string) {
+	t.Helper()
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte("new "+name), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	m := initialModel()
+	m.panels[0].path = srcDir
+	m.panels[0].entries, _ = fs.ReadDir(srcDir)
+	m.panels[1].path = dstDir
+	return m, dstDir
+}
+
+// finishOperation feeds the messages of a running file operation into the
+// model until its result arrives
+func finishOperation(t *testing.T, m model, cmd tea.Cmd) (model, fileOpResultMsg) {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		updated, next := m.Update(msg)
+		m = updated.(model)
+		if result, ok := msg.(fileOpResultMsg); ok {
+			return m, result
+		}
+		cmd = next
+	}
+	t.Fatal("File operation ended without a result")
+	return m, fileOpResultMsg{}
+}
+
+// pressKey sends a rune key to the model
+func pressKey(m model, key string) (model, tea.Cmd) {
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return updated.(model), cmd
+}
+
+// acceptDestination submits the destination prompt of a copy or move as it is
+func acceptDestination(t *testing.T, m model) (model, tea.Cmd) {
+	t.Helper()
+	if m.prompt == nil {
+		t.Fatal("Expected the destination prompt")
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return updated.(model), cmd
+}
+
+func TestDelete_RequiresConfirmation(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "victim.txt")
+	victim := filepath.Join(m.panels[0].path, "victim.txt")
+
+	m, cmd := pressKey(m, "D")
+	if cmd != nil || m.dialog == nil {
+		t.Fatal("Delete should open a confirmation dialog instead of running")
+	}
+
+	m, cmd = pressKey(m, "n")
+	if cmd != nil || m.dialog != nil {
+		t.Fatal("Answering no should close the dialog without running")
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Fatal("File should still exist after declining")
+	}
+
+	m, _ = pressKey(m, "D")
+	m, cmd = pressKey(m, "y")
+	if cmd == nil {
+		t.Fatal("Confirming should run the delete")
+	}
+	if _, msg := finishOperation(t, m, cmd); msg.err != nil {
+		t.Fatalf("Delete failed: %v", msg.err)
+	}
+	if _, err := os.Stat(victim); !os.IsNotExist(err) {
+		t.Error("File should be deleted after confirming")
+	}
+}
+
+func TestCopy_ConflictChoices(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{"o", "new a.txt"},
+		{"s", "old"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			m, dstDir := fileOpsTestModel(t, "a.txt")
+			if err := os.WriteFile(filepath.Join(dstDir, "a.txt"), []byte("old"), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+
+			m, _ = pressKey(m, "c")
+			m, cmd := acceptDestination(t, m)
+			if cmd != nil || m.dialog == nil {
+				t.Fatal("Existing destination should open the conflict dialog")
+			}
+			m, cmd = pressKey(m, tc.key)
+			if cmd == nil {
+				t.Fatal("Choosing an option should run the operation")
+			}
+			finishOperation(t, m, cmd)
+
+			content, _ := os.ReadFile(filepath.Join(dstDir, "a.txt"))
+			if string(content) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, content)
+			}
+		})
+	}
+}
+
+func TestCopy_ConflictRename(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt")
+	if err := os.WriteFile(filepath.Join(dstDir, "a.txt"), []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	m, _ = pressKey(m, "c")
+	m, _ = acceptDestination(t, m)
+	m, _ = pressKey(m, "r")
+	if m.prompt == nil || m.prompt.text() != "a (1).txt" {
+		t.Fatalf("Rename should prompt with a free name, got %v", m.prompt)
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Submitting the new name should run the operation")
+	}
+	finishOperation(t, updated.(model), cmd)
+
+	if content, _ := os.ReadFile(filepath.Join(dstDir, "a (1).txt")); string(content) != "new a.txt" {
+		t.Errorf("Expected renamed copy, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dstDir, "a.txt")); string(content) != "old" {
+		t.Errorf("Existing file should be untouched, got %q", content)
+	}
+}
+
+func TestCopy_ConflictOverwriteAllAndCancel(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt", "b.txt", "c.txt")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dstDir, name), []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	m.panels[0].invertSelection()
+
+	cancelled, _ := pressKey(m, "c")
+	cancelled, _ = acceptDestination(t, cancelled)
+	cancelled, cmd := pressKey(cancelled, "c")
+	if cmd != nil || cancelled.dialog != nil {
+		t.Fatal("Cancel should abort without running")
+	}
+
+	m, _ = pressKey(m, "c")
+	m, _ = acceptDestination(t, m)
+	m, cmd = pressKey(m, "a")
+	if cmd == nil {
+		t.Fatal("Overwrite all should resolve all conflicts and run")
+	}
+	finishOperation(t, m, cmd)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if content, _ := os.ReadFile(filepath.Join(dstDir, name)); string(content) != "new "+name {
+			t.Errorf("Expected %s to be overwritten, got %q", name, content)
+		}
+	}
+}
+
+func TestFreeName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x (1).tar"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if name := freeName(dir, "x.tar"); name != "x (2).tar" {
+		t.Errorf("Expected x (2).tar, got %s", name)
+	}
+}
+
+func TestRunFileOperation_SamePath(t *testing.T) {
+	dir := t.TempDir()
+	item := fileOpItem{entry: fs.FileEntry{Name: "a.txt"}, dstName: "a.txt"}
+	if err := runFileOperation(context.Background(), &fileOperation{op: "copy", srcDir: dir, dstDir: dir}, item, nil); err == nil {
+		t.Error("Copying a file onto itself should fail")
+	}
+}
+
+func TestCopy_OverwriteMergesDirectories(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t)
+	srcDir := m.panels[0].path
+	for dir, files := range map[string][]string{srcDir: {"d/a.txt", "d/sub/b.txt"}, dstDir: {"d/a.txt", "d/keep.txt", "d/sub/c.txt"}} {
+		for _, name := range files {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(dir), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+		}
+	}
+	m = readPanel(m, 0)
+
+	m, _ = pressKey(m, "c")
+	m, _ = acceptDestination(t, m)
+	m, cmd := pressKey(m, "o")
+	if _, msg := finishOperation(t, m, cmd); msg.err != nil {
+		t.Fatalf("Copy failed: %v", msg.err)
+	}
+	for name, want := range map[string]string{"a.txt": srcDir, "keep.txt": dstDir, "sub/b.txt": srcDir, "sub/c.txt": dstDir} {
+		if data, err := os.ReadFile(filepath.Join(dstDir, "d", name)); err != nil || string(data) != want {
+			t.Errorf("Expected %s from %s, got %q, %v", name, want, data, err)
+		}
+	}
+}
+
+func TestCopy_OverwriteAsksBeforeDeletingDirectory(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "d")
+	if err := os.MkdirAll(filepath.Join(dstDir, "d", "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	m, _ = pressKey(m, "c")
+	m, _ = acceptDestination(t, m)
+	m, cmd := pressKey(m, "o")
+	if cmd != nil || m.dialog == nil || m.dialog.title != "Replace directory" {
+		t.Fatal("Replacing a non-empty directory with a file should ask again")
+	}
+	m, cmd = pressKey(m, "n")
+	if cmd == nil {
+		t.Fatal("Declining should skip the entry and run the operation")
+	}
+	finishOperation(t, m, cmd)
+	if _, err := os.Stat(filepath.Join(dstDir, "d", "sub")); err != nil {
+		t.Errorf("Expected the directory kept: %v", err)
+	}
+
+	m, _ = pressKey(m, "c")
+	m, _ = acceptDestination(t, m)
+	m, _ = pressKey(m, "a")
+	m, cmd = pressKey(m, "y")
+	finishOperation(t, m, cmd)
+	if data, err := os.ReadFile(filepath.Join(dstDir, "d")); err != nil || string(data) != "new d" {
+		t.Errorf("Expected the directory replaced by the file, got %q, %v", data, err)
+	}
+}
+
+func TestCopy_SameFileThroughOtherPath(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(srcDir, dstDir string) error
+	}{
+		{"hard link", func(srcDir, dstDir string) error {
+			return os.Link(filepath.Join(srcDir, "a.txt"), filepath.Join(dstDir, "a.txt"))
+		}},
+		{"linked parent", func(srcDir, dstDir string) error {
+			return os.Symlink(srcDir, filepath.Join(dstDir, "link"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, dstDir := fileOpsTestModel(t, "a.txt")
+			srcDir := m.panels[0].path
+			if err := tt.link(srcDir, dstDir); err != nil {
+				t.Fatalf("Failed to link: %v", err)
+			}
+			if tt.name == "linked parent" {
+				m.panels[1].path = filepath.Join(dstDir, "link")
+			}
+
+			m, _ = pressKey(m, "c")
+			m, cmd := acceptDestination(t, m)
+			if m.dialog != nil {
+				t.Fatal("Expected no overwrite question for the source itself")
+			}
+			if _, msg := finishOperation(t, m, cmd); !errors.Is(msg.err, fs.ErrSameFile) {
+				t.Errorf("Expected fs.ErrSameFile, got %v", msg.err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(srcDir, "a.txt")); string(data) != "new a.txt" {
+				t.Errorf("Expected the source untouched, got %q", data)
+			}
+
+			// The job refuses it too once the question was answered
+			item := fileOpItem{entry: fs.FileEntry{Name: "a.txt"}, dstName: "a.txt", overwrite: true}
+			fo := &fileOperation{op: "copy", srcDir: srcDir, dstDir: m.panels[1].path}
+			if err := runFileOperation(context.Background(), fo, item, nil); !errors.Is(err, fs.ErrSameFile) {
+				t.Errorf("Expected fs.ErrSameFile from the job, got %v", err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(srcDir, "a.txt")); string(data) != "new a.txt" {
+				t.Errorf("Expected the source untouched, got %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// filterMode selects how the quick filter text is matched against names
+type filterMode int
+
+const (
+	filterSubstring filterMode = iota // Text anywhere in the name
+	filterGlob                        // Wildcard pattern, as a substring without wildcards
+	filterFuzzy                       // Characters of the text in order
+	filterModeCount
+)
+
+func (f filterMode) String() string {
+	switch f {
+	case filterGlob:
+		return "glob"
+	case filterFuzzy:
+		return "fuzzy"
+	}
+	return "substring"
+}
+
+var (
+	filterStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#00FFFF"))
+
+	// Applied on top of the line style to the matched characters
+	matchStyle = lipgloss.NewStyle().Underline(true).Bold(true)
+)
+
+// quickFilter narrows a panel to the entries matching the typed text
+type quickFilter struct {
+	text    []rune
+	mode    filterMode
+	editing bool // Typed keys go to the filter
+}
+
+// match reports whether name matches the filter and returns the rune indices
+// of the matched characters. An empty filter matches everything.
+func (f *quickFilter) match(name string) ([]int, bool) {
+	if len(f.text) == 0 {
+		return nil, true
+	}
+	return matchFilter(f.mode, string(f.text), name)
+}
+
+// matchFilter matches name against text, ignoring case
+func matchFilter(mode filterMode, text, name string) ([]int, bool) {
+	switch mode {
+	case filterGlob:
+		if !fs.HasWildcard(text) {
+			text = "*" + text + "*"
+		}
+		return fs.MatchWildcardPositions(text, name)
+	case filterFuzzy:
+		return matchFuzzy(text, name)
+	}
+	return matchSubstring(text, name)
+}
+
+func matchSubstring(text, name string) ([]int, bool) {
+	t, n := fs.LowerRunes(text), fs.LowerRunes(name)
+	for start := 0; start+len(t) <= len(n); start++ {
+		if string(n[start:start+len(t)]) == string(t) {
+			positions := make([]int, len(t))
+			for i := range t {
+				positions[i] = start + i
+			}
+			return positions, true
+		}
+	}
+	return nil, false
+}
+
+// matchFuzzy matches the characters of text in order, taking the earliest
+// occurrence of each
+func matchFuzzy(text, name string) ([]int, bool) {
+	t, n := fs.LowerRunes(text), fs.LowerRunes(name)
+	var positions []int
+	ni := 0
+	for _, r := range t {
+		for ni < len(n) && n[ni] != r {
+			ni++
+		}
+		if ni == len(n) {
+			return nil, false
+		}
+		positions = append(positions, ni)
+		ni++
+	}
+	return positions, true
+}
+
+// matchPositions returns the matched characters of entry for highlighting,
+// or nil without a filter
+func (p *panel) matchPositions(entry fs.FileEntry) []int {
+	if p.filter == nil {
+		return nil
+	}
+	positions, _ := p.filter.match(entry.Name)
+	return positions
+}
+
+// openFilter starts editing the quick filter of the active panel, keeping
+// the current text
+func (m *model) openFilter() {
+	p := &m.panels[m.activePanel]
+	if p.filter == nil {
+		p.filter = &quickFilter{}
+	}
+	p.filter.editing = true
+}
+
+// clearFilter removes the quick filter and keeps the cursor on its entry
+func (p *panel) clearFilter() {
+	p.filter = nil
+	p.ensureCursorVisible()
+}
+
+// updateFilter handles the keys typed while editing the quick filter.
+// Navigation and other keys are left to the panel.
+func (m *model) updateFilter(msg tea.KeyMsg) (handled bool, cmd tea.Cmd) {
+	p := &m.panels[m.activePanel]
+	f := p.filter
+	if msg.Alt {
+		return false, nil
+	}
+	// The filter key cycles the mode, unless it is a character to type
+	if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace && m.keys.lookup(msg.String()) == actionFilter {
+		f.mode = (f.mode + 1) % filterModeCount
+		p.ensureCursorVisible()
+		return true, nil
+	}
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		f.text = append(f.text, msg.Runes...)
+	case tea.KeyBackspace:
+		if len(f.text) > 0 {
+			f.text = f.text[:len(f.text)-1]
+		}
+	case tea.KeyEnter:
+		f.editing = false
+		if len(f.text) == 0 {
+			p.filter = nil
+		}
+		return true, nil
+	case tea.KeyEsc:
+		p.clearFilter()
+		return true, nil
+	default:
+		return false, nil
+	}
+	p.ensureCursorVisible()
+	return true, nil
+}
+
+// filterFooter describes the filter and how many entries it lets through
+func (p *panel) filterFooter() string {
+	f := p.filter
+	shown, total := 0, 0
+	for _, entry := range p.entries {
+		if p.showHidden || !strings.HasPrefix(entry.Name, ".") {
+			total++
+			if p.isVisible(entry) {
+				shown++
+			}
+		}
+	}
+	cursor := ""
+	if f.editing {
+		cursor = "_"
+	}
+	return filterStyle.Render(fmt.Sprintf(" Filter [%s]: %s%s (%d/%d)", f.mode, string(f.text), cursor, shown, total))
+}
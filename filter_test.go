@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestMatchFilter(t *testing.T) {
+	testCases := []struct {
+		mode      filterMode
+		text      string
+		name      string
+		positions []int
+		match     bool
+	}{
+		{filterSubstring, "port", "Report.txt", []int{2, 3, 4, 5}, true},
+		{filterSubstring, "PORT", "report.txt", []int{2, 3, 4, 5}, true},
+		{filterSubstring, "rpt", "report.txt", nil, false},
+		{filterGlob, "*.go", "main.go", []int{4, 5, 6}, true},
+		{filterGlob, "*.go", "main.go.bak", nil, false},
+		{filterGlob, "ain", "main.go", []int{1, 2, 3}, true},
+		{filterGlob, "m?in*", "main.go", []int{0, 1, 2, 3}, true},
+		// Patterns with wildcards match the whole name
+		{filterGlob, "m?in", "main.go", nil, false},
+		{filterFuzzy, "rpt", "report.txt", []int{0, 2, 5}, true},
+		{filterFuzzy, "mgo", "main.go", []int{0, 5, 6}, true},
+		{filterFuzzy, "ogm", "main.go", nil, false},
+		{filterFuzzy, "üb", "Übung", []int{0, 1}, true},
+	}
+
+	for _, tc := range testCases {
+		positions, ok := matchFilter(tc.mode, tc.text, tc.name)
+		if ok != tc.match {
+			t.Errorf("matchFilter(%s, %q, %q) matched = %v, expected %v", tc.mode, tc.text, tc.name, ok, tc.match)
+			continue
+		}
+		if ok && fmt.Sprint(positions) != fmt.Sprint(tc.positions) {
+			t.Errorf("matchFilter(%s, %q, %q) = %v, expected %v", tc.mode, tc.text, tc.name, positions, tc.positions)
+		}
+	}
+}
+
+func TestQuickFilter_NarrowsAndKeepsCursorOnMatch(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "alpha.txt", "beta.go", "gamma.go", ".hidden.go")
+	m = pressKeys(t, m, "ctrl+f")
+	m = typeText(t, m, ".go")
+
+	p := &m.panels[0]
+	var visible []string
+	for _, entry := range p.entries {
+		if p.isVisible(entry) {
+			visible = append(visible, entry.Name)
+		}
+	}
+	// Hidden files stay hidden while filtering
+	if strings.Join(visible, ",") != "beta.go,gamma.go" {
+		t.Errorf("Expected beta.go and gamma.go to be visible, got %v", visible)
+	}
+	if name := p.entries[p.cursor].Name; name != "beta.go" {
+		t.Errorf("Expected cursor on beta.go, got %s", name)
+	}
+
+	// Navigation skips filtered-out entries
+	m = pressKeys(t, m, "down")
+	p = &m.panels[0]
+	if name := p.entries[p.cursor].Name; name != "gamma.go" {
+		t.Errorf("Expected cursor on gamma.go after down, got %s", name)
+	}
+
+	// No match leaves nothing to operate on
+	m = typeText(t, m, "x")
+	if targets := m.panels[0].operationTargets(); targets != nil {
+		t.Errorf("Expected no targets without visible entries, got %v", targets)
+	}
+}
+
+func TestQuickFilter_EnterKeepsEscClears(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "alpha.txt", "beta.go")
+	m = pressKeys(t, m, "ctrl+f")
+	m = typeText(t, m, "be")
+	m = pressKeys(t, m, "enter")
+
+	p := &m.panels[0]
+	if p.filter == nil || p.filter.editing {
+		t.Fatal("Enter should keep the filter and stop editing")
+	}
+	// Keys act on the panel again
+	m, _ = pressKey(m, "h")
+	if !m.panels[0].showHidden {
+		t.Error("Expected h to toggle hidden files after leaving the filter")
+	}
+	if string(m.panels[0].filter.text) != "be" {
+		t.Errorf("Expected filter text to stay \"be\", got %q", string(m.panels[0].filter.text))
+	}
+
+	m = pressKeys(t, m, "esc")
+	p = &m.panels[0]
+	if p.filter != nil {
+		t.Fatal("Esc should clear the filter")
+	}
+	if name := p.entries[p.cursor].Name; name != "beta.go" {
+		t.Errorf("Expected cursor to stay on beta.go, got %s", name)
+	}
+}
+
+func TestQuickFilter_CycleModes(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "report.txt", "main.go")
+	m = pressKeys(t, m, "ctrl+f")
+	m = typeText(t, m, "rpt")
+	if m.panels[0].isVisible(m.panels[0].entries[m.panels[0].indexOf("report.txt")]) {
+		t.Error("Substring filter should not match report.txt")
+	}
+
+	// substring -> glob -> fuzzy
+	m = pressKeys(t, m, "ctrl+f")
+	m = pressKeys(t, m, "ctrl+f")
+	p := &m.panels[0]
+	if p.filter.mode != filterFuzzy {
+		t.Fatalf("Expected fuzzy mode, got %s", p.filter.mode)
+	}
+	if name := p.entries[p.cursor].Name; name != "report.txt" {
+		t.Errorf("Expected cursor on report.txt, got %s", name)
+	}
+	if footer := p.filterFooter(); !strings.Contains(footer, "Filter [fuzzy]: rpt_ (1/2)") {
+		t.Errorf("Unexpected filter footer %q", footer)
+	}
+}
+
+func TestNameCell_HighlightPositions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		width     int
+		match     []int
+		highlight string
+	}{
+		{"main.go", 20, []int{1, 2}, "ai"},
+		// The extension is kept when the name is shortened
+		{"a-very-long-name.txt", 16, []int{0, 17, 18, 19}, "atxt"},
+		// Characters cut from the name are not highlighted
+		{"a-very-long-name.txt", 16, []int{12, 13}, ""},
+	}
+
+	for _, tc := range testCases {
+		cell, highlight := nameCell(fs.FileEntry{Name: tc.name}, tc.width, tc.match)
+		runes := []rune(cell)
+		var got strings.Builder
+		for _, i := range highlight {
+			got.WriteRune(runes[i])
+		}
+		if got.String() != tc.highlight {
+			t.Errorf("nameCell(%q, %d) highlights %q in %q, expected %q", tc.name, tc.width, got.String(), cell, tc.highlight)
+		}
+	}
+}
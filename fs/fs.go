@@ -1,48 +1,106 @@
 package fs
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LinkType tells whether a FileEntry is a symbolic link and what it points to
+type LinkType int
+
+const (
+	NoLink     LinkType = iota
+	FileLink            // Link to a file or other non-directory
+	DirLink             // Link to a directory
+	BrokenLink          // Link whose target does not exist or cannot be resolved
 )
 
 type FileEntry struct {
-	Name  string
-	IsDir bool
-	Size  int64
+	Name       string
+	IsDir      bool // For links, whether the target is a directory
+	Size       int64
+	Mode       os.FileMode // Of the entry itself, not of a link target
+	ModTime    time.Time
+	Uid        int
+	Gid        int
+	Link       LinkType
+	LinkTarget string // Target as stored in the link, possibly relative
+}
+
+// newFileEntry describes entry of dir, resolving symbolic links
+func newFileEntry(dir string, entry os.DirEntry) FileEntry {
+	fileEntry := FileEntry{Name: entry.Name(), IsDir: entry.IsDir()}
+	if info, err := entry.Info(); err == nil {
+		fileEntry.Size = info.Size()
+		fileEntry.Mode = info.Mode()
+		fileEntry.ModTime = info.ModTime()
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			fileEntry.Uid = int(st.Uid)
+			fileEntry.Gid = int(st.Gid)
+		}
+	}
+	if entry.Type()&os.ModeSymlink == 0 {
+		return fileEntry
+	}
+
+	path := filepath.Join(dir, entry.Name())
+	fileEntry.LinkTarget, _ = os.Readlink(path)
+	target, err := os.Stat(path)
+	if err != nil {
+		fileEntry.Link = BrokenLink
+		return fileEntry
+	}
+	fileEntry.IsDir = target.IsDir()
+	fileEntry.Size = target.Size()
+	if fileEntry.IsDir {
+		fileEntry.Link = DirLink
+	} else {
+		fileEntry.Link = FileLink
+	}
+	return fileEntry
 }
 
+// ReadDir lists a directory with directories first, then by name
 func ReadDir(path string) ([]FileEntry, error) {
-	entries, err := os.ReadDir(path)
+	return ReadDirSorted(path, SortOptions{})
+}
+
+// ReadDirSorted lists a directory in the order selected by opts
+func ReadDirSorted(path string, opts SortOptions) ([]FileEntry, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	// Unlike os.ReadDir, File.ReadDir keeps the file system order
+	entries, err := dir.ReadDir(-1)
 	if err != nil {
 		return nil, err
 	}
 
 	var files []FileEntry
 	for _, entry := range entries {
-		info, _ := entry.Info()
-		files = append(files, FileEntry{
-			Name:  entry.Name(),
-			IsDir: entry.IsDir(),
-			Size:  info.Size(),
-		})
+		files = append(files, newFileEntry(path, entry))
 	}
-
-	// Sort: directories first, then alphabetically
-	sort.Slice(files, func(i, j int) bool {
-		if files[i].IsDir != files[j].IsDir {
-			return files[i].IsDir
-		}
-		return files[i].Name < files[j].Name
-	})
-
+	SortEntries(files, opts)
 	return files, nil
 }
 
 // Copy copies a file from src to dst
 func Copy(src, dst string) error {
+	return copyFile(context.Background(), src, dst, nil, CopyOptions{})
+}
+
+// copyFile copies a single file and the metadata selected by opts. If the
+// copy fails or ctx is cancelled, the partially written destination is removed.
+func copyFile(ctx context.Context, src, dst string, tracker *Tracker, opts CopyOptions) error {
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -53,39 +111,289 @@ func Copy(src, dst string) error {
 	if err != nil {
 		return err
 	}
+	// Truncating the destination would empty the source
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return ErrSameFile
+	}
 
 	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
 	if err != nil {
 		return err
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	tracker.startFile(src)
+	_, err = io.Copy(dstFile, &contextReader{ctx: ctx, r: srcFile, tracker: tracker})
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && opts.preservesMetadata() {
+		err = preserveMetadata(src, dst, srcInfo, opts)
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	tracker.finishFile()
+	return nil
+}
+
+// contextReader stops reading once ctx is cancelled, holds while the tracker
+// is paused and reports the bytes read
+type contextReader struct {
+	ctx     context.Context
+	r       io.Reader
+	tracker *Tracker
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.tracker.wait(r.ctx); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	r.tracker.addBytes(int64(n))
+	return n, err
 }
 
 // Move moves a file or directory from src to dst
 func Move(src, dst string) error {
+	return MoveContext(context.Background(), src, dst, nil)
+}
+
+// MoveContext moves a file or directory from src to dst. Across partitions,
+// or onto an existing directory that is not empty, it copies with all
+// metadata and deletes, reporting to tracker (which may be nil). A directory
+// is merged into an existing one. If ctx is cancelled during the copy, the
+// source is left untouched.
+func MoveContext(ctx context.Context, src, dst string, tracker *Tracker) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Try to rename the file (only works on the same partition)
 	err := os.Rename(src, dst)
 	if err == nil {
+		tracker.skip(src, dst)
 		return nil
 	}
 
-	// If rename fails (e.g. different partitions), copy and delete
-	if err := Copy(src, dst); err != nil {
+	// Copy and delete only where a rename cannot work: across file systems,
+	// and onto a directory that is not empty, which the copy merges into.
+	// Anything else, like a missing permission, would fail the copy as well
+	// or leave it half done. Links are moved as links, like a rename does.
+	srcInfo, statErr := os.Lstat(src)
+	if statErr != nil {
+		return err
+	}
+	merging := srcInfo.IsDir() && (errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST))
+	if !errors.Is(err, syscall.EXDEV) && !merging {
+		return err
+	}
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		if err := copySymlink(src, dst, tracker, PreserveAll); err != nil {
+			return err
+		}
+		return os.Remove(src)
+	}
+	if srcInfo.IsDir() {
+		opts := PreserveAll
+		opts.Merge = true
+		if err := copyDir(ctx, src, dst, tracker, opts); err != nil {
+			return err
+		}
+		return os.RemoveAll(src)
+	}
+	if err := copyFile(ctx, src, dst, tracker, PreserveAll); err != nil {
 		return err
 	}
 	return os.Remove(src)
 }
 
+// renameIfFree renames src to dst unless dst exists, for systems that cannot
+// check and rename in one step. Something may still appear at dst in between.
+func renameIfFree(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: os.ErrExist}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// moveNoReplace moves src to dst and fails with an error matching
+// os.ErrExist if dst exists, also when it appears while a move to another
+// file system copies. The copy goes to a temporary directory next to dst and
+// is renamed into place, so it never merges into or replaces dst.
+func moveNoReplace(src, dst string) error {
+	err := renameNoReplace(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dst), ".move-")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(tmpDir, filepath.Base(dst))
+	err = CopyContext(context.Background(), src, tmp, nil, PreserveAll)
+	if err == nil {
+		err = renameNoReplace(tmp, dst)
+	}
+	if removeErr := os.RemoveAll(tmpDir); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
 // Delete deletes a file or directory
 func Delete(path string) error {
 	return os.Remove(path)
 }
 
+// Mkdir creates the directory path along with any missing parents. It fails
+// with os.ErrExist if path already exists.
+func Mkdir(path string) error {
+	err := os.Mkdir(path, 0755)
+	if errors.Is(err, os.ErrNotExist) {
+		return os.MkdirAll(path, 0755)
+	}
+	return err
+}
+
+// CreateFile creates an empty file. It fails with os.ErrExist if path already
+// exists.
+func CreateFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ErrSameFile is returned when source and destination are the same file
+var ErrSameFile = errors.New("source and destination are the same file")
+
+// SameFile reports whether the paths a and b lead to the same file, following
+// symbolic links, so that different paths through a linked directory or hard
+// links are recognised. Paths that cannot be read are not the same.
+func SameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	return err == nil && os.SameFile(aInfo, bInfo)
+}
+
+// Contains reports whether path lies inside dir. Symbolic links in the
+// parents of both are resolved, so a path that reaches dir through a linked
+// directory is inside it too, while a link named by path or dir itself is
+// taken as it is.
+func Contains(dir, path string) bool {
+	dir, path = resolveParent(dir), resolveParent(path)
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// resolveParent resolves the symbolic links in the parent of path. Missing
+// directories at its end, which an operation may create, are kept as named.
+func resolveParent(path string) string {
+	path = filepath.Clean(path)
+	dir, rest := filepath.Dir(path), filepath.Base(path)
+	for {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		dir, rest = parent, filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// ExistingDir returns path if it is an existing directory, or otherwise its
+// closest ancestor that is
+func ExistingDir(path string) string {
+	for {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
 // CopyDir copies a directory recursively
 func CopyDir(src, dst string) error {
+	return copyDir(context.Background(), src, dst, nil, CopyOptions{})
+}
+
+// CopyContext copies a file or directory tree from src to dst with the
+// metadata and symbolic link handling selected by opts, reporting to tracker
+// (which may be nil) and stopping when ctx is cancelled
+func CopyContext(ctx context.Context, src, dst string, tracker *Tracker, opts CopyOptions) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Mode()&os.ModeSymlink != 0 {
+		switch opts.Symlinks {
+		case SymlinkSkip:
+			tracker.finishFile()
+			return nil
+		case SymlinkFollow:
+			if srcInfo, err = os.Stat(src); err != nil {
+				// A broken link cannot be followed, keep it as it is
+				return copySymlink(src, dst, tracker, opts)
+			}
+		default:
+			return copySymlink(src, dst, tracker, opts)
+		}
+	}
+	if srcInfo.IsDir() {
+		return copyDir(ctx, src, dst, tracker, opts)
+	}
+	return copyFile(ctx, src, dst, tracker, opts)
+}
+
+// copySymlink creates a link at dst with the same target as the link src
+func copySymlink(src, dst string, tracker *Tracker, opts CopyOptions) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	if opts.Owner {
+		if info, err := os.Lstat(src); err == nil {
+			if st, ok := info.Sys().(*syscall.Stat_t); ok {
+				// Best effort, like for regular files
+				if err := os.Lchown(dst, int(st.Uid), int(st.Gid)); err != nil && !errors.Is(err, os.ErrPermission) {
+					return err
+				}
+			}
+		}
+	}
+	tracker.finishFile()
+	return nil
+}
+
+// copyDir copies a directory tree. The metadata of each directory is applied
+// after its contents, so that read-only directories can be filled and their
+// times are not changed by creating the entries.
+func copyDir(ctx context.Context, src, dst string, tracker *Tracker, opts CopyOptions) error {
+	return copyTree(ctx, src, dst, tracker, opts, nil)
+}
+
+// copyTree implements copyDir. ancestors are the source directories being
+// copied above src; a followed link leading back to one of them would recurse
+// forever and is copied as a link instead.
+func copyTree(ctx context.Context, src, dst string, tracker *Tracker, opts CopyOptions, ancestors []os.FileInfo) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
@@ -94,8 +402,13 @@ func CopyDir(src, dst string) error {
 		return fmt.Errorf("src is not a directory: %s", src)
 	}
 
-	// Create destination directory if it doesn't exist
-	err = os.MkdirAll(dst, srcInfo.Mode())
+	// Create destination directory if it doesn't exist. With the exact mode
+	// applied afterwards, the owner needs write access in the meantime.
+	perm := srcInfo.Mode().Perm()
+	if opts.Mode {
+		perm |= 0700
+	}
+	err = os.MkdirAll(dst, perm)
 	if err != nil {
 		return err
 	}
@@ -104,68 +417,94 @@ func CopyDir(src, dst string) error {
 	if err != nil {
 		return err
 	}
+	ancestors = append(ancestors, srcInfo)
 
 	for _, entry := range entries {
+		if err := tracker.wait(ctx); err != nil {
+			return err
+		}
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
-		if entry.IsDir() {
-			err = CopyDir(srcPath, dstPath)
-			if err != nil {
-				return err
+		isDir, asLink := entry.IsDir(), false
+		if entry.Type()&os.ModeSymlink != 0 {
+			switch opts.Symlinks {
+			case SymlinkSkip:
+				tracker.finishFile()
+				continue
+			case SymlinkFollow:
+				target, err := os.Stat(srcPath)
+				// Broken links and links back into the tree stay links
+				asLink = err != nil || (target.IsDir() && isAncestor(target, ancestors))
+				isDir = err == nil && target.IsDir()
+			default:
+				asLink = true
 			}
-		} else {
-			// Check if destination file already exists
-			if _, err := os.Stat(dstPath); err == nil {
-				return fmt.Errorf("destination file already exists: %s", dstPath)
+		}
+
+		isDir = isDir && !asLink
+		if opts.Merge {
+			if err := clearForMerge(dstPath, isDir); err != nil {
+				return err
 			}
-			err = Copy(srcPath, dstPath)
+		}
+		if isDir {
+			err = copyTree(ctx, srcPath, dstPath, tracker, opts, ancestors)
 			if err != nil {
 				return err
 			}
+			continue
+		}
+		// Check if destination file already exists
+		if _, err := os.Lstat(dstPath); err == nil {
+			return fmt.Errorf("destination file already exists: %s", dstPath)
+		}
+		if asLink {
+			err = copySymlink(srcPath, dstPath, tracker, opts)
+		} else {
+			err = copyFile(ctx, srcPath, dstPath, tracker, opts)
+		}
+		if err != nil {
+			return err
 		}
 	}
+	if opts.preservesMetadata() {
+		return preserveMetadata(src, dst, srcInfo, opts)
+	}
 	return nil
 }
 
-// DeleteDir deletes a directory recursively
-func DeleteDir(path string) error {
-	return os.RemoveAll(path)
-}
-
-// ReadDirRecursive reads a directory recursively (for future use)
-func ReadDirRecursive(path string, depth int) ([]FileEntry, error) {
-	entries, err := os.ReadDir(path)
+// clearForMerge makes room at path for an entry merged into a directory. An
+// existing directory stays when a directory is merged into it; anything else
+// in the way is removed, directories only when empty.
+func clearForMerge(path string, dir bool) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	var files []FileEntry
-	for _, entry := range entries {
-		info, _ := entry.Info()
-		isDir := entry.IsDir()
-		files = append(files, FileEntry{
-			Name:  entry.Name(),
-			IsDir: isDir,
-			Size:  info.Size(),
-		})
-
-		// Recursive for directories (up to a certain depth)
-		if isDir && depth > 0 {
-			subEntries, err := ReadDirRecursive(filepath.Join(path, entry.Name()), depth-1)
-			if err != nil {
-				continue
-			}
-			files = append(files, subEntries...)
-		}
+	if dir && info.IsDir() {
+		return nil
 	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("cannot replace %s: %w", path, err)
+	}
+	return nil
+}
 
-	sort.Slice(files, func(i, j int) bool {
-		if files[i].IsDir != files[j].IsDir {
-			return files[i].IsDir
+// isAncestor reports whether dir is one of ancestors
+func isAncestor(dir os.FileInfo, ancestors []os.FileInfo) bool {
+	for _, ancestor := range ancestors {
+		if os.SameFile(dir, ancestor) {
+			return true
 		}
-		return files[i].Name < files[j].Name
-	})
+	}
+	return false
+}
 
-	return files, nil
+// DeleteDir deletes a directory recursively
+func DeleteDir(path string) error {
+	return os.RemoveAll(path)
 }
@@ -1,8 +1,11 @@
 package fs
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -204,6 +207,69 @@ func TestDelete_NonExistent(t *testing.T) {
 	}
 }
 
+func TestMkdir(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Missing parents are created too
+	path := filepath.Join(tmpDir, "a", "b", "c")
+	if err := Mkdir(path); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		t.Errorf("Expected directory %s: %v", path, err)
+	}
+
+	if err := Mkdir(path); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected os.ErrExist for an existing directory, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := Mkdir(filepath.Join(tmpDir, "file", "sub")); err == nil {
+		t.Error("Expected error for a parent that is a file")
+	}
+}
+
+func TestCreateFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "new.txt")
+
+	if err := CreateFile(path); err != nil {
+		t.Fatalf("CreateFile failed: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("Expected empty file %s: %v", path, err)
+	}
+	if err := CreateFile(path); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected os.ErrExist for an existing file, got %v", err)
+	}
+	if err := CreateFile(filepath.Join(tmpDir, "missing", "new.txt")); err == nil {
+		t.Error("Expected error for a missing parent directory")
+	}
+}
+
+func TestExistingDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "file"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{tmpDir, tmpDir},
+		{filepath.Join(tmpDir, "a", "b"), tmpDir},
+		{filepath.Join(tmpDir, "file"), tmpDir},
+		{"/", "/"},
+	}
+	for _, tt := range tests {
+		if got := ExistingDir(tt.path); got != tt.want {
+			t.Errorf("ExistingDir(%q) = %q, expected %q", tt.path, got, tt.want)
+		}
+	}
+}
+
 func TestCopyDir(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -322,100 +388,262 @@ func TestDeleteDir(t *testing.T) {
 	}
 }
 
-func TestReadDirRecursive(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestFileEntry(t *testing.T) {
+	entry := FileEntry{
+		Name:  "test.txt",
+		IsDir: false,
+		Size:  1234,
+	}
 
-	// Create Verzeichnisstruktur
-	if err := os.MkdirAll(filepath.Join(tmpDir, "dir1", "subdir1"), 0755); err != nil {
-		t.Fatalf("Failed to create directory structure: %v", err)
+	if entry.Name != "test.txt" {
+		t.Errorf("Expected Name 'test.txt', got '%s'", entry.Name)
 	}
-	if err := os.MkdirAll(filepath.Join(tmpDir, "dir2"), 0755); err != nil {
-		t.Fatalf("Failed to create directory: %v", err)
+	if entry.IsDir != false {
+		t.Error("Expected IsDir to be false")
 	}
+	if entry.Size != 1234 {
+		t.Errorf("Expected Size 1234, got %d", entry.Size)
+	}
+}
 
-	if err := os.WriteFile(filepath.Join(tmpDir, "file1.txt"), []byte("test"), 0644); err != nil {
-		t.Fatalf("Failed to create file: %v", err)
+// createLinks creates a file, a directory and links to both plus a broken
+// link in dir
+func createLinks(t *testing.T, dir string) {
+	t.Helper()
+	createTree(t, dir, "file.txt", "sub/inner.txt")
+	for name, target := range map[string]string{"file-link": "file.txt", "dir-link": "sub", "broken": "missing"} {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
 	}
-	if err := os.WriteFile(filepath.Join(tmpDir, "dir1", "file2.txt"), []byte("test"), 0644); err != nil {
-		t.Fatalf("Failed to create file: %v", err)
+}
+
+func TestReadDir_Symlinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	createLinks(t, tmpDir)
+
+	entries, err := ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
 	}
-	if err := os.WriteFile(filepath.Join(tmpDir, "dir1", "subdir1", "file3.txt"), []byte("test"), 0644); err != nil {
-		t.Fatalf("Failed to create file: %v", err)
+	byName := map[string]FileEntry{}
+	for _, entry := range entries {
+		byName[entry.Name] = entry
 	}
-	if err := os.WriteFile(filepath.Join(tmpDir, "dir2", "file4.txt"), []byte("test"), 0644); err != nil {
-		t.Fatalf("Failed to create file: %v", err)
+
+	testCases := []struct {
+		name   string
+		link   LinkType
+		isDir  bool
+		target string
+	}{
+		{"file.txt", NoLink, false, ""},
+		{"file-link", FileLink, false, "file.txt"},
+		{"dir-link", DirLink, true, "sub"},
+		{"broken", BrokenLink, false, "missing"},
+	}
+	for _, tc := range testCases {
+		entry := byName[tc.name]
+		if entry.Link != tc.link || entry.IsDir != tc.isDir || entry.LinkTarget != tc.target {
+			t.Errorf("%s: unexpected entry %+v", tc.name, entry)
+		}
 	}
+}
 
-	// Test with Tiefe 1 (nur ein Level rekursiv)
-	entries, err := ReadDirRecursive(tmpDir, 1)
-	if err != nil {
-		t.Fatalf("ReadDirRecursive failed: %v", err)
+func TestCopyContext_SymlinkPolicy(t *testing.T) {
+	testCases := []struct {
+		policy SymlinkPolicy
+		check  func(t *testing.T, dst string)
+	}{
+		{SymlinkCopy, func(t *testing.T, dst string) {
+			for _, name := range []string{"file-link", "dir-link", "broken"} {
+				info, err := os.Lstat(filepath.Join(dst, name))
+				if err != nil || info.Mode()&os.ModeSymlink == 0 {
+					t.Errorf("%s should be copied as a link", name)
+				}
+			}
+		}},
+		{SymlinkFollow, func(t *testing.T, dst string) {
+			if info, err := os.Lstat(filepath.Join(dst, "file-link")); err != nil || !info.Mode().IsRegular() {
+				t.Error("file-link should be copied as a file")
+			}
+			if info, err := os.Lstat(filepath.Join(dst, "dir-link")); err != nil || !info.IsDir() {
+				t.Error("dir-link should be copied as a directory")
+			}
+			if info, err := os.Lstat(filepath.Join(dst, "broken")); err != nil || info.Mode()&os.ModeSymlink == 0 {
+				t.Error("A broken link cannot be followed and should stay a link")
+			}
+		}},
+		{SymlinkSkip, func(t *testing.T, dst string) {
+			for _, name := range []string{"file-link", "dir-link", "broken"} {
+				if _, err := os.Lstat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+					t.Errorf("%s should be skipped", name)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dst, "file.txt")); err != nil {
+				t.Error("Regular files should still be copied")
+			}
+		}},
 	}
 
-	// Expected: dir1, dir2, file1.txt (dir1 contains subdir1, not read at depth=1)
-	if len(entries) < 3 {
-		t.Errorf("Expected at least 3 entries, got %d", len(entries))
+	for _, tc := range testCases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			src := filepath.Join(tmpDir, "src")
+			createLinks(t, src)
+			dst := filepath.Join(tmpDir, "dst")
+			if err := CopyContext(context.Background(), src, dst, nil, CopyOptions{Symlinks: tc.policy}); err != nil {
+				t.Fatalf("CopyContext failed: %v", err)
+			}
+			tc.check(t, dst)
+		})
 	}
+}
 
-	// Test with Tiefe 2 (zwei Level rekursiv)
-	entries, err = ReadDirRecursive(tmpDir, 2)
-	if err != nil {
-		t.Fatalf("ReadDirRecursive failed: %v", err)
+func TestCopyContext_FollowCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	createTree(t, src, "sub/a.txt")
+	// sub/loop points back to src
+	if err := os.Symlink("..", filepath.Join(src, "sub", "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
 	}
 
-	// Expected more entries at depth=2
-	if len(entries) < 4 {
-		t.Errorf("Expected at least 4 entries with depth=2, got %d", len(entries))
+	dst := filepath.Join(tmpDir, "dst")
+	if err := CopyContext(context.Background(), src, dst, nil, CopyOptions{Symlinks: SymlinkFollow}); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+	info, err := os.Lstat(filepath.Join(dst, "sub", "loop"))
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Error("A link back into the copied tree should stay a link")
 	}
 }
 
-func TestReadDirRecursive_NoDepth(t *testing.T) {
+func TestCopyContext_TopLevelLink(t *testing.T) {
 	tmpDir := t.TempDir()
+	createLinks(t, tmpDir)
 
-	// Create Verzeichnisstruktur
-	if err := os.MkdirAll(filepath.Join(tmpDir, "dir1", "subdir1"), 0755); err != nil {
-		t.Fatalf("Failed to create directory structure: %v", err)
+	dst := filepath.Join(tmpDir, "copied-link")
+	if err := CopyContext(context.Background(), filepath.Join(tmpDir, "dir-link"), dst, nil, CopyOptions{}); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
 	}
-	if err := os.WriteFile(filepath.Join(tmpDir, "file1.txt"), []byte("test"), 0644); err != nil {
-		t.Fatalf("Failed to create file: %v", err)
+	if target, err := os.Readlink(dst); err != nil || target != "sub" {
+		t.Errorf("Expected a link to sub, got %q (%v)", target, err)
 	}
+}
 
-	// Test with Tiefe 0 (nur das aktuelle Verzeichnis)
-	entries, err := ReadDirRecursive(tmpDir, 0)
-	if err != nil {
-		t.Fatalf("ReadDirRecursive failed: %v", err)
+func TestCopyContext_Merge(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	for _, path := range []string{src + "/sub", src + "/empty-in-dst", dst + "/sub", dst + "/full/inner", dst + "/empty-in-dst"} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	files := map[string]string{
+		src + "/a.txt": "new", src + "/sub/b.txt": "new", src + "/full": "new", src + "/link": "new",
+		dst + "/a.txt": "old", dst + "/keep.txt": "old", dst + "/sub/c.txt": "old",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if err := os.Symlink("keep.txt", dst+"/link"); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
 	}
 
-	// Only the aktuelle Verzeichnis
-	found := false
-	for _, entry := range entries {
-		if entry.Name == "file1.txt" {
-			found = true
+	// full is a file in the source but a non-empty directory in the destination
+	err := CopyContext(context.Background(), src, dst, nil, CopyOptions{Merge: true})
+	if err == nil || !strings.Contains(err.Error(), "full") {
+		t.Errorf("Expected merging to refuse deleting the non-empty directory, got %v", err)
+	}
+	if err := os.RemoveAll(dst + "/full/inner"); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if err := CopyContext(context.Background(), src, dst, nil, CopyOptions{Merge: true}); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+
+	want := map[string]string{"a.txt": "new", "keep.txt": "old", "sub/b.txt": "new", "sub/c.txt": "old", "full": "new", "link": "new"}
+	for name, content := range want {
+		if data, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(data) != content {
+			t.Errorf("Expected %s to hold %q, got %q, %v", name, content, data, err)
 		}
-		// Bei depth=0 sollten Unterverzeichnisse nicht rekursiv gelesen werden
-		// aber der Eintrag selbst sollte vorhanden sein (dir1 in diesem Fall)
-		_ = entry // Use entry to avoid unused variable warning
 	}
+	if info, err := os.Lstat(dst + "/link"); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("Expected the link replaced by a file, not written through: %v", err)
+	}
+}
 
-	if !found {
-		t.Error("Expected to find file1.txt")
+func TestMoveContext_MergesDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	for path, content := range map[string]string{src + "/a.txt": "new", dst + "/a.txt": "old", dst + "/keep.txt": "old"} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	if err := MoveContext(context.Background(), src, dst, nil); err != nil {
+		t.Fatalf("MoveContext failed: %v", err)
+	}
+	for name, content := range map[string]string{"a.txt": "new", "keep.txt": "old"} {
+		if data, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(data) != content {
+			t.Errorf("Expected %s to hold %q, got %q, %v", name, content, data, err)
+		}
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Error("Expected the source removed")
 	}
 }
 
-func TestFileEntry(t *testing.T) {
-	entry := FileEntry{
-		Name:  "test.txt",
-		IsDir: false,
-		Size:  1234,
+func TestMoveContext_IntoItself(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "d")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
 	}
 
-	if entry.Name != "test.txt" {
-		t.Errorf("Expected Name 'test.txt', got '%s'", entry.Name)
+	// The rename fails with EINVAL, which a copy must not work around
+	if err := MoveContext(context.Background(), src, filepath.Join(src, "sub", "d"), nil); err == nil {
+		t.Fatal("Expected moving a directory into itself to fail")
 	}
-	if entry.IsDir != false {
-		t.Error("Expected IsDir to be false")
+	if _, err := os.Lstat(filepath.Join(src, "sub", "d")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing copied, got %v", err)
 	}
-	if entry.Size != 1234 {
-		t.Errorf("Expected Size 1234, got %d", entry.Size)
+}
+
+func TestContains(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "d", "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "d"), filepath.Join(tmpDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"d", "d/sub", true},
+		{"d", "d/new/dir", true},
+		{"d", "d", false},
+		{"d", "dd", false},
+		{"d", "link/sub", true},
+		{"d", "link/new/dir", true},
+		{"link/sub", "d/sub/x", true},
+		// The link itself is not inside what it points to
+		{"d", "link", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(filepath.Join(tmpDir, tt.dir), filepath.Join(tmpDir, tt.path)); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
 	}
 }
@@ -0,0 +1,73 @@
+package fs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MatchWildcard reports whether name matches pattern, ignoring case.
+// '*' matches any sequence of characters and '?' matches a single character;
+// all other characters match themselves.
+func MatchWildcard(pattern, name string) bool {
+	_, ok := MatchWildcardPositions(pattern, name)
+	return ok
+}
+
+// MatchWildcardPositions is like MatchWildcard and also returns the rune
+// indices in name matched by the characters of pattern other than '*'
+func MatchWildcardPositions(pattern, name string) ([]int, bool) {
+	p := LowerRunes(pattern)
+	n := LowerRunes(name)
+
+	// Name index matched by each pattern position. Backtracking re-matches the
+	// positions after the last '*', so the final values belong to the match.
+	at := make([]int, len(p))
+	pi, ni := 0, 0
+	// Position of the last '*' and the name index it is currently matched up to
+	star, starMatch := -1, 0
+	for ni < len(n) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == n[ni]):
+			at[pi] = ni
+			pi++
+			ni++
+		case pi < len(p) && p[pi] == '*':
+			star, starMatch = pi, ni
+			pi++
+		case star >= 0:
+			// Let the last '*' swallow one more character and retry
+			starMatch++
+			pi, ni = star+1, starMatch
+		default:
+			return nil, false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	if pi != len(p) {
+		return nil, false
+	}
+
+	var positions []int
+	for i, r := range p {
+		if r != '*' {
+			positions = append(positions, at[i])
+		}
+	}
+	return positions, true
+}
+
+// LowerRunes lowercases s rune by rune, so that indices match those of s
+func LowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// HasWildcard reports whether pattern contains '*' or '?'
+func HasWildcard(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?")
+}
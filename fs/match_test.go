@@ -0,0 +1,72 @@
+package fs
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*", "anything", true},
+		{"*", "", true},
+		{"*.go", "main.go", true},
+		{"*.go", "main.go.bak", false},
+		{"*.GO", "Main.go", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"*test*", "my_test_file", true},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+		{"[abc]", "[abc]", true},
+		{"ü*", "Übung", true},
+	}
+
+	for _, tc := range testCases {
+		if got := MatchWildcard(tc.pattern, tc.name); got != tc.match {
+			t.Errorf("MatchWildcard(%q, %q) = %v, expected %v", tc.pattern, tc.name, got, tc.match)
+		}
+	}
+}
+
+func TestHasWildcard(t *testing.T) {
+	if !HasWildcard("*.go") || !HasWildcard("a?c") {
+		t.Error("Expected patterns with * or ? to contain wildcards")
+	}
+	if HasWildcard("plain.txt") {
+		t.Error("Expected plain name to contain no wildcards")
+	}
+}
+
+func TestMatchWildcardPositions(t *testing.T) {
+	testCases := []struct {
+		pattern   string
+		name      string
+		positions []int
+	}{
+		{"*.go", "main.go", []int{4, 5, 6}},
+		{"a*b", "aXbXb", []int{0, 4}},
+		{"*st*", "test_list", []int{2, 3}},
+		{"?ü*", "xÜber", []int{0, 1}},
+		{"*", "abc", nil},
+	}
+
+	for _, tc := range testCases {
+		got, ok := MatchWildcardPositions(tc.pattern, tc.name)
+		if !ok {
+			t.Errorf("MatchWildcardPositions(%q, %q) did not match", tc.pattern, tc.name)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.positions) {
+			t.Errorf("MatchWildcardPositions(%q, %q) = %v, expected %v", tc.pattern, tc.name, got, tc.positions)
+		}
+	}
+	if _, ok := MatchWildcardPositions("*.go", "main.txt"); ok {
+		t.Error("Expected no match for main.txt")
+	}
+}
@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// SymlinkPolicy decides how a copy treats symbolic links
+type SymlinkPolicy int
+
+const (
+	SymlinkCopy   SymlinkPolicy = iota // Recreate the link itself
+	SymlinkFollow                      // Copy what the link points to
+	SymlinkSkip                        // Leave links out
+)
+
+func (p SymlinkPolicy) String() string {
+	switch p {
+	case SymlinkFollow:
+		return "follow"
+	case SymlinkSkip:
+		return "skip"
+	}
+	return "copy as link"
+}
+
+// CopyOptions selects the metadata a copy carries over from the source and
+// how it treats symbolic links and existing entries. Without options a copy
+// gets the source permissions minus the umask and the current time, like a
+// plain cp, recreates links as links and fails on existing entries.
+type CopyOptions struct {
+	Mode     bool // Exact permission bits including setuid, setgid and sticky
+	Times    bool // Access and modification times
+	Owner    bool // User and group, where the process is permitted to set them
+	Xattrs   bool // Extended attributes, which include POSIX ACLs on Linux
+	Symlinks SymlinkPolicy
+	// Merge copies a directory into an existing one, replacing entries of
+	// the same name. Directories in the way are only removed when empty.
+	Merge bool
+}
+
+// PreserveAll keeps all metadata, so that copies are usable as backups
+var PreserveAll = CopyOptions{Mode: true, Times: true, Owner: true, Xattrs: true}
+
+func (o CopyOptions) preservesMetadata() bool {
+	return o.Mode || o.Times || o.Owner || o.Xattrs
+}
+
+// preserveMetadata applies the metadata of src, described by info, to dst.
+// Ownership goes first as changing it clears setuid and setgid, and times go
+// last as setting the other attributes may touch them.
+func preserveMetadata(src, dst string, info os.FileInfo, opts CopyOptions) error {
+	mode := info.Mode()
+	if opts.Owner {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			err := os.Lchown(dst, int(st.Uid), int(st.Gid))
+			if errors.Is(err, os.ErrPermission) {
+				// Like cp -p, never leave setuid/setgid on a file owned by someone else
+				mode &^= os.ModeSetuid | os.ModeSetgid
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	if opts.Xattrs {
+		if err := copyXattrs(src, dst); err != nil {
+			return err
+		}
+	}
+	if opts.Mode {
+		if err := os.Chmod(dst, mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+	}
+	if opts.Times {
+		if err := os.Chtimes(dst, accessTime(info), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
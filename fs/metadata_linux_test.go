@@ -0,0 +1,34 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCopyContext_PreservesXattrs(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "a.txt")
+	src := filepath.Join(tmpDir, "a.txt")
+	if err := unix.Lsetxattr(src, "user.comment", []byte("keep me"), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			t.Skip("File system does not support user xattrs")
+		}
+		t.Fatalf("Failed to set xattr: %v", err)
+	}
+
+	dst := filepath.Join(tmpDir, "b.txt")
+	if err := CopyContext(context.Background(), src, dst, nil, PreserveAll); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+	value, err := getXattr(dst, "user.comment")
+	if err != nil {
+		t.Fatalf("Xattr missing on copy: %v", err)
+	}
+	if string(value) != "keep me" {
+		t.Errorf("Expected xattr %q, got %q", "keep me", value)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package fs
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime falls back to the modification time where atime is not
+// available through the standard library
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+// copyXattrs is only implemented on Linux and macOS
+func copyXattrs(src, dst string) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyContext_PreservesFileMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "tool")
+	if err := os.WriteFile(src, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	// Bits a umask-subject OpenFile would lose
+	mode := os.FileMode(0707) | os.ModeSetgid
+	if err := os.Chmod(src, mode); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	atime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	if err := os.Chtimes(src, atime, mtime); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	dst := filepath.Join(tmpDir, "copy")
+	if err := CopyContext(context.Background(), src, dst, nil, PreserveAll); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("Failed to stat copy: %v", err)
+	}
+	if info.Mode() != mode {
+		t.Errorf("Expected mode %v, got %v", mode, info.Mode())
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("Expected mtime %v, got %v", mtime, info.ModTime())
+	}
+	// Platforms without atime support report the modification time
+	if got := accessTime(info); !got.Equal(mtime) && !got.Equal(atime) {
+		t.Errorf("Expected atime %v, got %v", atime, got)
+	}
+}
+
+func TestCopyContext_WithoutOptionsUsesCurrentTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "a.txt")
+	if err := os.WriteFile(src, []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(src, old, old); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	dst := filepath.Join(tmpDir, "b.txt")
+	if err := CopyContext(context.Background(), src, dst, nil, CopyOptions{}); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+	info, _ := os.Stat(dst)
+	if info.ModTime().Equal(old) {
+		t.Error("A plain copy should not keep the modification time")
+	}
+}
+
+func TestCopyContext_PreservesDirectoryMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "src/sub/a.txt")
+	src := filepath.Join(tmpDir, "src")
+	sub := filepath.Join(src, "sub")
+
+	mtime := time.Date(2019, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, dir := range []string{sub, src} {
+		if err := os.Chtimes(dir, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+	// A read-only directory can only be filled if its mode is applied last
+	if err := os.Chmod(sub, 0555); err != nil {
+		t.Fatalf("Failed to chmod: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(sub, 0755) })
+
+	dst := filepath.Join(tmpDir, "dst")
+	if err := CopyContext(context.Background(), src, dst, nil, PreserveAll); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(dst, "sub"), 0755) })
+
+	for _, dir := range []string{dst, filepath.Join(dst, "sub")} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", dir, err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("%s: expected mtime %v, got %v", dir, mtime, info.ModTime())
+		}
+	}
+	if info, _ := os.Stat(filepath.Join(dst, "sub")); info.Mode().Perm() != 0555 {
+		t.Errorf("Expected mode 0555, got %v", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dst, "sub", "a.txt")); err != nil {
+		t.Errorf("Contents of the read-only directory should be copied: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package fs
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// mimeSniffSize is the number of leading bytes http.DetectContentType looks at
+const mimeSniffSize = 512
+
+// MimeType returns the media type of the file at path without parameters,
+// e.g. "image/png". It is derived from the extension if that is known, and
+// from the content otherwise.
+func MimeType(path string) (string, error) {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return stripParams(t), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, mimeSniffSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return sniffMimeType(buf[:n], n == mimeSniffSize), nil
+}
+
+// sniffMimeType detects the content type of the first bytes of a file, which
+// may be cut short of the end of the file if truncated is set. The sniffer
+// takes text in other encodings for UTF-8 text; like the viewer, only valid
+// UTF-8 counts as text here.
+func sniffMimeType(buf []byte, truncated bool) string {
+	t := stripParams(http.DetectContentType(buf))
+	if t != "text/plain" {
+		return t
+	}
+	if truncated {
+		buf = trimIncompleteRune(buf)
+	}
+	if !utf8.Valid(buf) {
+		return "application/octet-stream"
+	}
+	return t
+}
+
+// stripParams removes parameters such as "; charset=utf-8"
+func stripParams(t string) string {
+	t, _, _ = strings.Cut(t, ";")
+	return strings.TrimSpace(t)
+}
@@ -0,0 +1,71 @@
+package fs
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMimeType(t *testing.T) {
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"page.html", []byte("plain words"), "text/html"}, // Extension wins
+		{"doc.pdf", []byte("%PDF-1.7"), "application/pdf"},
+		{"notes", []byte("just some text\n"), "text/plain"},
+		{"image", png, "image/png"},
+		{"blob", []byte{0, 1, 2, 3, 0xff}, "application/octet-stream"},
+		{"latin1", []byte("caf\xe9"), "application/octet-stream"},
+		{"archive", []byte("\x1f\x8b\x08\x00"), "application/x-gzip"},
+		{"index", []byte("\n<!DOCTYPE html><p>hi"), "text/html"},
+		{"empty", nil, "text/plain"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, tt.content, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		got, err := MimeType(path)
+		if err != nil || got != tt.want {
+			t.Errorf("MimeType(%s) = %q, %v, expected %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := MimeType(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected an error for a missing file without a known extension")
+	}
+}
+
+func TestSniffMimeType_MatchesStdlib(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF", "image/jpeg"},
+		{"gif", "GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"webp without riff", "XXXX\x24\x00\x00\x00WEBPVP8 ", "application/octet-stream"},
+		{"pdf", "%PDF-1.7\n", "application/pdf"},
+		{"zip", "PK\x03\x04\x14\x00", "application/zip"},
+		{"gzip", "\x1f\x8b\x08\x00", "application/x-gzip"},
+		{"html", "<!DOCTYPE html><p>hi", "text/html"},
+		{"xml", "<?xml version=\"1.0\"?><a/>", "text/xml"},
+		{"text", "plain words\n", "text/plain"},
+	}
+	for _, tt := range tests {
+		got := sniffMimeType([]byte(tt.content), false)
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+		if stdlib := stripParams(http.DetectContentType([]byte(tt.content))); got != stdlib {
+			t.Errorf("%s: expected the result of http.DetectContentType, %q, got %q", tt.name, stdlib, got)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// reportInterval limits how often a Tracker calls its ProgressFunc
+const reportInterval = 100 * time.Millisecond
+
+// Progress is a snapshot of a running copy or move
+type Progress struct {
+	BytesDone   int64
+	BytesTotal  int64
+	FilesDone   int
+	FilesTotal  int
+	CurrentFile string
+	Elapsed     time.Duration
+}
+
+// Throughput returns the average number of bytes copied per second
+func (p Progress) Throughput() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.BytesDone) / p.Elapsed.Seconds()
+}
+
+// ETA estimates the remaining time from the average throughput. It returns
+// 0 if there is not enough data yet.
+func (p Progress) ETA() time.Duration {
+	throughput := p.Throughput()
+	if throughput <= 0 || p.BytesTotal <= p.BytesDone {
+		return 0
+	}
+	return time.Duration(float64(p.BytesTotal-p.BytesDone) / throughput * float64(time.Second))
+}
+
+// ProgressFunc receives progress updates
+type ProgressFunc func(Progress)
+
+// Tracker accumulates the progress of one or more copies and reports it
+// through a ProgressFunc at most every reportInterval. A nil *Tracker is valid
+// and tracks nothing.
+type Tracker struct {
+	progress   Progress
+	started    time.Time
+	lastReport time.Time
+	report     ProgressFunc
+	measured   map[string]measuredSize // Sizes of the trees measured up front
+	// Pauser, if set, holds copies between reads while it is paused
+	Pauser *Pauser
+}
+
+// NewTracker creates a tracker for an operation of the given size
+func NewTracker(bytesTotal int64, filesTotal int, report ProgressFunc) *Tracker {
+	return &Tracker{
+		progress: Progress{BytesTotal: bytesTotal, FilesTotal: filesTotal},
+		started:  time.Now(),
+		report:   report,
+	}
+}
+
+// measuredSize is what Measure counted in one tree
+type measuredSize struct {
+	files int
+	bytes int64
+}
+
+// NewMeasuredTracker measures paths like Measure and creates a tracker for
+// their total. A move that renames one of them counts the size measured here
+// instead of walking the tree again.
+func NewMeasuredTracker(ctx context.Context, paths []string, report ProgressFunc) (*Tracker, error) {
+	measured := make(map[string]measuredSize, len(paths))
+	var files int
+	var bytes int64
+	for _, path := range paths {
+		f, b, err := Measure(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		measured[path] = measuredSize{files: f, bytes: b}
+		files += f
+		bytes += b
+	}
+	t := NewTracker(bytes, files, report)
+	t.measured = measured
+	return t, nil
+}
+
+// Progress returns the current snapshot
+func (t *Tracker) Progress() Progress {
+	if t == nil {
+		return Progress{}
+	}
+	p := t.progress
+	p.Elapsed = time.Since(t.started)
+	return p
+}
+
+// Flush reports the current progress immediately
+func (t *Tracker) Flush() {
+	if t == nil || t.report == nil {
+		return
+	}
+	t.lastReport = time.Now()
+	t.report(t.Progress())
+}
+
+// wait blocks while the tracker's Pauser is paused and returns ctx's error
+func (t *Tracker) wait(ctx context.Context) error {
+	if t == nil {
+		return ctx.Err()
+	}
+	return t.Pauser.Wait(ctx)
+}
+
+func (t *Tracker) maybeReport() {
+	if t != nil && time.Since(t.lastReport) >= reportInterval {
+		t.Flush()
+	}
+}
+
+func (t *Tracker) startFile(path string) {
+	if t == nil {
+		return
+	}
+	t.progress.CurrentFile = path
+	t.maybeReport()
+}
+
+func (t *Tracker) addBytes(n int64) {
+	if t == nil {
+		return
+	}
+	t.progress.BytesDone += n
+	t.maybeReport()
+}
+
+func (t *Tracker) finishFile() {
+	if t == nil {
+		return
+	}
+	t.progress.FilesDone++
+	t.maybeReport()
+}
+
+// skip counts the tree src, now at dst, that was handled without copying,
+// e.g. by a rename. Trees the tracker did not measure up front are measured
+// at dst.
+func (t *Tracker) skip(src, dst string) {
+	if t == nil {
+		return
+	}
+	size, ok := t.measured[src]
+	if !ok {
+		files, bytes, err := Measure(context.Background(), dst)
+		if err != nil {
+			return
+		}
+		size = measuredSize{files: files, bytes: bytes}
+	}
+	t.progress.CurrentFile = dst
+	t.progress.FilesDone += size.files
+	t.progress.BytesDone += size.bytes
+	t.maybeReport()
+}
+
+// Pauser suspends a running operation at its next checkpoint. The zero value
+// is not paused and a nil *Pauser never pauses. It is safe for concurrent use.
+type Pauser struct {
+	mu     sync.Mutex
+	resume chan struct{} // Closed on Resume, nil while not paused
+}
+
+// Pause makes Wait block until Resume is called
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume == nil {
+		p.resume = make(chan struct{})
+	}
+}
+
+// Resume releases everyone blocked in Wait
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+// Paused reports whether the pauser is paused
+func (p *Pauser) Paused() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resume != nil
+}
+
+// Wait blocks while the pauser is paused. It returns early with ctx's error
+// if ctx is cancelled.
+func (p *Pauser) Wait(ctx context.Context) error {
+	if p != nil {
+		p.mu.Lock()
+		resume := p.resume
+		p.mu.Unlock()
+		if resume != nil {
+			select {
+			case <-resume:
+			case <-ctx.Done():
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// Measure counts the regular files and their bytes in the given files and
+// directory trees
+func Measure(ctx context.Context, paths ...string) (files int, bytes int64, err error) {
+	for _, path := range paths {
+		info, err := os.Lstat(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !info.IsDir() {
+			files++
+			bytes += info.Size()
+			continue
+		}
+		err = Walk(ctx, path, func(_ string, entry FileEntry) error {
+			// Links count as files, what they point to depends on the copy
+			if entry.Link != NoLink {
+				files++
+			} else if !entry.IsDir {
+				files++
+				bytes += entry.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return files, bytes, nil
+}
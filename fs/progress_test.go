@@ -0,0 +1,170 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProgress_ThroughputAndETA(t *testing.T) {
+	p := Progress{BytesDone: 100, BytesTotal: 300, Elapsed: 2 * time.Second}
+
+	if p.Throughput() != 50 {
+		t.Errorf("Expected throughput 50, got %f", p.Throughput())
+	}
+	if p.ETA() != 4*time.Second {
+		t.Errorf("Expected ETA 4s, got %v", p.ETA())
+	}
+
+	if (Progress{}).ETA() != 0 {
+		t.Error("ETA without data should be 0")
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "a.txt", "dir/b.txt", "dir/sub/c.txt")
+
+	files, bytes, err := Measure(context.Background(), filepath.Join(tmpDir, "a.txt"), filepath.Join(tmpDir, "dir"))
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	// createTree writes "test" into every file
+	if files != 3 || bytes != 12 {
+		t.Errorf("Expected 3 files and 12 bytes, got %d and %d", files, bytes)
+	}
+}
+
+func TestCopyContext_ReportsProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "src/a.txt", "src/sub/b.txt")
+	src := filepath.Join(tmpDir, "src")
+
+	files, bytes, err := Measure(context.Background(), src)
+	if err != nil {
+		t.Fatalf("Measure failed: %v", err)
+	}
+	var last Progress
+	tracker := NewTracker(bytes, files, func(p Progress) { last = p })
+
+	if err := CopyContext(context.Background(), src, filepath.Join(tmpDir, "dst"), tracker, CopyOptions{}); err != nil {
+		t.Fatalf("CopyContext failed: %v", err)
+	}
+	tracker.Flush()
+
+	if last.FilesDone != 2 || last.BytesDone != 8 || last.FilesTotal != 2 || last.BytesTotal != 8 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "dst", "sub", "b.txt")); err != nil {
+		t.Errorf("Copied tree should be complete: %v", err)
+	}
+}
+
+func TestCopyContext_CancelRemovesPartialFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "big.bin")
+	if err := os.WriteFile(src, make([]byte, 1<<20), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	dst := filepath.Join(tmpDir, "copy.bin")
+
+	// Cancel as soon as the copy of the file starts
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := NewTracker(1<<20, 1, func(p Progress) {
+		if p.CurrentFile != "" {
+			cancel()
+		}
+	})
+
+	err := CopyContext(ctx, src, dst, tracker, CopyOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("Partial destination file should be removed")
+	}
+}
+
+func TestMoveContext_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "a.txt")
+	if err := os.WriteFile(src, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := MoveContext(ctx, src, filepath.Join(tmpDir, "b.txt"), nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Error("Source should be untouched after cancellation")
+	}
+}
+
+func TestMoveContext_RenameCountsProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "dir/a.txt", "dir/b.txt")
+
+	tracker := NewTracker(8, 2, nil)
+	if err := MoveContext(context.Background(), filepath.Join(tmpDir, "dir"), filepath.Join(tmpDir, "moved"), tracker); err != nil {
+		t.Fatalf("MoveContext failed: %v", err)
+	}
+	if p := tracker.Progress(); p.FilesDone != 2 || p.BytesDone != 8 {
+		t.Errorf("Renamed tree should count as done, got %+v", p)
+	}
+}
+
+func TestMoveContext_RenameUsesMeasuredSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "dir/a.txt", "dir/b.txt")
+	src := filepath.Join(tmpDir, "dir")
+
+	tracker, err := NewMeasuredTracker(context.Background(), []string{src}, nil)
+	if err != nil {
+		t.Fatalf("NewMeasuredTracker failed: %v", err)
+	}
+	if p := tracker.Progress(); p.FilesTotal != 2 || p.BytesTotal != 8 {
+		t.Fatalf("Unexpected totals: %+v", p)
+	}
+
+	// A file added after measuring shows that the tree is not walked again
+	createTree(t, tmpDir, "dir/c.txt")
+	if err := MoveContext(context.Background(), src, filepath.Join(tmpDir, "moved"), tracker); err != nil {
+		t.Fatalf("MoveContext failed: %v", err)
+	}
+	if p := tracker.Progress(); p.FilesDone != 2 || p.BytesDone != 8 {
+		t.Errorf("Expected the measured size counted, got %+v", p)
+	}
+}
+
+func TestPauser(t *testing.T) {
+	var p Pauser
+	ctx := context.Background()
+	if err := p.Wait(ctx); err != nil {
+		t.Fatalf("Wait on a running pauser failed: %v", err)
+	}
+
+	p.Pause()
+	done := make(chan error)
+	go func() { done <- p.Wait(ctx) }()
+	select {
+	case <-done:
+		t.Fatal("Wait should block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	p.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("Wait after resume failed: %v", err)
+	}
+
+	p.Pause()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := p.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled while paused, got %v", err)
+	}
+}
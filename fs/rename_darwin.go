@@ -0,0 +1,22 @@
+package fs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// renameNoReplace renames src to dst like os.Rename, but fails with an error
+// matching os.ErrExist instead of replacing dst
+func renameNoReplace(src, dst string) error {
+	err := unix.RenamexNp(src, dst, unix.RENAME_EXCL)
+	if errors.Is(err, unix.ENOTSUP) {
+		// The file system does not know the flag
+		return renameIfFree(src, dst)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package fs
+
+// renameNoReplace renames src to dst like os.Rename, but fails with an error
+// matching os.ErrExist instead of replacing dst
+func renameNoReplace(src, dst string) error {
+	return renameIfFree(src, dst)
+}
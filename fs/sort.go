@@ -0,0 +1,153 @@
+package fs
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SortMode is the key directory listings are sorted by
+type SortMode int
+
+const (
+	SortName      SortMode = iota // Case-insensitive name
+	SortNatural                   // Name with digit runs compared as numbers
+	SortExtension                 // Extension, then name
+	SortSize                      // Size, then name
+	SortModTime                   // Modification time, then name
+	SortUnsorted                  // Order returned by the file system
+	sortModeCount
+)
+
+func (m SortMode) String() string {
+	switch m {
+	case SortNatural:
+		return "natural"
+	case SortExtension:
+		return "extension"
+	case SortSize:
+		return "size"
+	case SortModTime:
+		return "time"
+	case SortUnsorted:
+		return "unsorted"
+	}
+	return "name"
+}
+
+// Next returns the sort mode after m, wrapping around
+func (m SortMode) Next() SortMode {
+	return (m + 1) % sortModeCount
+}
+
+// SortOptions selects the order of a directory listing. The zero value sorts
+// by name, ascending, with directories first.
+type SortOptions struct {
+	Mode       SortMode
+	Descending bool
+	MixDirs    bool // Sort directories among the files instead of first
+}
+
+// SortEntries sorts entries in place. Unsorted listings keep their order,
+// apart from directories moving to the front.
+func SortEntries(entries []FileEntry, opts SortOptions) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !opts.MixDirs && a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		c := compareEntries(a, b, opts.Mode)
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// compareEntries compares by mode and falls back to the name for ties
+func compareEntries(a, b FileEntry, mode SortMode) int {
+	var c int
+	switch mode {
+	case SortUnsorted:
+		return 0
+	case SortNatural:
+		return compareNatural(a.Name, b.Name)
+	case SortExtension:
+		c = compareFold(filepath.Ext(a.Name), filepath.Ext(b.Name))
+	case SortSize:
+		c = compareInt64(a.Size, b.Size)
+	case SortModTime:
+		c = a.ModTime.Compare(b.ModTime)
+	}
+	if c != 0 {
+		return c
+	}
+	return compareFold(a.Name, b.Name)
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareFold compares case-insensitively, falling back to byte order so that
+// names differing only in case still have a stable order
+func compareFold(a, b string) int {
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNatural compares case-insensitively with runs of digits compared by
+// their numeric value, so that "file2" < "file10" and "v1.9" < "v1.10"
+func compareNatural(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			runA, runB := digitRun(a[i:]), digitRun(b[j:])
+			numA, numB := strings.TrimLeft(runA, "0"), strings.TrimLeft(runB, "0")
+			if len(numA) != len(numB) {
+				return compareInt64(int64(len(numA)), int64(len(numB)))
+			}
+			if c := strings.Compare(numA, numB); c != 0 {
+				return c
+			}
+			i += len(runA)
+			j += len(runB)
+			continue
+		}
+
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return compareInt64(int64(la), int64(lb))
+		}
+		i += sizeA
+		j += sizeB
+	}
+	if c := compareInt64(int64(len(a)-i), int64(len(b)-j)); c != 0 {
+		return c
+	}
+	// Equal apart from case or leading zeros
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitRun(s string) string {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return s[:n]
+}
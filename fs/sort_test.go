@@ -0,0 +1,78 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func names(entries []FileEntry) string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, entry.Name)
+	}
+	return strings.Join(result, " ")
+}
+
+func TestCompareNatural(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		less bool
+	}{
+		{"file2", "file10", true},
+		{"file10", "file2", false},
+		{"v1.9.txt", "v1.10.txt", true},
+		{"Apple", "banana", true},
+		{"img007", "img8", true},
+		{"a", "a1", true},
+	}
+	for _, tc := range testCases {
+		if got := compareNatural(tc.a, tc.b) < 0; got != tc.less {
+			t.Errorf("compareNatural(%q, %q) < 0 = %v, expected %v", tc.a, tc.b, got, tc.less)
+		}
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := func() []FileEntry {
+		return []FileEntry{
+			{Name: "b.txt", Size: 30, ModTime: base.Add(2 * time.Hour)},
+			{Name: "Zeta", IsDir: true, ModTime: base},
+			{Name: "a10.go", Size: 10, ModTime: base.Add(3 * time.Hour)},
+			{Name: "a9.md", Size: 20, ModTime: base.Add(time.Hour)},
+			{Name: "alpha", IsDir: true, ModTime: base.Add(4 * time.Hour)},
+		}
+	}
+
+	testCases := []struct {
+		opts     SortOptions
+		expected string
+	}{
+		{SortOptions{}, "alpha Zeta a10.go a9.md b.txt"},
+		{SortOptions{Mode: SortNatural}, "alpha Zeta a9.md a10.go b.txt"},
+		{SortOptions{Mode: SortExtension}, "alpha Zeta a10.go a9.md b.txt"},
+		{SortOptions{Mode: SortSize, Descending: true}, "Zeta alpha b.txt a9.md a10.go"},
+		{SortOptions{Mode: SortModTime, Descending: true}, "alpha Zeta a10.go b.txt a9.md"},
+		{SortOptions{Mode: SortModTime, MixDirs: true}, "Zeta a9.md b.txt a10.go alpha"},
+		{SortOptions{Mode: SortUnsorted}, "Zeta alpha b.txt a10.go a9.md"},
+		{SortOptions{Mode: SortUnsorted, MixDirs: true}, "b.txt Zeta a10.go a9.md alpha"},
+	}
+	for _, tc := range testCases {
+		list := entries()
+		SortEntries(list, tc.opts)
+		if got := names(list); got != tc.expected {
+			t.Errorf("%+v: expected %q, got %q", tc.opts, tc.expected, got)
+		}
+	}
+}
+
+func TestSortMode_Next(t *testing.T) {
+	mode := SortName
+	for i := 0; i < int(sortModeCount); i++ {
+		mode = mode.Next()
+	}
+	if mode != SortName {
+		t.Errorf("Cycling through all modes should wrap around, got %v", mode)
+	}
+}
@@ -0,0 +1,388 @@
+package fs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// trashInfoDateFormat is the DeletionDate format of the Trash specification
+const trashInfoDateFormat = "2006-01-02T15:04:05"
+
+// TrashItem is an entry of a trash directory as defined by the
+// freedesktop.org Trash specification
+type TrashItem struct {
+	Name         string // Name inside the trash "files" directory
+	OriginalPath string
+	DeletionDate time.Time
+	IsDir        bool
+	Size         int64
+	TrashDir     string // Trash directory containing the item
+}
+
+// HomeTrashDir returns the user's home trash, $XDG_DATA_HOME/Trash
+func HomeTrashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// MoveToTrash moves path into the trash directory of the file system it lives
+// on and records its original location in a .trashinfo file
+func MoveToTrash(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	trashDir, topDir, err := trashDirFor(path, info)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(trashDir, "files"), 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(trashDir, "info"), 0700); err != nil {
+		return err
+	}
+
+	// Paths in a per-mount trash are relative to the mount point
+	recordedPath := path
+	if topDir != "" {
+		if recordedPath, err = filepath.Rel(topDir, path); err != nil {
+			return err
+		}
+	}
+
+	name, infoPath, err := reserveTrashName(trashDir, filepath.Base(path), recordedPath)
+	if err != nil {
+		return err
+	}
+	if err := Move(path, filepath.Join(trashDir, "files", name)); err != nil {
+		_ = os.Remove(infoPath)
+		return err
+	}
+	return nil
+}
+
+// trashDirFor picks the trash directory for path: the home trash if path is
+// on the same device, otherwise the trash of the mount point containing path.
+// topDir is the mount point for a per-mount trash and empty for the home trash.
+func trashDirFor(path string, info os.FileInfo) (trashDir, topDir string, err error) {
+	homeTrash, err := HomeTrashDir()
+	if err != nil {
+		return "", "", err
+	}
+	if sameDevice(info, ExistingDir(homeTrash)) {
+		return homeTrash, "", nil
+	}
+
+	topDir = mountPoint(path)
+	if dir, ok := mountTrashDir(topDir); ok {
+		return dir, topDir, nil
+	}
+
+	// No usable trash on that device, fall back to copying into the home trash
+	return homeTrash, "", nil
+}
+
+// mountTrashDir returns the current user's trash directory on the file system
+// mounted at topDir, creating it if needed: $topdir/.Trash/$uid if the
+// administrator set up .Trash, otherwise $topdir/.Trash-$uid. It reports
+// false if neither can be used safely.
+func mountTrashDir(topDir string) (string, bool) {
+	uid := strconv.Itoa(os.Getuid())
+	if shared := filepath.Join(topDir, ".Trash"); isSharedTrash(shared) {
+		dir := filepath.Join(shared, uid)
+		if err := os.Mkdir(dir, 0700); (err == nil || errors.Is(err, os.ErrExist)) && isOwnTrash(dir) {
+			return dir, true
+		}
+	}
+	dir := filepath.Join(topDir, ".Trash-"+uid)
+	if err := os.Mkdir(dir, 0700); (err == nil || errors.Is(err, os.ErrExist)) && isOwnTrash(dir) {
+		return dir, true
+	}
+	return "", false
+}
+
+// isSharedTrash reports whether dir may hold the trash directories of the
+// users of a file system: a real directory rather than a symbolic link, with
+// the sticky bit so that users cannot remove each other's
+func isSharedTrash(dir string) bool {
+	info, err := os.Lstat(dir)
+	return err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0
+}
+
+// isOwnTrash reports whether dir is safe to use as the current user's trash:
+// a real directory rather than a symbolic link, owned by the user and closed
+// to everyone else. Anything else may have been planted by another user to
+// read or swap what is trashed.
+func isOwnTrash(dir string) bool {
+	info, err := os.Lstat(dir)
+	if err != nil || !info.IsDir() || info.Mode().Perm()&0077 != 0 {
+		return false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid()
+}
+
+// reserveTrashName atomically creates the .trashinfo file for a free name in
+// trashDir and returns the name and the info file path
+func reserveTrashName(trashDir, base, recordedPath string) (name, infoPath string, err error) {
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: recordedPath}).EscapedPath(), time.Now().Format(trashInfoDateFormat))
+
+	for n := 1; n < 10000; n++ {
+		name = base
+		if n > 1 {
+			name = fmt.Sprintf("%s.%d", base, n)
+		}
+		infoPath = filepath.Join(trashDir, "info", name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		// The name is only free if nothing was left behind in files/
+		if _, err := os.Lstat(filepath.Join(trashDir, "files", name)); err == nil {
+			f.Close()
+			continue
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(infoPath)
+			return "", "", err
+		}
+		return name, infoPath, nil
+	}
+	return "", "", fmt.Errorf("no free name in trash for %s", base)
+}
+
+// ListTrash returns the items of the home trash and of the per-mount trash
+// directories of all mounted file systems, newest first
+func ListTrash() ([]TrashItem, error) {
+	homeTrash, err := HomeTrashDir()
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := listTrashDir(homeTrash, "")
+	if err != nil {
+		return nil, err
+	}
+	uid := strconv.Itoa(os.Getuid())
+	for _, topDir := range mountPoints() {
+		dirs := []string{filepath.Join(topDir, ".Trash-"+uid)}
+		if shared := filepath.Join(topDir, ".Trash"); isSharedTrash(shared) {
+			dirs = append(dirs, filepath.Join(shared, uid))
+		}
+		for _, dir := range dirs {
+			// Directories that would not be trashed into are not read either
+			if dir == homeTrash || !isOwnTrash(dir) {
+				continue
+			}
+			more, err := listTrashDir(dir, topDir)
+			if err != nil {
+				continue
+			}
+			items = append(items, more...)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletionDate.After(items[j].DeletionDate)
+	})
+	return items, nil
+}
+
+// listTrashDir reads the .trashinfo files of one trash directory. topDir is
+// the mount point relative paths are resolved against.
+func listTrashDir(trashDir, topDir string) ([]TrashItem, error) {
+	infos, err := os.ReadDir(filepath.Join(trashDir, "info"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TrashItem
+	for _, entry := range infos {
+		name, ok := strings.CutSuffix(entry.Name(), ".trashinfo")
+		if !ok {
+			continue
+		}
+		item, err := readTrashInfo(filepath.Join(trashDir, "info", entry.Name()))
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(item.OriginalPath) {
+			item.OriginalPath = filepath.Join(topDir, item.OriginalPath)
+		}
+		item.Name = name
+		item.TrashDir = trashDir
+		info, err := os.Lstat(filepath.Join(trashDir, "files", name))
+		if err != nil {
+			// Orphaned info file
+			continue
+		}
+		item.IsDir = info.IsDir()
+		item.Size = info.Size()
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// readTrashInfo parses the Path and DeletionDate keys of a .trashinfo file
+func readTrashInfo(path string) (TrashItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	defer f.Close()
+
+	var item TrashItem
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == "[Trash Info]"
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inSection || !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			if item.OriginalPath, err = url.PathUnescape(value); err != nil {
+				return TrashItem{}, err
+			}
+		case "DeletionDate":
+			// An unparsable date is not fatal, the item can still be restored
+			item.DeletionDate, _ = time.ParseInLocation(trashInfoDateFormat, value, time.Local)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return TrashItem{}, err
+	}
+	if item.OriginalPath == "" {
+		return TrashItem{}, fmt.Errorf("%s: missing Path", path)
+	}
+	return item, nil
+}
+
+// RestoreFromTrash moves a trashed item back to its original path. It fails
+// if something else now exists at that path.
+func RestoreFromTrash(item TrashItem) error {
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return err
+	}
+	// Whatever took the original place in the meantime is kept
+	err := moveNoReplace(filepath.Join(item.TrashDir, "files", item.Name), item.OriginalPath)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists", item.OriginalPath)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(trashInfoPath(item))
+}
+
+// PurgeFromTrash permanently deletes a trashed item
+func PurgeFromTrash(item TrashItem) error {
+	if err := os.RemoveAll(filepath.Join(item.TrashDir, "files", item.Name)); err != nil {
+		return err
+	}
+	return os.Remove(trashInfoPath(item))
+}
+
+func trashInfoPath(item TrashItem) string {
+	return filepath.Join(item.TrashDir, "info", item.Name+".trashinfo")
+}
+
+// sameDevice reports whether info and the file at path are on the same device
+func sameDevice(info os.FileInfo, path string) bool {
+	other, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	return deviceOf(info) == deviceOf(other)
+}
+
+func deviceOf(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
+
+// mountPoint returns the topmost ancestor of path on the same device
+func mountPoint(path string) string {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	dev := deviceOf(info)
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		parentInfo, err := os.Lstat(parent)
+		if err != nil || deviceOf(parentInfo) != dev {
+			return path
+		}
+		path = parent
+	}
+}
+
+// mountPoints lists mounted file systems. It relies on /proc and returns
+// nothing on systems without it, leaving only the home trash to be listed.
+func mountPoints() []string {
+	f, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// Spaces and other special characters are octal-escaped
+		point, err := strconv.Unquote(`"` + strings.ReplaceAll(fields[1], `"`, `\"`) + `"`)
+		if err != nil {
+			point = fields[1]
+		}
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,290 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// setupTrash points the home trash at a temporary directory
+func setupTrash(t *testing.T) string {
+	t.Helper()
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	return filepath.Join(dataHome, "Trash")
+}
+
+func TestMoveToTrash(t *testing.T) {
+	trashDir := setupTrash(t)
+	tmpDir := t.TempDir()
+
+	path := filepath.Join(tmpDir, "my file.txt")
+	if err := os.WriteFile(path, []byte("trash me"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if err := MoveToTrash(path); err != nil {
+		t.Fatalf("MoveToTrash failed: %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("File should be gone from its original location")
+	}
+	content, err := os.ReadFile(filepath.Join(trashDir, "files", "my file.txt"))
+	if err != nil || string(content) != "trash me" {
+		t.Errorf("File should be in the trash, got %q, %v", content, err)
+	}
+
+	info, err := os.ReadFile(filepath.Join(trashDir, "info", "my file.txt.trashinfo"))
+	if err != nil {
+		t.Fatalf("Info file should exist: %v", err)
+	}
+	if !strings.HasPrefix(string(info), "[Trash Info]\n") {
+		t.Errorf("Info file should start with the group header: %q", info)
+	}
+	if !strings.Contains(string(info), "Path="+strings.ReplaceAll(path, " ", "%20")+"\n") {
+		t.Errorf("Info file should contain the URL-encoded original path: %q", info)
+	}
+	if !strings.Contains(string(info), "DeletionDate=") {
+		t.Errorf("Info file should contain the deletion date: %q", info)
+	}
+}
+
+func TestMoveToTrash_NameCollision(t *testing.T) {
+	trashDir := setupTrash(t)
+
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "same.txt")
+		if err := os.WriteFile(path, []byte{byte('0' + i)}, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := MoveToTrash(path); err != nil {
+			t.Fatalf("MoveToTrash failed: %v", err)
+		}
+	}
+
+	for _, name := range []string{"same.txt", "same.txt.2"} {
+		if _, err := os.Stat(filepath.Join(trashDir, "files", name)); err != nil {
+			t.Errorf("Expected %s in trash: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(trashDir, "info", name+".trashinfo")); err != nil {
+			t.Errorf("Expected info for %s: %v", name, err)
+		}
+	}
+}
+
+func TestListAndRestoreFromTrash(t *testing.T) {
+	setupTrash(t)
+	tmpDir := t.TempDir()
+
+	dir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "f.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := MoveToTrash(dir); err != nil {
+		t.Fatalf("MoveToTrash failed: %v", err)
+	}
+
+	items, err := ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash failed: %v", err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 trash item, got %d", len(items))
+	}
+	item := items[0]
+	if item.OriginalPath != dir || !item.IsDir || item.DeletionDate.IsZero() {
+		t.Errorf("Unexpected trash item: %+v", item)
+	}
+
+	// The original parent may have disappeared in the meantime
+	if err := os.RemoveAll(tmpDir); err != nil {
+		t.Fatalf("Failed to remove parent: %v", err)
+	}
+	if err := RestoreFromTrash(item); err != nil {
+		t.Fatalf("RestoreFromTrash failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub", "f.txt")); err != nil {
+		t.Errorf("Restored directory should contain its files: %v", err)
+	}
+
+	items, _ = ListTrash()
+	if len(items) != 0 {
+		t.Errorf("Trash should be empty after restore, got %d items", len(items))
+	}
+}
+
+func TestRestoreFromTrash_TargetExists(t *testing.T) {
+	setupTrash(t)
+	tmpDir := t.TempDir()
+
+	path := filepath.Join(tmpDir, "a.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := MoveToTrash(path); err != nil {
+		t.Fatalf("MoveToTrash failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	items, _ := ListTrash()
+	if err := RestoreFromTrash(items[0]); err == nil {
+		t.Error("Restore should fail when the original path is taken")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new" {
+		t.Errorf("Existing file should be untouched, got %q", content)
+	}
+}
+
+func TestPurgeFromTrash(t *testing.T) {
+	trashDir := setupTrash(t)
+	path := filepath.Join(t.TempDir(), "gone.txt")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := MoveToTrash(path); err != nil {
+		t.Fatalf("MoveToTrash failed: %v", err)
+	}
+
+	items, _ := ListTrash()
+	if err := PurgeFromTrash(items[0]); err != nil {
+		t.Fatalf("PurgeFromTrash failed: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(trashDir, "files"))
+	infos, _ := os.ReadDir(filepath.Join(trashDir, "info"))
+	if len(entries) != 0 || len(infos) != 0 {
+		t.Errorf("Trash should be empty, got %d files and %d infos", len(entries), len(infos))
+	}
+}
+
+func TestReadTrashInfo_RelativePath(t *testing.T) {
+	trashDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(trashDir, "info"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(trashDir, "files", "x"), 0700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	info := "[Trash Info]\nPath=data/caf%C3%A9\nDeletionDate=2024-05-06T07:08:09\n"
+	if err := os.WriteFile(filepath.Join(trashDir, "info", "x.trashinfo"), []byte(info), 0600); err != nil {
+		t.Fatalf("Failed to create info file: %v", err)
+	}
+
+	items, err := listTrashDir(trashDir, "/mnt/usb")
+	if err != nil {
+		t.Fatalf("listTrashDir failed: %v", err)
+	}
+	if len(items) != 1 || items[0].OriginalPath != "/mnt/usb/data/café" {
+		t.Fatalf("Unexpected items: %+v", items)
+	}
+	if items[0].DeletionDate.Year() != 2024 || items[0].DeletionDate.Second() != 9 {
+		t.Errorf("Unexpected deletion date: %v", items[0].DeletionDate)
+	}
+}
+
+func TestMountTrashDir(t *testing.T) {
+	uid := strconv.Itoa(os.Getuid())
+	tests := []struct {
+		name  string
+		setup func(topDir string) error
+		want  string // Relative to topDir, "" for none
+	}{
+		{"created", func(string) error { return nil }, ".Trash-" + uid},
+		{"shared", func(topDir string) error {
+			if err := os.Mkdir(filepath.Join(topDir, ".Trash"), 0777); err != nil {
+				return err
+			}
+			return os.Chmod(filepath.Join(topDir, ".Trash"), 0777|os.ModeSticky)
+		}, filepath.Join(".Trash", uid)},
+		{"shared without sticky bit", func(topDir string) error {
+			return os.Mkdir(filepath.Join(topDir, ".Trash"), 0777)
+		}, ".Trash-" + uid},
+		{"shared link", func(topDir string) error {
+			target := filepath.Join(topDir, "elsewhere")
+			if err := os.Mkdir(target, 0777); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, 0777|os.ModeSticky); err != nil {
+				return err
+			}
+			return os.Symlink(target, filepath.Join(topDir, ".Trash"))
+		}, ".Trash-" + uid},
+		{"link", func(topDir string) error {
+			target := filepath.Join(topDir, "elsewhere")
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			return os.Symlink(target, filepath.Join(topDir, ".Trash-"+uid))
+		}, ""},
+		{"open to others", func(topDir string) error {
+			dir := filepath.Join(topDir, ".Trash-"+uid)
+			if err := os.Mkdir(dir, 0700); err != nil {
+				return err
+			}
+			return os.Chmod(dir, 0777)
+		}, ""},
+		{"file", func(topDir string) error {
+			return os.WriteFile(filepath.Join(topDir, ".Trash-"+uid), nil, 0600)
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topDir := t.TempDir()
+			if err := tt.setup(topDir); err != nil {
+				t.Fatalf("Setup failed: %v", err)
+			}
+			dir, ok := mountTrashDir(topDir)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Expected no usable trash, got %s", dir)
+				}
+				return
+			}
+			if !ok || dir != filepath.Join(topDir, tt.want) {
+				t.Errorf("Expected %s, got %q, %v", tt.want, dir, ok)
+			}
+		})
+	}
+}
+
+func TestMoveNoReplace(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	dst := filepath.Join(tmpDir, "dst")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// A rename would replace the empty directory, a copy would merge into it
+	if err := os.Mkdir(dst, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := moveNoReplace(src, dst); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("Expected os.ErrExist, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "sub")); err != nil {
+		t.Errorf("Expected the source kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "sub")); !os.IsNotExist(err) {
+		t.Error("Expected nothing moved into the destination")
+	}
+
+	if err := os.Remove(dst); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if err := moveNoReplace(src, dst); err != nil {
+		t.Fatalf("moveNoReplace failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "sub")); err != nil {
+		t.Errorf("Expected the directory moved: %v", err)
+	}
+}
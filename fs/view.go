@@ -0,0 +1,103 @@
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// sniffSize is the number of leading bytes inspected to detect binary content
+const sniffSize = 8000
+
+// IsBinary reports whether the file at path looks like binary data.
+// A file is treated as binary if its first bytes contain a NUL byte or are
+// not valid UTF-8.
+func IsBinary(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	buf = buf[:n]
+
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return true, nil
+	}
+	// The sniffed block may end in the middle of a multi-byte rune
+	if n == sniffSize {
+		buf = trimIncompleteRune(buf)
+	}
+	return !utf8.Valid(buf), nil
+}
+
+// trimIncompleteRune drops a trailing, truncated UTF-8 sequence
+func trimIncompleteRune(buf []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(buf); i++ {
+		if utf8.RuneStart(buf[len(buf)-i]) {
+			if !utf8.FullRune(buf[len(buf)-i:]) {
+				return buf[:len(buf)-i]
+			}
+			break
+		}
+	}
+	return buf
+}
+
+// LineOffsets returns the byte offset at which every line of the file starts.
+// Only the offsets are kept in memory, so arbitrarily large files can be
+// indexed and later read window by window with ReadRange.
+func LineOffsets(path string) ([]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var offsets []int64
+	var pos int64
+	atLineStart := true
+	r := bufio.NewReaderSize(f, 64*1024)
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(chunk) > 0 && atLineStart {
+			offsets = append(offsets, pos)
+		}
+		pos += int64(len(chunk))
+		atLineStart = len(chunk) > 0 && chunk[len(chunk)-1] == '\n'
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return offsets, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadRange reads up to n bytes starting at offset off
+func ReadRange(path string, off int64, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	read, err := f.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return buf[:read], nil
+}
@@ -0,0 +1,152 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testCases := []struct {
+		name    string
+		content []byte
+		binary  bool
+	}{
+		{"empty", []byte{}, false},
+		{"text", []byte("hello\nworld\n"), false},
+		{"utf8", []byte("grüße\n"), false},
+		{"nul", []byte("abc\x00def"), true},
+		{"invalid_utf8", []byte{0xff, 0xfe, 0x41}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tc.name)
+			if err := os.WriteFile(path, tc.content, 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+
+			binary, err := IsBinary(path)
+			if err != nil {
+				t.Fatalf("IsBinary failed: %v", err)
+			}
+			if binary != tc.binary {
+				t.Errorf("Expected binary=%v, got %v", tc.binary, binary)
+			}
+		})
+	}
+}
+
+func TestIsBinary_RuneCutAtSniffBoundary(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// "ü" is two bytes; place it so the sniffed block ends after its first byte
+	content := make([]byte, 0, sniffSize+1)
+	for len(content) < sniffSize-1 {
+		content = append(content, 'a')
+	}
+	content = append(content, []byte("ü")...)
+
+	path := filepath.Join(tmpDir, "boundary.txt")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	binary, err := IsBinary(path)
+	if err != nil {
+		t.Fatalf("IsBinary failed: %v", err)
+	}
+	if binary {
+		t.Error("Text with a multi-byte rune at the sniff boundary should not be binary")
+	}
+}
+
+func TestLineOffsets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testCases := []struct {
+		name     string
+		content  string
+		expected []int64
+	}{
+		{"empty", "", nil},
+		{"single_no_newline", "abc", []int64{0}},
+		{"trailing_newline", "ab\ncd\n", []int64{0, 3}},
+		{"no_trailing_newline", "ab\ncd", []int64{0, 3}},
+		{"empty_lines", "\n\nx", []int64{0, 1, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tc.name)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+
+			offsets, err := LineOffsets(path)
+			if err != nil {
+				t.Fatalf("LineOffsets failed: %v", err)
+			}
+			if len(offsets) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, offsets)
+			}
+			for i := range offsets {
+				if offsets[i] != tc.expected[i] {
+					t.Errorf("Expected %v, got %v", tc.expected, offsets)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLineOffsets_LongLine(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A line longer than the reader buffer must still count as one line
+	long := make([]byte, 200*1024)
+	for i := range long {
+		long[i] = 'x'
+	}
+	content := append(long, []byte("\nshort\n")...)
+
+	path := filepath.Join(tmpDir, "long.txt")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	offsets, err := LineOffsets(path)
+	if err != nil {
+		t.Fatalf("LineOffsets failed: %v", err)
+	}
+	if len(offsets) != 2 || offsets[1] != int64(len(long)+1) {
+		t.Errorf("Expected offsets [0 %d], got %v", len(long)+1, offsets)
+	}
+}
+
+func TestReadRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "range.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	data, err := ReadRange(path, 3, 4)
+	if err != nil {
+		t.Fatalf("ReadRange failed: %v", err)
+	}
+	if string(data) != "3456" {
+		t.Errorf("Expected %q, got %q", "3456", data)
+	}
+
+	// Reading past the end returns the available bytes
+	data, err = ReadRange(path, 8, 10)
+	if err != nil {
+		t.Fatalf("ReadRange failed: %v", err)
+	}
+	if string(data) != "89" {
+		t.Errorf("Expected %q, got %q", "89", data)
+	}
+}
@@ -0,0 +1,57 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// WalkFunc is called by Walk for every entry below the root. path is the full
+// path of the entry. Returning SkipDir for a directory skips its contents;
+// any other error stops the walk.
+type WalkFunc func(path string, entry FileEntry) error
+
+// SkipDir tells Walk to skip the contents of the current directory
+var SkipDir = filepath.SkipDir
+
+// Walk streams the tree below root depth-first, in directory order, calling fn
+// for each entry as soon as its directory has been read. Symbolic links are
+// reported with their link type but not followed, so cyclic links cannot make
+// it loop. Unreadable subdirectories are skipped. Walk
+// returns ctx.Err() when the context is cancelled.
+func Walk(ctx context.Context, root string, fn WalkFunc) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	return walkEntries(ctx, root, entries, fn)
+}
+
+func walkEntries(ctx context.Context, dir string, entries []os.DirEntry, fn WalkFunc) error {
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		err := fn(path, newFileEntry(dir, entry))
+		if errors.Is(err, SkipDir) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			children, err := os.ReadDir(path)
+			if err != nil {
+				continue
+			}
+			if err := walkEntries(ctx, path, children, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
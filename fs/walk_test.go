@@ -0,0 +1,103 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// createTree creates the given files (and their parent directories) below root
+func createTree(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "a.txt", "dir1/b.txt", "dir1/sub/c.txt", "dir2/d.txt")
+
+	var visited []string
+	err := Walk(context.Background(), tmpDir, func(path string, entry FileEntry) error {
+		rel, _ := filepath.Rel(tmpDir, path)
+		if filepath.Base(rel) != entry.Name {
+			t.Errorf("Entry name %q does not match path %q", entry.Name, path)
+		}
+		visited = append(visited, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	expected := []string{"a.txt", "dir1", "dir1/b.txt", "dir1/sub", "dir1/sub/c.txt", "dir2", "dir2/d.txt"}
+	sort.Strings(visited)
+	if len(visited) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, visited)
+			break
+		}
+	}
+}
+
+func TestWalk_SkipDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "keep/a.txt", "skip/b.txt")
+
+	var visited []string
+	err := Walk(context.Background(), tmpDir, func(path string, entry FileEntry) error {
+		visited = append(visited, entry.Name)
+		if entry.IsDir && entry.Name == "skip" {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	for _, name := range visited {
+		if name == "b.txt" {
+			t.Error("Contents of skipped directory should not be visited")
+		}
+	}
+}
+
+func TestWalk_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTree(t, tmpDir, "a.txt", "b.txt", "c.txt")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := Walk(ctx, tmpDir, func(path string, entry FileEntry) error {
+		count++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected walk to stop after 1 entry, visited %d", count)
+	}
+}
+
+func TestWalk_NonExistent(t *testing.T) {
+	err := Walk(context.Background(), "/non/existent/path", func(string, FileEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected error for non-existent root")
+	}
+}
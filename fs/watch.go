@@ -0,0 +1,289 @@
+package fs
+
+import (
+	"hash/fnv"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDebounce is how long a Watcher waits for a burst of events to end
+	DefaultDebounce = 200 * time.Millisecond
+	// maxDebounceDelay bounds the wait during a continuous stream of events
+	maxDebounceDelay = 2 * time.Second
+	// pollInterval is how often directories without inotify are scanned
+	pollInterval = 2 * time.Second
+)
+
+// watchBackend watches directories and sends the path of a directory to its
+// channel when the directory or its entries change
+type watchBackend interface {
+	add(path string) error
+	remove(path string)
+	watching(path string) bool // False once a watch ended on its own
+	close()
+}
+
+// Watcher reports changes to the entries of a set of directories. Events are
+// collected until none arrived for the debounce delay, then each changed
+// directory is sent once on Changes. Directories are watched with inotify
+// where available and polled otherwise.
+type Watcher struct {
+	Changes <-chan string
+
+	changes  chan string
+	raw      chan string
+	notify   watchBackend // nil if the platform has no inotify
+	poll     *pollBackend
+	debounce time.Duration
+
+	mu      sync.Mutex
+	watched map[string]watchBackend
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWatcher starts a watcher that waits debounce after the last event
+// before reporting a change
+func NewWatcher(debounce time.Duration) *Watcher {
+	w := newWatcher(debounce, pollInterval)
+	if notify, err := newInotifyBackend(w.raw, w.done); err == nil {
+		w.notify = notify
+	}
+	return w
+}
+
+// newWatcher creates a watcher that only polls
+func newWatcher(debounce, interval time.Duration) *Watcher {
+	w := &Watcher{
+		changes:  make(chan string),
+		raw:      make(chan string, 64),
+		debounce: debounce,
+		watched:  map[string]watchBackend{},
+		done:     make(chan struct{}),
+	}
+	w.Changes = w.changes
+	w.poll = newPollBackend(w.raw, w.done, interval)
+	go w.run()
+	return w
+}
+
+// Watch replaces the watched directories with paths. Directories that are
+// already watched keep their watch.
+func (w *Watcher) Watch(paths ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	wanted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	for path, backend := range w.watched {
+		if !wanted[path] {
+			backend.remove(path)
+			delete(w.watched, path)
+		}
+	}
+	for path := range wanted {
+		if backend, ok := w.watched[path]; ok && backend.watching(path) {
+			continue
+		}
+		// Fall back to polling when inotify is unavailable or refuses the
+		// watch, e.g. because the watch limit is reached
+		if w.notify != nil && w.notify.add(path) == nil {
+			w.watched[path] = w.notify
+			continue
+		}
+		if w.poll.add(path) == nil {
+			w.watched[path] = w.poll
+		}
+	}
+}
+
+// Watched returns the watched directories, sorted
+func (w *Watcher) Watched() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	paths := make([]string, 0, len(w.watched))
+	for path := range w.watched {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Close stops watching. Changes is not closed, so that receivers waiting on
+// it do not mistake closing for a change.
+func (w *Watcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		if w.notify != nil {
+			w.notify.close()
+		}
+		w.poll.close()
+	})
+}
+
+// run debounces the events of the backends
+func (w *Watcher) run() {
+	pending := map[string]bool{}
+	var first time.Time
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+
+	for {
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case path := <-w.raw:
+			if !w.isWatched(path) {
+				continue
+			}
+			if len(pending) == 0 {
+				first = time.Now()
+			}
+			pending[path] = true
+			// Wait for the burst to end, but not longer than maxDebounceDelay
+			// in total
+			delay := min(w.debounce, maxDebounceDelay-time.Since(first))
+			timer.Stop()
+			select {
+			case <-timer.C:
+			default:
+			}
+			timer.Reset(max(delay, 0))
+		case <-timer.C:
+			for path := range pending {
+				select {
+				case w.changes <- path:
+				case <-w.done:
+					return
+				}
+			}
+			pending = map[string]bool{}
+		}
+	}
+}
+
+func (w *Watcher) isWatched(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.watched[path]
+	return ok
+}
+
+// pollBackend detects changes by comparing a signature of each directory's
+// entries at a fixed interval
+type pollBackend struct {
+	raw      chan<- string
+	done     <-chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	dirs map[string]uint64 // Last signature by path
+}
+
+func newPollBackend(raw chan<- string, done <-chan struct{}, interval time.Duration) *pollBackend {
+	p := &pollBackend{raw: raw, done: done, interval: interval, dirs: map[string]uint64{}}
+	go p.run()
+	return p
+}
+
+func (p *pollBackend) add(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dirs[path] = dirSignature(path)
+	return nil
+}
+
+func (p *pollBackend) remove(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dirs, path)
+}
+
+func (p *pollBackend) watching(path string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.dirs[path]
+	return ok
+}
+
+// close is a no-op, the polling stops when done is closed
+func (p *pollBackend) close() {}
+
+func (p *pollBackend) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			for _, path := range p.scan() {
+				select {
+				case p.raw <- path:
+				case <-p.done:
+					return
+				}
+			}
+		}
+	}
+}
+
+// scan returns the directories whose signature changed since the last scan
+func (p *pollBackend) scan() []string {
+	p.mu.Lock()
+	paths := make([]string, 0, len(p.dirs))
+	for path := range p.dirs {
+		paths = append(paths, path)
+	}
+	p.mu.Unlock()
+
+	var changed []string
+	for _, path := range paths {
+		signature := dirSignature(path)
+		p.mu.Lock()
+		old, ok := p.dirs[path]
+		if ok && old != signature {
+			p.dirs[path] = signature
+			changed = append(changed, path)
+		}
+		p.mu.Unlock()
+	}
+	return changed
+}
+
+// dirSignature hashes the names, sizes, modes and modification times of the
+// entries of a directory. A missing or unreadable directory has signature 0.
+func dirSignature(path string) uint64 {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	var buf [8]byte
+	writeInt := func(v int64) {
+		for i := range buf {
+			buf[i] = byte(v >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	for _, entry := range entries {
+		h.Write([]byte(entry.Name()))
+		h.Write([]byte{0})
+		if info, err := entry.Info(); err == nil {
+			writeInt(info.Size())
+			writeInt(int64(info.Mode()))
+			writeInt(info.ModTime().UnixNano())
+		}
+	}
+	// Never 0, so that an empty directory differs from a missing one
+	return h.Sum64() | 1
+}
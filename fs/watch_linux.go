@@ -0,0 +1,145 @@
+package fs
+
+import (
+	"os"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the events that change a directory listing
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF |
+	unix.IN_ONLYDIR
+
+// inotifyBackend watches directories with a single inotify instance
+type inotifyBackend struct {
+	file *os.File // Non-blocking, so that closing it ends a pending read
+	fd   int
+	raw  chan<- string
+	done <-chan struct{}
+
+	mu sync.Mutex
+	// Watched paths by watch descriptor. Paths to the same inode, such as a
+	// directory and a link to it, share one descriptor, which is removed
+	// with the last of them.
+	paths map[int][]string
+	wds   map[string]int
+}
+
+func newInotifyBackend(raw chan<- string, done <-chan struct{}) (watchBackend, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	b := &inotifyBackend{
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		fd:    fd,
+		raw:   raw,
+		done:  done,
+		paths: map[int][]string{},
+		wds:   map[string]int{},
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *inotifyBackend) add(path string) error {
+	wd, err := unix.InotifyAddWatch(b.fd, path, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if old, ok := b.wds[path]; ok && old != wd {
+		// The path now leads to another directory
+		b.release(path)
+	}
+	if !slices.Contains(b.paths[wd], path) {
+		b.paths[wd] = append(b.paths[wd], path)
+	}
+	b.wds[path] = wd
+	return nil
+}
+
+func (b *inotifyBackend) remove(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.release(path)
+}
+
+// release drops path from its watch descriptor and removes the descriptor
+// once no path uses it. The caller holds mu.
+func (b *inotifyBackend) release(path string) {
+	wd, ok := b.wds[path]
+	if !ok {
+		return
+	}
+	delete(b.wds, path)
+	b.paths[wd] = slices.DeleteFunc(b.paths[wd], func(p string) bool { return p == path })
+	if len(b.paths[wd]) == 0 {
+		delete(b.paths, wd)
+		_, _ = unix.InotifyRmWatch(b.fd, uint32(wd))
+	}
+}
+
+func (b *inotifyBackend) watching(path string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.wds[path]
+	return ok
+}
+
+func (b *inotifyBackend) close() {
+	b.file.Close()
+}
+
+// run reads events until the inotify file is closed
+func (b *inotifyBackend) run() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := b.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, path := range b.parse(buf[:n]) {
+			select {
+			case b.raw <- path:
+			case <-b.done:
+				return
+			}
+		}
+	}
+}
+
+// parse returns the watched directories affected by the events in buf
+func (b *inotifyBackend) parse(buf []byte) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var paths []string
+	for offset := 0; offset+unix.SizeofInotifyEvent <= len(buf); {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		offset += unix.SizeofInotifyEvent + int(event.Len)
+
+		if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+			// Events were lost, any directory may have changed
+			for path := range b.wds {
+				paths = append(paths, path)
+			}
+			continue
+		}
+		watched := b.paths[int(event.Wd)]
+		if event.Mask&unix.IN_IGNORED != 0 {
+			// The directory was removed or unmounted and the watch is gone
+			delete(b.paths, int(event.Wd))
+			for _, path := range watched {
+				delete(b.wds, path)
+			}
+		}
+		paths = append(paths, watched...)
+	}
+	return paths
+}
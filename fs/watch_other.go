@@ -0,0 +1,11 @@
+//go:build !linux
+
+package fs
+
+import "errors"
+
+// newInotifyBackend is only implemented on Linux, elsewhere all directories
+// are polled
+func newInotifyBackend(raw chan<- string, done <-chan struct{}) (watchBackend, error) {
+	return nil, errors.New("inotify is not available")
+}
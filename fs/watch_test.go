@@ -0,0 +1,169 @@
+package fs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForChange returns the next reported directory, or "" after timeout
+func waitForChange(w *Watcher, timeout time.Duration) string {
+	select {
+	case path := <-w.Changes:
+		return path
+	case <-time.After(timeout):
+		return ""
+	}
+}
+
+func TestWatcher_ReportsChanges(t *testing.T) {
+	testCases := []struct {
+		name    string
+		watcher func() *Watcher
+	}{
+		{"inotify", func() *Watcher { return NewWatcher(20 * time.Millisecond) }},
+		{"polling", func() *Watcher { return newWatcher(20*time.Millisecond, 20*time.Millisecond) }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			watched, other := t.TempDir(), t.TempDir()
+			w := tc.watcher()
+			defer w.Close()
+			w.Watch(watched)
+
+			// Changes outside the watched directories are not reported
+			if err := os.WriteFile(filepath.Join(other, "ignored.txt"), nil, 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+			if path := waitForChange(w, 200*time.Millisecond); path != "" {
+				t.Fatalf("Expected no change, got %s", path)
+			}
+
+			if err := os.WriteFile(filepath.Join(watched, "new.txt"), nil, 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+			if path := waitForChange(w, 2*time.Second); path != watched {
+				t.Fatalf("Expected change in %s, got %q", watched, path)
+			}
+		})
+	}
+}
+
+func TestWatcher_DebouncesBursts(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWatcher(100 * time.Millisecond)
+	defer w.Close()
+	w.Watch(dir)
+
+	for i := 0; i < 20; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	if path := waitForChange(w, 2*time.Second); path != dir {
+		t.Fatalf("Expected change in %s, got %q", dir, path)
+	}
+	if path := waitForChange(w, 300*time.Millisecond); path != "" {
+		t.Errorf("Expected one change for the burst, got another for %s", path)
+	}
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	w := NewWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	w.Watch(a, b)
+	if got := w.Watched(); len(got) != 2 {
+		t.Fatalf("Expected 2 watched directories, got %v", got)
+	}
+	w.Watch(b)
+	if got := w.Watched(); len(got) != 1 || got[0] != b {
+		t.Fatalf("Expected only %s to be watched, got %v", b, got)
+	}
+	if err := os.WriteFile(filepath.Join(a, "new.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if path := waitForChange(w, 200*time.Millisecond); path != "" {
+		t.Errorf("Expected no change after unwatching %s, got %s", a, path)
+	}
+}
+
+func TestWatcher_RemovedDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gone")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	w := NewWatcher(10 * time.Millisecond)
+	defer w.Close()
+	w.Watch(dir)
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if path := waitForChange(w, 2*time.Second); path != dir {
+		t.Fatalf("Expected change in %s, got %q", dir, path)
+	}
+
+	// Watching the recreated directory works again
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	w.Watch(dir)
+	if err := os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if path := waitForChange(w, 2*time.Second); path != dir {
+		t.Fatalf("Expected change in recreated %s, got %q", dir, path)
+	}
+}
+
+func TestDirSignature(t *testing.T) {
+	dir := t.TempDir()
+	empty := dirSignature(dir)
+	if empty == 0 {
+		t.Error("Expected an empty directory to have a signature")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if dirSignature(dir) == empty {
+		t.Error("Expected the signature to change with a new file")
+	}
+	if dirSignature(filepath.Join(dir, "missing")) != 0 {
+		t.Error("Expected signature 0 for a missing directory")
+	}
+}
+
+func TestWatcher_SameDirectoryTwice(t *testing.T) {
+	// Both panels on one directory, one of them through a link, share the
+	// inotify watch of the directory
+	dir := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatalf("Failed to create link: %v", err)
+	}
+	w := NewWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	w.Watch(dir, link)
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	got := map[string]bool{waitForChange(w, 2*time.Second): true, waitForChange(w, 2*time.Second): true}
+	if !got[dir] || !got[link] {
+		t.Fatalf("Expected changes in %s and %s, got %v", dir, link, got)
+	}
+
+	// The panel leaving the link keeps the watch of the other one alive
+	w.Watch(dir)
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if path := waitForChange(w, 2*time.Second); path != dir {
+		t.Fatalf("Expected change in %s, got %q", dir, path)
+	}
+}
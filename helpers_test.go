@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testKeyTypes are the named keys keyMsg knows, as tea.KeyMsg.String()
+// reports them
+var testKeyTypes = map[string]tea.KeyType{
+	"enter": tea.KeyEnter, "esc": tea.KeyEsc, "tab": tea.KeyTab,
+	"backspace": tea.KeyBackspace, "delete": tea.KeyDelete,
+	"up": tea.KeyUp, "down": tea.KeyDown, "left": tea.KeyLeft, "right": tea.KeyRight,
+	"home": tea.KeyHome, "end": tea.KeyEnd, "pgup": tea.KeyPgUp, "pgdown": tea.KeyPgDown,
+	"shift+up": tea.KeyShiftUp, "shift+down": tea.KeyShiftDown,
+	"ctrl+c": tea.KeyCtrlC, "ctrl+f": tea.KeyCtrlF, "ctrl+g": tea.KeyCtrlG,
+	"ctrl+n": tea.KeyCtrlN, "ctrl+o": tea.KeyCtrlO, "ctrl+p": tea.KeyCtrlP,
+	"f3": tea.KeyF3, "f4": tea.KeyF4, "f7": tea.KeyF7,
+}
+
+// keyMsg builds the message bubbletea sends for key, written as
+// tea.KeyMsg.String() reports it, e.g. "enter", "alt+left" or "x"
+func keyMsg(key string) tea.KeyMsg {
+	if keyType, ok := testKeyTypes[key]; ok {
+		return tea.KeyMsg{Type: keyType}
+	}
+	if name, ok := strings.CutPrefix(key, "alt+"); ok && name != "" {
+		msg := keyMsg(name)
+		msg.Alt = true
+		return msg
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
+
+// runCmd runs cmd and feeds its message, or each message of a batch, into
+// the model. Commands the model returns in turn are not run.
+func runCmd(m model, cmd tea.Cmd) model {
+	if cmd == nil {
+		return m
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, c := range batch {
+			m = runCmd(m, c)
+		}
+		return m
+	}
+	updated, _ := m.Update(msg)
+	return updated.(model)
+}
+
+// readPanel reads the directory of the panel at index into the model
+func readPanel(m model, index int) model {
+	updated, _ := m.Update(m.readDirCmd(index)())
+	return updated.(model)
+}
+
+// pressKeys sends the keys one after another and runs the command each of
+// them returns
+func pressKeys(t *testing.T, m model, keys ...string) model {
+	t.Helper()
+	for _, key := range keys {
+		updated, cmd := m.Update(keyMsg(key))
+		m = runCmd(updated.(model), cmd)
+	}
+	return m
+}
+
+// typeText types text into the model one character at a time
+func typeText(t *testing.T, m model, text string) model {
+	t.Helper()
+	for _, r := range text {
+		m = pressKeys(t, m, string(r))
+	}
+	return m
+}
+
+func TestKeyMsg(t *testing.T) {
+	keys := []string{"x", "R", " ", "alt+r", "alt+left"}
+	for key := range testKeyTypes {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		if got := keyMsg(key).String(); got != key {
+			t.Errorf("keyMsg(%q) is reported as %q", key, got)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxHistory is the number of directories remembered per panel
+const maxHistory = 50
+
+// dirHistory is the list of directories visited in a panel, oldest first,
+// with the position of the current one for going back and forward
+type dirHistory struct {
+	dirs []string
+	pos  int
+}
+
+// visit records path as the current directory. Directories after the current
+// position are dropped, like in a web browser.
+func (h *dirHistory) visit(path string) {
+	if len(h.dirs) > 0 && h.dirs[h.pos] == path {
+		return
+	}
+	if len(h.dirs) > 0 {
+		h.dirs = h.dirs[:h.pos+1]
+	}
+	h.dirs = append(h.dirs, path)
+	if len(h.dirs) > maxHistory {
+		h.dirs = h.dirs[len(h.dirs)-maxHistory:]
+	}
+	h.pos = len(h.dirs) - 1
+}
+
+// recent returns the visited directories, most recent first, without
+// duplicates and without the current one
+func (h *dirHistory) recent() []string {
+	current := ""
+	if len(h.dirs) > 0 {
+		current = h.dirs[h.pos]
+	}
+	seen := map[string]bool{current: true}
+	var dirs []string
+	for i := len(h.dirs) - 1; i >= 0; i-- {
+		if !seen[h.dirs[i]] {
+			seen[h.dirs[i]] = true
+			dirs = append(dirs, h.dirs[i])
+		}
+	}
+	return dirs
+}
+
+// changeDir shows path in the panel at index, with the cursor on the entry
+// called selectName if it is not empty
+func (m *model) changeDir(index int, path, selectName string) tea.Cmd {
+	p := &m.panels[index]
+	p.path = path
+	p.selectName = selectName
+	p.cursor = 0
+	p.clearSelection()
+	p.filter = nil
+	return m.readDirCmd(index)
+}
+
+// stepHistory goes back (step -1) or forward (step 1) in the history of the
+// active panel, skipping directories that no longer exist
+func (m *model) stepHistory(step int) tea.Cmd {
+	p := &m.panels[m.activePanel]
+	if p.isVirtual() {
+		return nil
+	}
+	h := &p.history
+	for pos := h.pos + step; pos >= 0 && pos < len(h.dirs); pos += step {
+		if info, err := os.Stat(h.dirs[pos]); err != nil || !info.IsDir() {
+			continue
+		}
+		h.pos = pos
+		// Coming back up from a subdirectory, put the cursor on it
+		selectName := ""
+		if filepath.Dir(p.path) == h.dirs[pos] {
+			selectName = filepath.Base(p.path)
+		}
+		return m.changeDir(m.activePanel, h.dirs[pos], selectName)
+	}
+	if step < 0 {
+		m.statusMsg = "No earlier directory in history"
+	} else {
+		m.statusMsg = "No later directory in history"
+	}
+	return nil
+}
+
+// openHistory shows the recent directories of the active panel
+func (m *model) openHistory() {
+	p := &m.panels[m.activePanel]
+	dirs := p.history.recent()
+	m.popup = newPopup("Directory history", dirs, func(m *model, index int) tea.Cmd {
+		p := &m.panels[m.activePanel]
+		p.closeSearch()
+		p.trash = nil
+		return m.changeDir(m.activePanel, dirs[index], "")
+	})
+	m.popup.help = m.keys.hints(scopePopup, []keyHint{{[]action{actionOpen}, "Go"}, {[]action{actionClose}, "Close"}})
+}
+
+// stateDir returns the directory for data kept between sessions,
+// $XDG_STATE_HOME/min-commander
+func stateDir() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "min-commander"), nil
+}
+
+// savedHistory is the history file format
+type savedHistory struct {
+	Panels   [2][]string `json:"panels"`
+	Commands []string    `json:"commands,omitempty"` // Command line history
+}
+
+func historyFile() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// loadHistory restores the panel histories of the last session. The start
+// directories are added when the panels are first read.
+func (m *model) loadHistory() error {
+	path, err := historyFile()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var saved savedHistory
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	for i, dirs := range saved.Panels {
+		h := &m.panels[i].history
+		h.dirs = nil
+		for _, dir := range dirs {
+			h.visit(dir)
+		}
+	}
+	m.commands.history = saved.Commands
+	m.commands.pos = len(saved.Commands)
+	return nil
+}
+
+// saveHistory writes the panel and command histories for the next session
+func (m *model) saveHistory() error {
+	path, err := historyFile()
+	if err != nil {
+		return err
+	}
+	var saved savedHistory
+	for i := range m.panels {
+		saved.Panels[i] = m.panels[i].history.dirs
+	}
+	saved.Commands = m.commands.history
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data, creating its directory if needed.
+// Readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		// A temporary file left behind would need cleaning up by hand
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
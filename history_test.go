@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirHistory_Visit(t *testing.T) {
+	var h dirHistory
+	for _, dir := range []string{"/a", "/b", "/b", "/c"} {
+		h.visit(dir)
+	}
+	if strings.Join(h.dirs, ",") != "/a,/b,/c" || h.pos != 2 {
+		t.Fatalf("Unexpected history %v at %d", h.dirs, h.pos)
+	}
+
+	// Visiting after going back drops the later directories
+	h.pos = 0
+	h.visit("/d")
+	if strings.Join(h.dirs, ",") != "/a,/d" || h.pos != 1 {
+		t.Errorf("Unexpected history %v at %d", h.dirs, h.pos)
+	}
+
+	for i := 0; i < maxHistory+10; i++ {
+		h.visit(fmt.Sprintf("/dir%d", i))
+	}
+	if len(h.dirs) != maxHistory || h.dirs[h.pos] != fmt.Sprintf("/dir%d", maxHistory+9) {
+		t.Errorf("Expected the last %d directories, got %d ending with %s", maxHistory, len(h.dirs), h.dirs[h.pos])
+	}
+}
+
+func TestDirHistory_Recent(t *testing.T) {
+	var h dirHistory
+	for _, dir := range []string{"/a", "/b", "/a", "/c", "/b"} {
+		h.visit(dir)
+	}
+	if got := strings.Join(h.recent(), ","); got != "/c,/a" {
+		t.Errorf("Expected /c,/a, got %s", got)
+	}
+}
+
+func TestStepHistory(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	gone := filepath.Join(root, "gone")
+	for _, dir := range []string{sub, gone} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	m := initialModel()
+	for _, dir := range []string{root, gone, sub} {
+		m.panels[0].path = dir
+		m = readPanel(m, 0)
+	}
+	if err := os.Remove(gone); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+
+	// The removed directory is skipped
+	m = pressKeys(t, m, "alt+left")
+	p := &m.panels[0]
+	if p.path != root {
+		t.Fatalf("Expected back to %s, got %s", root, p.path)
+	}
+	if p.entries[p.cursor].Name != "sub" {
+		t.Errorf("Expected cursor on sub, got %s", p.entries[p.cursor].Name)
+	}
+
+	m = pressKeys(t, m, "alt+left")
+	if m.panels[0].path != root || !strings.Contains(m.statusMsg, "No earlier") {
+		t.Errorf("Expected to stay in %s at the start of the history", root)
+	}
+
+	m = pressKeys(t, m, "alt+right")
+	if m.panels[0].path != sub {
+		t.Errorf("Expected forward to %s, got %s", sub, m.panels[0].path)
+	}
+	if len(m.panels[0].history.dirs) != 3 {
+		t.Errorf("Going back and forward should not change the history, got %v", m.panels[0].history.dirs)
+	}
+}
+
+func TestHistoryPopup(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	m := initialModel()
+	for _, dir := range []string{a, b} {
+		m.panels[0].path = dir
+		m = readPanel(m, 0)
+	}
+
+	m = pressKeys(t, m, "alt+h")
+	if m.popup == nil || len(m.popup.items) != 1 || m.popup.items[0] != a {
+		t.Fatalf("Expected a popup listing %s", a)
+	}
+	m = pressKeys(t, m, "enter")
+	if m.popup != nil || m.panels[0].path != a {
+		t.Errorf("Expected the popup to close and the panel to show %s, got %s", a, m.panels[0].path)
+	}
+}
+
+func TestHistory_SaveAndLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := initialModel()
+	m.panels[0].history.visit("/a")
+	m.panels[0].history.visit("/b")
+	m.panels[1].history.visit("/c")
+	if err := m.saveHistory(); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded := initialModel()
+	if err := loaded.loadHistory(); err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+	h := loaded.panels[0].history
+	if strings.Join(h.dirs, ",") != "/a,/b" || h.pos != 1 {
+		t.Errorf("Unexpected loaded history %v at %d", h.dirs, h.pos)
+	}
+	if strings.Join(loaded.panels[1].history.dirs, ",") != "/c" {
+		t.Errorf("Unexpected loaded history %v", loaded.panels[1].history.dirs)
+	}
+
+	dir, _ := stateDir()
+	if _, err := os.Stat(filepath.Join(dir, "history.json")); err != nil {
+		t.Errorf("Expected the history in the state directory: %v", err)
+	}
+}
+
+func TestWriteFileAtomic_FailureLeavesNoTemporaryFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	// A directory that is not empty cannot be replaced by the rename
+	if err := os.MkdirAll(filepath.Join(path, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if err := writeFileAtomic(path, []byte("{}")); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the directory left, got %v, %v", entries, err)
+	}
+}
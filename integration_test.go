@@ -109,11 +109,16 @@ func TestIntegration_HandleFileOperation(t *testing.T) {
 	m := initialModel()
 	m.panels[0].path = testDir
 	m.panels[0].entries, _ = fs.ReadDir(testDir)
+	// Copy into an isolated destination instead of the default "/"
+	m.panels[1].path = t.TempDir()
 
-	// Test copy operation
-	cmd := m.handleFileOperation("copy")
+	// Test copy operation, to the destination the prompt suggests
+	if cmd := m.handleFileOperation("copy"); cmd != nil {
+		t.Error("handleFileOperation should ask for the destination first")
+	}
+	m, cmd := acceptDestination(t, m)
 	if cmd == nil {
-		t.Error("handleFileOperation should return a command")
+		t.Fatal("Accepting the destination should return a command")
 	}
 
 	// Execute the command
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+const (
+	defaultJobConcurrency = 2
+	maxJobConcurrency     = 8
+)
+
+type jobState int
+
+const (
+	jobPending jobState = iota
+	jobRunning
+	jobDone
+	jobFailed
+	jobCancelled
+)
+
+func (s jobState) String() string {
+	switch s {
+	case jobPending:
+		return "pending"
+	case jobRunning:
+		return "running"
+	case jobDone:
+		return "done"
+	case jobFailed:
+		return "failed"
+	}
+	return "cancelled"
+}
+
+// job is a file operation in the job queue
+type job struct {
+	id       int
+	fo       *fileOperation
+	state    jobState
+	progress fs.Progress
+	err      error
+	pauser   *fs.Pauser
+	cancel   context.CancelFunc // Set while running
+	updates  <-chan tea.Msg     // Progress and result of the running job
+}
+
+// active reports whether the job is queued or running
+func (j *job) active() bool {
+	return j.state == jobPending || j.state == jobRunning
+}
+
+// status describes the state for the jobs panel
+func (j *job) status() string {
+	if j.active() && j.pauser.Paused() {
+		return "paused"
+	}
+	return j.state.String()
+}
+
+// jobList is the queue of file operations. At most limit() jobs run at the
+// same time; paused running jobs keep their slot. The zero value is ready to
+// use.
+type jobList struct {
+	jobs        []*job
+	nextID      int
+	concurrency int  // Jobs run at once, 0 means defaultJobConcurrency
+	cursor      int  // Selected job in the jobs panel
+	open        bool // Jobs panel shown instead of the file panels
+	quitting    bool // Quit once the cancelled jobs have stopped
+}
+
+// limit returns the number of jobs that may run at once
+func (jl *jobList) limit() int {
+	if jl.concurrency <= 0 {
+		return defaultJobConcurrency
+	}
+	return jl.concurrency
+}
+
+// jobProgressMsg carries a progress snapshot of a running job
+type jobProgressMsg struct {
+	id       int
+	progress fs.Progress
+}
+
+// waitForJobCmd waits for the next progress update or the final result
+func waitForJobCmd(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (jl *jobList) find(id int) *job {
+	for _, j := range jl.jobs {
+		if j.id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// count returns the number of jobs in state
+func (jl *jobList) count(state jobState) int {
+	n := 0
+	for _, j := range jl.jobs {
+		if j.state == state {
+			n++
+		}
+	}
+	return n
+}
+
+// activeCount returns the number of queued and running jobs
+func (jl *jobList) activeCount() int {
+	return jl.count(jobPending) + jl.count(jobRunning)
+}
+
+// cancelAll stops all running jobs and drops the queued ones
+func (jl *jobList) cancelAll() {
+	for _, j := range jl.jobs {
+		jl.cancelJob(j)
+	}
+}
+
+func (jl *jobList) cancelJob(j *job) {
+	switch j.state {
+	case jobPending:
+		j.state = jobCancelled
+	case jobRunning:
+		// The job reports context.Canceled when it has stopped
+		j.cancel()
+	}
+}
+
+// enqueueJob adds fo to the job queue and starts it if a slot is free
+func (m *model) enqueueJob(fo *fileOperation) tea.Cmd {
+	m.jobs.nextID++
+	m.jobs.jobs = append(m.jobs.jobs, &job{id: m.jobs.nextID, fo: fo, pauser: &fs.Pauser{}})
+	return m.startJobs()
+}
+
+// startJobs runs queued jobs, oldest first, until all slots are taken
+func (m *model) startJobs() tea.Cmd {
+	var cmds []tea.Cmd
+	running := m.jobs.count(jobRunning)
+	for _, j := range m.jobs.jobs {
+		if running >= m.jobs.limit() {
+			break
+		}
+		if j.state == jobPending && !j.pauser.Paused() {
+			cmds = append(cmds, runJob(j))
+			running++
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// runJob starts j in the background. Progress and the final fileOpResultMsg
+// are delivered through the job's updates.
+func runJob(j *job) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan tea.Msg, 1)
+	j.state = jobRunning
+	j.err = nil
+	j.progress = fs.Progress{}
+	j.cancel = cancel
+	j.updates = updates
+
+	id, fo, pauser := j.id, j.fo, j.pauser
+	go func() {
+		defer close(updates)
+		defer cancel()
+		result := runFileOperationItems(ctx, fo, pauser, func(p fs.Progress) {
+			// Drop updates the UI has not caught up with yet
+			select {
+			case updates <- jobProgressMsg{id: id, progress: p}:
+			default:
+			}
+		})
+		result.jobID = id
+		updates <- result
+	}()
+	return waitForJobCmd(updates)
+}
+
+// handleJobProgress stores the progress of a running job
+func (m *model) handleJobProgress(msg jobProgressMsg) tea.Cmd {
+	j := m.jobs.find(msg.id)
+	if j == nil || j.state != jobRunning {
+		return nil
+	}
+	j.progress = msg.progress
+	return waitForJobCmd(j.updates)
+}
+
+// finishJob records the result of a job and starts the next queued ones
+func (m *model) finishJob(msg fileOpResultMsg) tea.Cmd {
+	j := m.jobs.find(msg.jobID)
+	if j == nil {
+		return nil
+	}
+	j.cancel = nil
+	j.updates = nil
+	j.err = msg.err
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		j.state = jobCancelled
+	case msg.err != nil:
+		j.state = jobFailed
+	default:
+		j.state = jobDone
+	}
+	if m.jobs.quitting && m.jobs.activeCount() == 0 {
+		return tea.Quit
+	}
+	return m.startJobs()
+}
+
+// openJobs shows the jobs panel
+func (m *model) openJobs() {
+	m.jobs.open = true
+	m.jobs.cursor = max(0, min(m.jobs.cursor, len(m.jobs.jobs)-1))
+}
+
+// jobHints are the keys listed below the jobs panel
+var jobHints = []keyHint{
+	{[]action{actionUp, actionDown}, "Select"},
+	{[]action{actionPauseJob}, "Pause/Resume"},
+	{[]action{actionRetryJob}, "Retry"},
+	{[]action{actionCancel}, "Cancel"},
+	{[]action{actionClearJobs}, "Clear finished"},
+	{[]action{actionMoreJobs, actionFewerJobs}, "Concurrency"},
+	{[]action{actionClose}, "Close"},
+}
+
+// updateJobs handles the keys of the jobs panel
+func (m *model) updateJobs(msg tea.KeyMsg) tea.Cmd {
+	jl := &m.jobs
+	var j *job
+	if jl.cursor < len(jl.jobs) {
+		j = jl.jobs[jl.cursor]
+	}
+
+	switch m.keys.lookupIn(scopeJobs, msg.String()) {
+	case actionQuit:
+		return m.quit()
+	case actionClose:
+		jl.open = false
+	case actionUp:
+		if jl.cursor > 0 {
+			jl.cursor--
+		}
+	case actionDown:
+		if jl.cursor < len(jl.jobs)-1 {
+			jl.cursor++
+		}
+	case actionPauseJob:
+		if j == nil || !j.active() {
+			return nil
+		}
+		if j.pauser.Paused() {
+			j.pauser.Resume()
+			m.statusMsg = fmt.Sprintf("Resumed job %d", j.id)
+			return m.startJobs()
+		}
+		j.pauser.Pause()
+		m.statusMsg = fmt.Sprintf("Paused job %d", j.id)
+	case actionRetryJob:
+		if j == nil || (j.state != jobFailed && j.state != jobCancelled) {
+			return nil
+		}
+		j.state = jobPending
+		j.err = nil
+		j.pauser.Resume()
+		m.statusMsg = fmt.Sprintf("Retrying job %d", j.id)
+		return m.startJobs()
+	case actionCancel:
+		if j == nil || !j.active() {
+			return nil
+		}
+		jl.cancelJob(j)
+		m.statusMsg = fmt.Sprintf("Cancelled job %d", j.id)
+	case actionClearJobs:
+		var kept []*job
+		for _, j := range jl.jobs {
+			if j.active() {
+				kept = append(kept, j)
+			}
+		}
+		jl.jobs = kept
+		jl.cursor = max(0, min(jl.cursor, len(jl.jobs)-1))
+	case actionMoreJobs:
+		jl.concurrency = min(jl.limit()+1, maxJobConcurrency)
+		m.statusMsg = fmt.Sprintf("Running up to %d jobs at once", jl.concurrency)
+		return m.startJobs()
+	case actionFewerJobs:
+		jl.concurrency = max(jl.limit()-1, 1)
+		m.statusMsg = fmt.Sprintf("Running up to %d jobs at once", jl.concurrency)
+	}
+	return nil
+}
+
+// quit exits the program, asking first if jobs would be interrupted. Running
+// jobs are cancelled and the program quits once they have stopped, so that
+// they can remove their partial files.
+func (m *model) quit() tea.Cmd {
+	n := m.jobs.activeCount()
+	if n == 0 {
+		return tea.Quit
+	}
+	m.dialog = newConfirmDialog("Quit", fmt.Sprintf("%d jobs are still running or queued.\nCancel them and quit?", n), func(m *model) tea.Cmd {
+		m.jobs.cancelAll()
+		if m.jobs.activeCount() == 0 {
+			return tea.Quit
+		}
+		m.jobs.quitting = true
+		m.statusMsg = "Stopping jobs before quitting…"
+		return nil
+	})
+	return nil
+}
+
+// describe summarizes what the job does
+func (j *job) describe() string {
+	fo := j.fo
+	switch fo.op {
+	case "copy":
+		return fmt.Sprintf("Copy %s -> %s", fo.name, fo.dstDir)
+	case "move":
+		return fmt.Sprintf("Move %s -> %s", fo.name, fo.dstDir)
+	case "trash":
+		return fmt.Sprintf("Trash %s", fo.name)
+	case "delete":
+		return fmt.Sprintf("Delete %s", fo.name)
+	}
+	return fo.op + " " + fo.name
+}
+
+// jobStatusLine renders the progress of the oldest running copy or move for
+// the status line, or "" if there is none
+func (m model) jobStatusLine() string {
+	j := m.jobs.progressJob()
+	if j == nil {
+		return ""
+	}
+	line := renderProgress(j.fo.op, j.fo.name, j.progress)
+	if keys := m.keys.keysFor(actionCancel); len(keys) > 0 {
+		line += fmt.Sprintf("  (%s: cancel)", displayKey(keys[0]))
+	}
+	if j.pauser.Paused() {
+		line += "  (paused)"
+	}
+	if running := m.jobs.count(jobRunning); running > 1 {
+		line += fmt.Sprintf("  (+%d jobs", running-1)
+		if keys := m.keys.keysFor(actionJobs); len(keys) > 0 {
+			line += fmt.Sprintf(", %s: jobs", displayKey(keys[0]))
+		}
+		line += ")"
+	}
+	return line
+}
+
+// progressJob returns the running job shown in the status line, or nil
+func (jl *jobList) progressJob() *job {
+	for _, j := range jl.jobs {
+		if j.state == jobRunning && showsProgress(j.fo.op) {
+			return j
+		}
+	}
+	return nil
+}
+
+// renderJobs renders the jobs panel in place of the two file panels
+func (m model) renderJobs() string {
+	jl := &m.jobs
+	// Same outer size as the two file panels side by side
+	style := activePanelStyle.Width(activePanelStyle.GetWidth()*2 + 2)
+	width := style.GetWidth() - 2
+	height := 20
+	if style.GetHeight() > 0 {
+		height = style.GetHeight()
+	}
+
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf(" Jobs: %d running, %d queued (up to %d at once)\n",
+		jl.count(jobRunning), jl.count(jobPending), jl.limit()))
+	if len(jl.jobs) == 0 {
+		s.WriteString(" No jobs")
+	}
+
+	// The header takes the first row
+	rows := height - 1
+	offset := max(0, jl.cursor-rows+1)
+	for i := offset; i < len(jl.jobs) && i < offset+rows; i++ {
+		j := jl.jobs[i]
+		line := fmt.Sprintf("%3d  %-9s %s", j.id, j.status(), j.describe())
+		switch {
+		case j.state == jobRunning && showsProgress(j.fo.op):
+			p := j.progress
+			line += fmt.Sprintf("  %3d%%  files %d/%d", progressPercent(p), p.FilesDone, p.FilesTotal)
+		case j.state == jobFailed:
+			line += ": " + strings.ReplaceAll(j.err.Error(), "\n", "; ")
+		}
+		line = ansi.Truncate(line, width, "…")
+		if i == jl.cursor {
+			line = selectedStyle.Render(line)
+		} else if j.state == jobFailed {
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render(line)
+		}
+		s.WriteString(line + "\n")
+	}
+	return style.Render(strings.TrimSuffix(s.String(), "\n"))
+}
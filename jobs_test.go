@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// copyOperation creates a copy of the named entries of srcDir into dstDir
+func copyOperation(srcDir, dstDir string, names ...string) *fileOperation {
+	fo := &fileOperation{op: "copy", name: names[0], srcDir: srcDir, dstDir: dstDir}
+	for _, name := range names {
+		fo.items = append(fo.items, fileOpItem{entry: fs.FileEntry{Name: name}, dstName: name})
+	}
+	return fo
+}
+
+// finishJobForTest drives the running job until its result arrives
+func finishJobForTest(t *testing.T, m model, id int) (model, fileOpResultMsg) {
+	t.Helper()
+	j := m.jobs.find(id)
+	if j == nil || j.state != jobRunning {
+		t.Fatalf("Job %d is not running", id)
+	}
+	return finishOperation(t, m, waitForJobCmd(j.updates))
+}
+
+func TestJobs_Concurrency(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt", "b.txt")
+	srcDir := m.panels[0].path
+	m.jobs.concurrency = 1
+
+	m.enqueueJob(copyOperation(srcDir, dstDir, "a.txt"))
+	if cmd := m.enqueueJob(copyOperation(srcDir, dstDir, "b.txt")); cmd != nil {
+		t.Error("Second job should wait for a free slot")
+	}
+	if m.jobs.jobs[0].state != jobRunning || m.jobs.jobs[1].state != jobPending {
+		t.Fatalf("Expected running and pending jobs, got %v and %v", m.jobs.jobs[0].state, m.jobs.jobs[1].state)
+	}
+
+	m, msg := finishJobForTest(t, m, 1)
+	if msg.jobID != 1 || msg.err != nil {
+		t.Fatalf("Unexpected result of job 1: %+v", msg)
+	}
+	if m.jobs.jobs[0].state != jobDone || m.jobs.jobs[1].state != jobRunning {
+		t.Fatalf("Finishing job 1 should start job 2, got %v and %v", m.jobs.jobs[0].state, m.jobs.jobs[1].state)
+	}
+
+	m, _ = finishJobForTest(t, m, 2)
+	if m.jobs.activeCount() != 0 {
+		t.Error("All jobs should be finished")
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(dstDir, name)); err != nil {
+			t.Errorf("%s should have been copied: %v", name, err)
+		}
+	}
+}
+
+func TestJobs_PauseAndCancelPending(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt", "b.txt")
+	srcDir := m.panels[0].path
+	m.jobs.concurrency = 1
+	m.jobs.open = true
+
+	m.enqueueJob(copyOperation(srcDir, dstDir, "a.txt"))
+	m.enqueueJob(copyOperation(srcDir, dstDir, "b.txt"))
+
+	// Pause the queued job, it must not start when the slot frees up
+	m.jobs.cursor = 1
+	m, _ = pressKey(m, "p")
+	if m.jobs.jobs[1].status() != "paused" {
+		t.Fatalf("Expected paused job, got %s", m.jobs.jobs[1].status())
+	}
+	m, _ = finishJobForTest(t, m, 1)
+	if m.jobs.jobs[1].state != jobPending {
+		t.Fatal("Paused job should stay queued")
+	}
+
+	m, _ = pressKey(m, "x")
+	if m.jobs.jobs[1].state != jobCancelled {
+		t.Fatalf("Expected cancelled job, got %v", m.jobs.jobs[1].state)
+	}
+
+	// Retrying resumes and runs the job
+	m, cmd := pressKey(m, "r")
+	if cmd == nil || m.jobs.jobs[1].state != jobRunning {
+		t.Fatal("Retry should start the job again")
+	}
+	m, msg := finishOperation(t, m, cmd)
+	if msg.err != nil {
+		t.Fatalf("Retried job failed: %v", msg.err)
+	}
+
+	m, _ = pressKey(m, "C")
+	if len(m.jobs.jobs) != 0 {
+		t.Errorf("Clearing should remove finished jobs, %d left", len(m.jobs.jobs))
+	}
+}
+
+func TestJobs_CancelRunning(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt")
+	m.jobs.open = true
+
+	m.enqueueJob(copyOperation(m.panels[0].path, dstDir, "a.txt"))
+	// Hold the job so that the cancellation hits it while it runs
+	m.jobs.jobs[0].pauser.Pause()
+
+	m, _ = pressKey(m, "x")
+	m, msg := finishJobForTest(t, m, 1)
+	if m.jobs.jobs[0].state != jobCancelled {
+		t.Fatalf("Expected cancelled job, got %v (%v)", m.jobs.jobs[0].state, msg.err)
+	}
+}
+
+func TestJobs_RetrySkipsCompletedItems(t *testing.T) {
+	m, dstDir := fileOpsTestModel(t, "a.txt", "b.txt")
+	srcDir := m.panels[0].path
+
+	// The second item fails because its destination directory is missing
+	fo := copyOperation(srcDir, dstDir, "a.txt", "b.txt")
+	fo.items[1].dstName = filepath.Join("sub", "b.txt")
+	m.enqueueJob(fo)
+	m, msg := finishJobForTest(t, m, 1)
+	if msg.err == nil || m.jobs.jobs[0].state != jobFailed {
+		t.Fatal("Job with a missing destination directory should fail")
+	}
+
+	// A file that replaced the first copy must not be overwritten by the retry
+	if err := os.WriteFile(filepath.Join(dstDir, "a.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dstDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	m.jobs.open = true
+	m, cmd := pressKey(m, "r")
+	m, msg = finishOperation(t, m, cmd)
+	if msg.err != nil || m.jobs.jobs[0].state != jobDone {
+		t.Fatalf("Retry should succeed, got %v", msg.err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dstDir, "a.txt")); string(data) != "edited" {
+		t.Errorf("Completed item was copied again: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dstDir, "sub", "b.txt")); err != nil {
+		t.Errorf("Failed item should be copied on retry: %v", err)
+	}
+}
+
+func TestJobs_QuitWarnsAboutActiveJobs(t *testing.T) {
+	m := initialModel()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.jobs.jobs = []*job{{id: 1, fo: &fileOperation{op: "copy"}, state: jobRunning, cancel: cancel, pauser: &fs.Pauser{}}}
+
+	m, cmd := pressKey(m, "q")
+	if cmd != nil || m.dialog == nil {
+		t.Fatal("Quitting with running jobs should ask first")
+	}
+
+	m, cmd = pressKey(m, "y")
+	if cmd != nil || m.dialog != nil || !m.jobs.quitting {
+		t.Fatal("Confirming should wait for the running job to stop")
+	}
+	if ctx.Err() == nil {
+		t.Error("Quitting should cancel running jobs")
+	}
+
+	// The job has removed its partial files once it reports
+	_, cmd = m.Update(fileOpResultMsg{jobID: 1, op: "copy", err: context.Canceled})
+	quits := false
+	for _, c := range cmd().(tea.BatchMsg) {
+		if c != nil {
+			_, ok := c().(tea.QuitMsg)
+			quits = quits || ok
+		}
+	}
+	if !quits {
+		t.Error("Expected to quit once the job has stopped")
+	}
+}
+
+func TestJobs_EscCancelsTransferInProgressBar(t *testing.T) {
+	m := initialModel()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.jobs.jobs = []*job{
+		{id: 1, fo: &fileOperation{op: "delete"}, state: jobRunning, cancel: func() {}, pauser: &fs.Pauser{}},
+		{id: 2, fo: &fileOperation{op: "copy", name: "big.iso"}, state: jobRunning, cancel: cancel, pauser: &fs.Pauser{}},
+	}
+	if line := m.jobStatusLine(); !strings.Contains(line, "Copying big.iso") || !strings.Contains(line, "(Esc: cancel)") {
+		t.Errorf("Expected the copy with the cancel hint, got %q", line)
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if ctx.Err() == nil {
+		t.Error("Esc should cancel the transfer shown in the progress bar")
+	}
+	if updated.(model).jobs.jobs[1].state != jobRunning {
+		t.Error("The job should run until it reports that it stopped")
+	}
+}
+
+func TestJobs_HelpFollowsKeymap(t *testing.T) {
+	m := initialModel()
+	m.width, m.height = 100, 30
+	if err := m.keys.bind(scopeJobs, actionRetryJob, []string{"f5"}); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	if err := m.keys.bind(scopePanel, actionJobs, []string{"J"}); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	m.jobs.jobs = []*job{
+		{id: 1, fo: &fileOperation{op: "copy", name: "a"}, state: jobRunning, pauser: &fs.Pauser{}},
+		{id: 2, fo: &fileOperation{op: "copy", name: "b"}, state: jobRunning, pauser: &fs.Pauser{}},
+	}
+	if line := m.jobStatusLine(); !strings.Contains(line, "(+1 jobs, J: jobs)") {
+		t.Errorf("Expected the remapped jobs key in the status line, got %q", line)
+	}
+
+	m.jobs.open = true
+	if view := m.View(); !strings.Contains(view, "F5: Retry") || strings.Contains(view, "r: Retry") {
+		t.Errorf("Expected the remapped retry key in the help line:\n%s", view)
+	}
+}
+
+func TestRenderJobs_FitsPanelHeight(t *testing.T) {
+	m := initialModel()
+	for i := 1; i <= 30; i++ {
+		m.jobs.jobs = append(m.jobs.jobs, &job{id: i, fo: &fileOperation{op: "copy", name: "a"}, state: jobDone})
+	}
+	m.jobs.cursor = len(m.jobs.jobs) - 1
+
+	view := m.renderJobs()
+	// The content area plus the top and bottom border
+	if got, want := lipgloss.Height(view), activePanelStyle.GetHeight()+2; got != want {
+		t.Errorf("Expected %d lines, got %d:\n%s", want, got, view)
+	}
+	if !strings.Contains(view, " 30  ") {
+		t.Errorf("Expected the cursor job shown:\n%s", view)
+	}
+}
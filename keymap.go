@@ -0,0 +1,507 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// action is a command that can be bound to keys
+type action string
+
+// keyScope is a part of the interface with bindings of its own. The viewer,
+// the jobs panel and the popups only see the keys of their scope, so a key
+// can do something else there than in the file panels.
+type keyScope string
+
+const (
+	scopePanel     keyScope = ""
+	scopeViewer    keyScope = "viewer"
+	scopeJobs      keyScope = "jobs"
+	scopePopup     keyScope = "popup"
+	scopeBookmarks keyScope = "bookmarks"
+	scopeTrash     keyScope = "trash"
+	scopeRename    keyScope = "rename"
+	scopeCommand   keyScope = "command"
+)
+
+// keyScopes lists every scope
+var keyScopes = []keyScope{scopePanel, scopeViewer, scopeJobs, scopePopup, scopeBookmarks, scopeTrash, scopeRename, scopeCommand}
+
+const (
+	actionQuit          action = "quit"
+	actionSwitchPanel   action = "switch_panel"
+	actionUp            action = "up"
+	actionDown          action = "down"
+	actionPageUp        action = "page_up"
+	actionPageDown      action = "page_down"
+	actionOpen          action = "open"
+	actionParent        action = "parent"
+	actionCancel        action = "cancel"
+	actionSearch        action = "search"
+	actionFilter        action = "filter"
+	actionHistoryBack   action = "history_back"
+	actionHistoryFwd    action = "history_forward"
+	actionHistory       action = "history"
+	actionBookmarks     action = "bookmarks"
+	actionAddBookmark   action = "add_bookmark"
+	actionMark          action = "mark"
+	actionMarkPattern   action = "mark_pattern"
+	actionUnmarkPattern action = "unmark_pattern"
+	actionInvertMarks   action = "invert_marks"
+	actionView          action = "view"
+	actionCopy          action = "copy"
+	actionMove          action = "move"
+	actionTrash         action = "trash"
+	actionDelete        action = "delete"
+	actionTrashView     action = "trash_view"
+	actionJobs          action = "jobs"
+	actionSortNext      action = "sort_next"
+	actionSortReverse   action = "sort_reverse"
+	actionSortDirs      action = "sort_dirs"
+	actionListing       action = "listing"
+	actionColumns       action = "columns"
+	actionSymlinks      action = "symlinks"
+	actionHidden        action = "hidden"
+	actionCommandLine   action = "command_line"
+	actionOutput        action = "output"
+	actionEdit          action = "edit"
+	actionEditNew       action = "edit_new"
+	actionMkdir         action = "mkdir"
+	actionNewFile       action = "new_file"
+	actionRename        action = "rename"
+	actionBatchRename   action = "batch_rename"
+	actionUndoRename    action = "undo_rename"
+
+	// Actions of the other scopes
+	actionTop         action = "top"
+	actionBottom      action = "bottom"
+	actionClose       action = "close"
+	actionScrollLeft  action = "scroll_left"
+	actionScrollRight action = "scroll_right"
+	actionPauseJob    action = "pause"
+	actionRetryJob    action = "retry"
+	actionClearJobs   action = "clear"
+	actionMoreJobs    action = "more_jobs"
+	actionFewerJobs   action = "fewer_jobs"
+	actionAdd         action = "add"
+	actionMoveUp      action = "move_up"
+	actionMoveDown    action = "move_down"
+	actionRestore     action = "restore"
+	actionNextField   action = "next_field"
+	actionPrevField   action = "prev_field"
+	actionRun         action = "run"
+)
+
+// actionBookmarkPrefix starts the actions bookmark_0 to bookmark_9, which
+// jump to the bookmark with that digit as its key
+const actionBookmarkPrefix = "bookmark_"
+
+// binding is an action with its keys
+type binding struct {
+	action action
+	keys   []string
+}
+
+// defaultBindings are the keys of each panel action without configuration.
+// The letters c, r and d stay next to F5, F6 and F8 for terminals (such as
+// the one in VS Code) that take the function keys for themselves.
+var defaultBindings = []binding{
+	{actionQuit, []string{"q", "ctrl+c"}},
+	{actionSwitchPanel, []string{"tab"}},
+	{actionUp, []string{"up"}},
+	{actionDown, []string{"down"}},
+	{actionPageUp, []string{"pgup"}},
+	{actionPageDown, []string{"pgdown"}},
+	{actionOpen, []string{"enter"}},
+	{actionParent, []string{"backspace"}},
+	{actionCancel, []string{"esc"}},
+	{actionSearch, []string{"/"}},
+	{actionFilter, []string{"ctrl+f"}},
+	{actionHistoryBack, []string{"alt+left"}},
+	{actionHistoryFwd, []string{"alt+right"}},
+	{actionHistory, []string{"alt+h"}},
+	{actionBookmarks, []string{"b"}},
+	{actionAddBookmark, []string{"B"}},
+	{actionMark, []string{"insert", " "}},
+	{actionMarkPattern, []string{"+"}},
+	{actionUnmarkPattern, []string{"-"}},
+	{actionInvertMarks, []string{"*"}},
+	{actionView, []string{"v", "f3"}},
+	{actionEdit, []string{"e", "f4"}},
+	{actionEditNew, []string{"E", "f16"}}, // Terminals report Shift+F4 as F16
+	{actionCopy, []string{"c", "f5"}},
+	{actionMove, []string{"r", "f6"}},
+	{actionRename, []string{"R", "f18"}}, // Terminals report Shift+F6 as F18
+	{actionBatchRename, []string{"alt+r"}},
+	{actionUndoRename, []string{"u"}},
+	{actionMkdir, []string{"m", "f7"}},
+	{actionNewFile, []string{"n"}},
+	{actionTrash, []string{"d", "f8"}},
+	{actionDelete, []string{"D"}},
+	{actionTrashView, []string{"t"}},
+	{actionJobs, []string{"j"}},
+	{actionSortNext, []string{"s"}},
+	{actionSortReverse, []string{"S"}},
+	{actionSortDirs, []string{"alt+d"}},
+	{actionListing, []string{"alt+t"}},
+	{actionColumns, []string{"alt+c"}},
+	{actionSymlinks, []string{"L"}},
+	{actionHidden, []string{"h"}},
+	{actionCommandLine, []string{":"}},
+	{actionOutput, []string{"ctrl+o"}},
+	{actionBookmarkPrefix + "0", []string{"alt+0"}},
+	{actionBookmarkPrefix + "1", []string{"alt+1"}},
+	{actionBookmarkPrefix + "2", []string{"alt+2"}},
+	{actionBookmarkPrefix + "3", []string{"alt+3"}},
+	{actionBookmarkPrefix + "4", []string{"alt+4"}},
+	{actionBookmarkPrefix + "5", []string{"alt+5"}},
+	{actionBookmarkPrefix + "6", []string{"alt+6"}},
+	{actionBookmarkPrefix + "7", []string{"alt+7"}},
+	{actionBookmarkPrefix + "8", []string{"alt+8"}},
+	{actionBookmarkPrefix + "9", []string{"alt+9"}},
+}
+
+// scopeBindings are the default keys of the scopes besides the panels. In
+// the jobs panel cancel stops the selected job, in the bookmarks popup
+// rename and delete edit the selected bookmark. The batch rename dialog and
+// the command line pass the keys that are not bound on to their input.
+var scopeBindings = map[keyScope][]binding{
+	scopeViewer: {
+		{actionUp, []string{"up"}},
+		{actionDown, []string{"down"}},
+		{actionPageUp, []string{"pgup"}},
+		{actionPageDown, []string{"pgdown", " "}},
+		{actionTop, []string{"home"}},
+		{actionBottom, []string{"end"}},
+		{actionScrollLeft, []string{"left"}},
+		{actionScrollRight, []string{"right"}},
+		{actionClose, []string{"esc", "q", "v", "f3"}},
+		{actionQuit, []string{"ctrl+c"}},
+	},
+	scopeJobs: {
+		{actionUp, []string{"up"}},
+		{actionDown, []string{"down"}},
+		{actionPauseJob, []string{"p", " "}},
+		{actionRetryJob, []string{"r"}},
+		{actionCancel, []string{"x", "delete"}},
+		{actionClearJobs, []string{"C"}},
+		{actionMoreJobs, []string{"+"}},
+		{actionFewerJobs, []string{"-"}},
+		{actionClose, []string{"esc", "j"}},
+		{actionQuit, []string{"q", "ctrl+c"}},
+	},
+	scopePopup: {
+		{actionUp, []string{"up"}},
+		{actionDown, []string{"down"}},
+		{actionPageUp, []string{"pgup"}},
+		{actionPageDown, []string{"pgdown"}},
+		{actionTop, []string{"home"}},
+		{actionBottom, []string{"end"}},
+		{actionOpen, []string{"enter"}},
+		{actionClose, []string{"esc", "q"}},
+		{actionQuit, []string{"ctrl+c"}},
+	},
+	scopeBookmarks: {
+		{actionAdd, []string{"a"}},
+		{actionRename, []string{"n"}},
+		{actionDelete, []string{"x", "delete"}},
+		{actionMoveUp, []string{"shift+up"}},
+		{actionMoveDown, []string{"shift+down"}},
+	},
+	scopeTrash: {
+		{actionRestore, []string{"u"}},
+	},
+	scopeRename: {
+		{actionNextField, []string{"tab"}},
+		{actionPrevField, []string{"shift+tab"}},
+		{actionUp, []string{"up"}},
+		{actionDown, []string{"down"}},
+		{actionPageUp, []string{"pgup"}},
+		{actionPageDown, []string{"pgdown"}},
+		{actionRename, []string{"enter"}},
+		{actionCancel, []string{"esc"}},
+		{actionQuit, []string{"ctrl+c"}},
+	},
+	scopeCommand: {
+		{actionUp, []string{"up"}},
+		{actionDown, []string{"down"}},
+		{actionRun, []string{"enter"}},
+		{actionCancel, []string{"esc"}},
+		{actionQuit, []string{"ctrl+c"}},
+	},
+}
+
+// bindingsOf returns the default bindings of scope
+func bindingsOf(scope keyScope) []binding {
+	if scope == scopePanel {
+		return defaultBindings
+	}
+	return scopeBindings[scope]
+}
+
+// isScope reports whether name is a known scope
+func isScope(name string) bool {
+	for _, scope := range keyScopes {
+		if string(scope) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// keymap binds keys, as reported by tea.KeyMsg.String(), to the actions of
+// each scope. The zero value uses the default bindings.
+type keymap struct {
+	scopes map[keyScope]*scopeKeys
+}
+
+// scopeKeys are the bindings of one scope
+type scopeKeys struct {
+	byKey    map[string]action
+	byAction map[action][]string
+}
+
+// defaultKeymap returns a keymap with the default bindings
+func defaultKeymap() keymap {
+	k := keymap{scopes: map[keyScope]*scopeKeys{}}
+	for _, scope := range keyScopes {
+		sk := &scopeKeys{byKey: map[string]action{}, byAction: map[action][]string{}}
+		for _, binding := range bindingsOf(scope) {
+			sk.byAction[binding.action] = binding.keys
+			for _, key := range binding.keys {
+				sk.byKey[key] = binding.action
+			}
+		}
+		k.scopes[scope] = sk
+	}
+	return k
+}
+
+var defaultKeys = defaultKeymap()
+
+// in returns the bindings of scope
+func (k keymap) in(scope keyScope) *scopeKeys {
+	if k.scopes == nil {
+		return defaultKeys.scopes[scope]
+	}
+	return k.scopes[scope]
+}
+
+// lookup returns the panel action bound to key, or "" if there is none
+func (k keymap) lookup(key string) action {
+	return k.lookupIn(scopePanel, key)
+}
+
+// lookupIn returns the action of scope bound to key, or "" if there is none
+func (k keymap) lookupIn(scope keyScope, key string) action {
+	return k.in(scope).byKey[key]
+}
+
+// keysFor returns the keys bound to the panel action a
+func (k keymap) keysFor(a action) []string {
+	return k.keysIn(scopePanel, a)
+}
+
+// keysIn returns the keys bound to a in scope
+func (k keymap) keysIn(scope keyScope, a action) []string {
+	return k.in(scope).byAction[a]
+}
+
+// bind replaces the keys of a in scope. It fails if one of the keys is bound
+// to another action of the scope.
+func (k *keymap) bind(scope keyScope, a action, keys []string) error {
+	if k.scopes == nil {
+		*k = defaultKeymap()
+	}
+	sk := k.scopes[scope]
+	for _, key := range keys {
+		if other, ok := sk.byKey[key]; ok && other != a {
+			return fmt.Errorf("key %q is already bound to %s", displayKey(key), other)
+		}
+	}
+	for _, key := range sk.byAction[a] {
+		delete(sk.byKey, key)
+	}
+	sk.byAction[a] = keys
+	for _, key := range keys {
+		sk.byKey[key] = a
+	}
+	return nil
+}
+
+// release removes key from the action of scope it is bound to
+func (k *keymap) release(scope keyScope, key string) {
+	if k.scopes == nil {
+		*k = defaultKeymap()
+	}
+	sk := k.scopes[scope]
+	a, ok := sk.byKey[key]
+	if !ok {
+		return
+	}
+	delete(sk.byKey, key)
+	var keys []string
+	for _, other := range sk.byAction[a] {
+		if other != key {
+			keys = append(keys, other)
+		}
+	}
+	sk.byAction[a] = keys
+}
+
+// isAction reports whether name is a known action of scope
+func isAction(scope keyScope, name string) bool {
+	for _, binding := range bindingsOf(scope) {
+		if string(binding.action) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// namedKeys are the keys besides single characters, without modifiers
+var namedKeys = map[string]bool{
+	"up": true, "down": true, "left": true, "right": true, "home": true, "end": true,
+	"pgup": true, "pgdown": true, "enter": true, "tab": true, "backspace": true,
+	"delete": true, "insert": true, "esc": true,
+}
+
+// keyAliases are accepted spellings of named keys
+var keyAliases = map[string]string{
+	"space": " ", "escape": "esc", "del": "delete", "ins": "insert",
+	"pageup": "pgup", "pagedown": "pgdown", "pgdn": "pgdown", "return": "enter",
+}
+
+// parseKey converts a key name from the configuration to the form reported
+// by tea.KeyMsg.String(), e.g. "Ctrl+F" to "ctrl+f" and "space" to " "
+func parseKey(name string) (string, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		return name, nil
+	}
+	var mods []string
+	rest := strings.ToLower(name)
+	for {
+		mod, after, ok := strings.Cut(rest, "+")
+		if !ok || after == "" || !(mod == "ctrl" || mod == "alt" || mod == "shift") {
+			break
+		}
+		mods = append(mods, mod)
+		rest = after
+		// A character after Alt keeps its case, e.g. alt+D, bubbletea
+		// reports control characters in lower case
+		if utf8.RuneCountInString(rest) == 1 {
+			if mod != "ctrl" {
+				rest = name[len(name)-len(rest):]
+			}
+			break
+		}
+	}
+	if alias, ok := keyAliases[rest]; ok {
+		rest = alias
+	}
+	if !namedKeys[rest] && !isFunctionKey(rest) && utf8.RuneCountInString(rest) != 1 {
+		return "", fmt.Errorf("unknown key %q", name)
+	}
+	if rest == " " && len(mods) == 0 {
+		return " ", nil
+	}
+	if rest == " " {
+		rest = "space"
+	}
+	return strings.Join(append(mods, rest), "+"), nil
+}
+
+// isFunctionKey reports whether name is f1 to f20
+func isFunctionKey(name string) bool {
+	var n int
+	_, err := fmt.Sscanf(name, "f%d", &n)
+	return err == nil && n >= 1 && n <= 20 && name == fmt.Sprintf("f%d", n)
+}
+
+// displayKey shows a key for help texts, e.g. "F5" or "Space"
+func displayKey(key string) string {
+	switch {
+	case key == " ":
+		return "Space"
+	case isFunctionKey(key):
+		return strings.ToUpper(key)
+	case utf8.RuneCountInString(key) == 1:
+		return key
+	}
+	parts := strings.Split(key, "+")
+	for i, part := range parts {
+		switch {
+		case part == "pgup":
+			parts[i] = "PgUp"
+		case part == "pgdown":
+			parts[i] = "PgDn"
+		case utf8.RuneCountInString(part) > 1:
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		case i > 0 && parts[i-1] == "Ctrl":
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
+// helpEntries are the actions listed in the help line with their labels
+var helpEntries = []struct {
+	action action
+	label  string
+}{
+	{actionSwitchPanel, "Switch"},
+	{actionCopy, "Copy"},
+	{actionMove, "Move"},
+	{actionRename, "Rename"},
+	{actionMkdir, "Mkdir"},
+	{actionTrash, "Trash"},
+	{actionDelete, "Delete"},
+	{actionTrashView, "Show trash"},
+	{actionJobs, "Jobs"},
+	{actionMark, "Mark"},
+	{actionView, "View"},
+	{actionEdit, "Edit"},
+	{actionSearch, "Search"},
+	{actionFilter, "Filter"},
+	{actionHistoryBack, "Back"},
+	{actionBookmarks, "Bookmarks"},
+	{actionHidden, "Hidden"},
+	{actionCommandLine, "Command"},
+	{actionQuit, "Quit"},
+}
+
+// helpLine lists the main actions with their first key
+func (k keymap) helpLine() string {
+	var parts []string
+	for _, entry := range helpEntries {
+		if keys := k.keysFor(entry.action); len(keys) > 0 {
+			parts = append(parts, displayKey(keys[0])+": "+entry.label)
+		}
+	}
+	return " " + strings.Join(parts, " | ")
+}
+
+// keyHint is a group of actions listed in a help text with a label
+type keyHint struct {
+	actions []action
+	label   string
+}
+
+// hints lists the first key of each action of the hints in scope, e.g.
+// "Up/Down: Scroll | Esc: Close". Hints without keys are left out.
+func (k keymap) hints(scope keyScope, hints []keyHint) string {
+	var parts []string
+	for _, hint := range hints {
+		var keys []string
+		for _, a := range hint.actions {
+			if bound := k.keysIn(scope, a); len(bound) > 0 {
+				keys = append(keys, displayKey(bound[0]))
+			}
+		}
+		if len(keys) > 0 {
+			parts = append(parts, strings.Join(keys, "/")+": "+hint.label)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"q", "q", true},
+		{"D", "D", true},
+		{"F5", "f5", true},
+		{"f20", "f20", true},
+		{"F21", "", false},
+		{"F05", "", false},
+		{"Ctrl+F", "ctrl+f", true},
+		{"alt+D", "alt+D", true},
+		{"Alt+Left", "alt+left", true},
+		{"shift+up", "shift+up", true},
+		{"space", " ", true},
+		{"ctrl+space", "ctrl+space", true},
+		{"PageDown", "pgdown", true},
+		{"PgDn", "pgdown", true},
+		{"Escape", "esc", true},
+		{"hyper+x", "", false},
+		{"ctrl+", "", false},
+		{"foo", "", false},
+	}
+	for _, tt := range tests {
+		got, err := parseKey(tt.name)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseKey(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+// The names bubbletea reports must be the ones parseKey produces
+func TestParseKey_MatchesKeyMsg(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  tea.KeyMsg
+	}{
+		{"F5", tea.KeyMsg{Type: tea.KeyF5}},
+		{"F8", tea.KeyMsg{Type: tea.KeyF8}},
+		{"Ctrl+F", tea.KeyMsg{Type: tea.KeyCtrlF}},
+		{"Alt+Left", tea.KeyMsg{Type: tea.KeyLeft, Alt: true}},
+		{"Insert", tea.KeyMsg{Type: tea.KeyInsert}},
+		{"PgUp", tea.KeyMsg{Type: tea.KeyPgUp}},
+		{"space", tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}},
+		{"alt+x", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}, Alt: true}},
+	}
+	for _, tt := range tests {
+		key, err := parseKey(tt.name)
+		if err != nil || key != tt.msg.String() {
+			t.Errorf("parseKey(%q) = %q, %v, bubbletea reports %q", tt.name, key, err, tt.msg.String())
+		}
+	}
+}
+
+func TestKeymap_Bind(t *testing.T) {
+	var k keymap
+	if k.lookup("f5") != actionCopy || k.lookup("c") != actionCopy {
+		t.Fatal("Expected the zero keymap to use the default bindings")
+	}
+	if err := k.bind(scopePanel, actionCopy, []string{"f5", "ctrl+k"}); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	if k.lookup("c") != "" || k.lookup("ctrl+k") != actionCopy {
+		t.Error("Expected c to be unbound and ctrl+k to copy")
+	}
+	if err := k.bind(scopePanel, actionView, []string{"ctrl+k"}); err == nil {
+		t.Error("Expected a conflict for a key bound to another action")
+	}
+	if k.lookup("ctrl+k") != actionCopy || k.lookup("v") != actionView {
+		t.Error("A failed bind must not change the keymap")
+	}
+
+	// Freed keys can be bound again
+	if err := k.bind(scopePanel, actionView, []string{"c"}); err != nil {
+		t.Errorf("Failed to bind a freed key: %v", err)
+	}
+	if defaultKeys.lookup("c") != actionCopy {
+		t.Error("Binding must not change the default keymap")
+	}
+}
+
+func TestKeymap_Scopes(t *testing.T) {
+	var k keymap
+	if k.lookupIn(scopeJobs, "r") != actionRetryJob || k.lookup("r") != actionMove {
+		t.Fatal("Expected r to retry in the jobs panel and move in the file panels")
+	}
+
+	// Keys of one scope do not conflict with those of another
+	if err := k.bind(scopeViewer, actionClose, []string{"c"}); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	if k.lookupIn(scopeViewer, "c") != actionClose || k.lookupIn(scopeViewer, "esc") != "" || k.lookup("c") != actionCopy {
+		t.Error("Expected only the viewer's close key to change")
+	}
+	if err := k.bind(scopeViewer, actionClose, []string{"up"}); err == nil {
+		t.Error("Expected a conflict within the viewer")
+	}
+
+	want := "c: Close | Home/End: Top/Bottom"
+	if got := k.hints(scopeViewer, []keyHint{{[]action{actionClose}, "Close"}, {[]action{actionTop, actionBottom}, "Top/Bottom"}, {[]action{actionCopy}, "Copy"}}); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestDisplayKey(t *testing.T) {
+	tests := map[string]string{
+		"q":        "q",
+		" ":        "Space",
+		"f5":       "F5",
+		"ctrl+f":   "Ctrl+F",
+		"alt+left": "Alt+Left",
+		"insert":   "Insert",
+		"pgdown":   "PgDn",
+	}
+	for key, want := range tests {
+		if got := displayKey(key); got != want {
+			t.Errorf("displayKey(%q) = %q, expected %q", key, got, want)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,14 +28,30 @@ var (
 	selectedStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#000000")).
 			Background(lipgloss.Color("#00AAAA"))
+
+	markedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFFF00")).
+			Bold(true)
+
+	brokenLinkStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5555"))
 )
 
 type panel struct {
 	path           string
 	entries        []fs.FileEntry
 	cursor         int
-	viewportOffset int  // For scrollbar
-	showHidden     bool // Show hidden files
+	viewportOffset int             // For scrollbar
+	showHidden     bool            // Show hidden files
+	search         *search         // Search results shown instead of the directory
+	trash          *trashView      // Trash listing shown instead of the directory
+	selectName     string          // Entry to put the cursor on after the next read
+	selected       map[string]bool // Marked entries by name
+	listing        listingMode     // Columns shown next to the names
+	customColumns  []string        // Columns of listingCustom, nil for the default
+	sort           fs.SortOptions  // Order of the directory listing
+	filter         *quickFilter    // Quick filter narrowing the entries, nil when off
+	history        dirHistory      // Visited directories for going back and forward
 }
 
 type model struct {
@@ -41,13 +60,29 @@ type model struct {
 	width          int
 	height         int
 	err            error
-	statusMsg      string // For status messages
-	viewportOffset int    // For scrolling in panels
+	statusMsg      string           // For status messages
+	viewportOffset int              // For scrolling in panels
+	viewer         *viewer          // Full-screen file viewer, nil when closed
+	prompt         *prompt          // Text input below the panels, nil when closed
+	dialog         *dialog          // Modal dialog over the panels, nil when closed
+	popup          *popup           // List to choose from over the panels, nil when closed
+	bookmarks      []bookmark       // Hotlist of directories
+	jobs           jobList          // Queued and finished file operations
+	symlinks       fs.SymlinkPolicy // How new copies treat symbolic links
+	searchSeq      int              // Id of the most recently started search
+	watcher        *fs.Watcher      // Reports changes to the panel directories, nil in tests
+	keys           keymap           // Key bindings of the panel commands
+	commands       commandLine      // Shell command line and its history
+	outputShown    bool             // Command output shown instead of the panels (Ctrl+O)
+	openers        []opener         // Programs for opening files by name or type
+	editor         string           // Configured editor, "" for the environment's
+	rename         *batchRename     // Batch rename dialog, nil when closed
+	lastRename     *renameBatch     // Most recent rename, for undo
 }
 
 func (m model) Init() tea.Cmd {
 	// Initialize both panels
-	return tea.Batch(m.readDirCmd(0), m.readDirCmd(1))
+	return tea.Batch(m.readDirCmd(0), m.readDirCmd(1), waitForDirChangeCmd(m.watcher))
 }
 
 func initialModel() model {
@@ -63,14 +98,44 @@ func initialModel() model {
 
 type readDirMsg struct {
 	index   int
+	path    string
 	entries []fs.FileEntry
 	err     error
+	refresh bool // Reload of the same directory, the cursor stays on its entry
+}
+
+// fileOpVerbs describes completed operations in status messages
+var fileOpVerbs = map[string]string{
+	"copy":    "Copied",
+	"move":    "Moved",
+	"delete":  "Deleted",
+	"trash":   "Moved to trash",
+	"restore": "Restored",
+	"purge":   "Deleted",
+}
+
+// isVirtual reports whether the panel shows something other than its directory
+func (p *panel) isVirtual() bool {
+	return p.search != nil || p.trash != nil
+}
+
+// refreshCmd reloads whatever the panel at index is showing, keeping the
+// cursor on the same entry
+func (m model) refreshCmd(index int) tea.Cmd {
+	if m.panels[index].trash != nil {
+		return listTrashCmd(index)
+	}
+	return readDirCmd(index, m.panels[index].path, m.panels[index].sort, true)
 }
 
 func (m model) readDirCmd(index int) tea.Cmd {
+	return readDirCmd(index, m.panels[index].path, m.panels[index].sort, false)
+}
+
+func readDirCmd(index int, path string, sort fs.SortOptions, refresh bool) tea.Cmd {
 	return func() tea.Msg {
-		entries, err := fs.ReadDir(m.panels[index].path)
-		return readDirMsg{index: index, entries: entries, err: err}
+		entries, err := fs.ReadDirSorted(path, sort)
+		return readDirMsg{index: index, path: path, entries: entries, err: err, refresh: refresh}
 	}
 }
 
@@ -83,152 +148,292 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		h := msg.Height - 6
 		panelStyle = panelStyle.Width(w).Height(h)
 		activePanelStyle = activePanelStyle.Width(w).Height(h)
+		if m.viewer != nil {
+			return m, m.viewer.resize(m.viewerHeight())
+		}
 
 	case readDirMsg:
+		if m.panels[msg.index].isVirtual() {
+			// Search results and the trash are not a directory listing
+			return m, nil
+		}
+		p := &m.panels[msg.index]
+		if msg.path != p.path {
+			// The panel has changed directory since
+			return m, nil
+		}
+		if msg.err != nil && msg.refresh {
+			return m, m.handleRefreshError(msg)
+		}
 		if msg.err != nil {
 			m.err = msg.err
 		} else {
-			m.panels[msg.index].entries = msg.entries
-			// Limit cursor to valid value
-			if m.panels[msg.index].cursor >= len(m.panels[msg.index].entries) {
-				m.panels[msg.index].cursor = max(0, len(m.panels[msg.index].entries)-1)
-			}
+			p.setEntries(msg.entries, msg.refresh)
+			p.history.visit(p.path)
+			m.watchPanels()
 		}
 
-	case fileOpResultMsg:
+	case dirChangedMsg:
+		return m, m.handleDirChanged(msg)
+
+	case commandDoneMsg:
+		return m, m.handleCommandDone(msg)
+
+	case editDoneMsg:
+		return m, m.handleEditDone(msg)
+
+	case searchResultsMsg:
+		return m, m.handleSearchResults(msg)
+
+	case trashListMsg:
+		m.handleTrashList(msg)
+
+	case viewerOpenedMsg:
 		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Cannot view file: %v", msg.err)
+			return m, nil
+		}
+		m.viewer = msg.viewer
+		m.viewer.keys = m.keys
+		return m, m.viewer.resize(m.viewerHeight())
+
+	case viewerChunkMsg:
+		if m.viewer != nil {
+			m.viewer.setChunk(msg)
+		}
+		return m, nil
+
+	case jobProgressMsg:
+		return m, m.handleJobProgress(msg)
+
+	case fileOpResultMsg:
+		if errors.Is(msg.err, context.Canceled) {
+			m.statusMsg = fmt.Sprintf("Cancelled %s: %s", msg.op, msg.entryName)
+		} else if msg.err != nil {
 			m.statusMsg = fmt.Sprintf("Error during %s: %v", msg.op, msg.err)
 		} else {
-			m.statusMsg = fmt.Sprintf("%s successful: %s", map[string]string{"copy": "Copied", "move": "Moved", "delete": "Deleted"}[msg.op], msg.entryName)
-			// Refresh both panels
-			return m, tea.Batch(m.readDirCmd(0), m.readDirCmd(1))
+			m.statusMsg = fmt.Sprintf("%s successful: %s", fileOpVerbs[msg.op], msg.entryName)
 		}
-		return m, nil
+		// Refresh both panels, a batch may have partially succeeded
+		return m, tea.Batch(m.finishJob(msg), m.refreshCmd(0), m.refreshCmd(1))
 
 	case tea.KeyMsg:
-		p := &m.panels[m.activePanel]
-		switch msg.String() {
-		case "ctrl+c", "q":
-			return m, tea.Quit
-		case "tab":
-			m.activePanel = (m.activePanel + 1) % 2
-			m.statusMsg = ""
-		case "up":
-			if p.cursor > 0 {
-				p.cursor--
-				// Skip hidden entries when scrolling up
-				for p.cursor > 0 && !p.showHidden && strings.HasPrefix(p.entries[p.cursor].Name, ".") {
-					p.cursor--
-				}
+		if m.dialog != nil {
+			if choice := m.dialog.update(msg); choice >= 0 {
+				d := m.dialog
+				m.dialog = nil
+				return m, d.onChoose(&m, choice)
 			}
-		case "down":
-			if p.cursor < len(p.entries)-1 {
-				p.cursor++
-				// Skip hidden entries when scrolling down
-				for p.cursor < len(p.entries) && !p.showHidden && strings.HasPrefix(p.entries[p.cursor].Name, ".") {
-					p.cursor++
-				}
+			return m, nil
+		}
+
+		if m.prompt != nil {
+			submitted, cancelled := m.prompt.update(msg)
+			if cancelled {
+				m.prompt = nil
+			} else if submitted {
+				pr := m.prompt
+				m.prompt = nil
+				return m, pr.onSubmit(&m, pr.text())
+			}
+			return m, nil
+		}
+
+		if m.commands.input != nil {
+			return m, m.updateCommandLine(msg)
+		}
+
+		if m.popup != nil {
+			return m, m.updatePopup(msg)
+		}
+
+		if m.rename != nil {
+			return m, m.updateBatchRename(msg)
+		}
+
+		if m.viewer != nil {
+			if m.keys.lookupIn(scopeViewer, msg.String()) == actionQuit {
+				m.viewer = nil
+				return m, m.quit()
+			}
+			open, cmd := m.viewer.update(msg)
+			if !open {
+				m.viewer = nil
+			}
+			return m, cmd
+		}
+
+		if m.jobs.open {
+			return m, m.updateJobs(msg)
+		}
+
+		p := &m.panels[m.activePanel]
+		if p.filter != nil && p.filter.editing {
+			if handled, cmd := m.updateFilter(msg); handled {
+				return m, cmd
 			}
-		case "pgup":
-			p.cursor = max(0, p.cursor-10)
-			// Skip hidden entries
-			for p.cursor > 0 && !p.showHidden && strings.HasPrefix(p.entries[p.cursor].Name, ".") {
-				p.cursor--
+		}
+		if p.trash != nil {
+			if handled, cmd := m.updateTrash(msg); handled {
+				return m, cmd
 			}
-		case "pgdown":
-			p.cursor = min(len(p.entries)-1, p.cursor+10)
-			// Skip hidden entries
-			for p.cursor < len(p.entries)-1 && !p.showHidden && strings.HasPrefix(p.entries[p.cursor].Name, ".") {
-				p.cursor++
+		}
+		a := m.keys.lookup(msg.String())
+		if digit, ok := strings.CutPrefix(string(a), actionBookmarkPrefix); ok {
+			return m, m.jumpToBookmark(digit)
+		}
+		switch a {
+		case actionQuit:
+			return m, m.quit()
+		case actionSwitchPanel:
+			m.activePanel = (m.activePanel + 1) % 2
+			m.statusMsg = ""
+		// Navigation skips hidden and filtered-out entries
+		case actionUp:
+			p.moveCursor(-1)
+		case actionDown:
+			p.moveCursor(1)
+		case actionPageUp:
+			p.moveCursor(-10)
+		case actionPageDown:
+			p.moveCursor(10)
+		case actionOpen:
+			if p.search != nil {
+				return m, m.jumpToSearchHit()
 			}
-		case "enter":
-			if len(p.entries) > 0 {
+			if len(p.entries) > 0 && p.isVisible(p.entries[p.cursor]) {
 				entry := p.entries[p.cursor]
 				if entry.IsDir {
-					p.path = filepath.Join(p.path, entry.Name)
-					p.cursor = 0
-					return m, m.readDirCmd(m.activePanel)
+					return m, m.changeDir(m.activePanel, filepath.Join(p.path, entry.Name), "")
 				}
+				return m, m.openFile()
+			}
+		case actionParent:
+			if p.search != nil {
+				p.closeSearch()
+				return m, m.changeDir(m.activePanel, p.path, p.selectName)
+			}
+			// Put the cursor on the directory being left
+			return m, m.changeDir(m.activePanel, filepath.Dir(p.path), filepath.Base(p.path))
+
+		case actionSearch:
+			m.openSearchPrompt()
+		case actionFilter:
+			m.openFilter()
+		case actionHistoryBack:
+			return m, m.stepHistory(-1)
+		case actionHistoryFwd:
+			return m, m.stepHistory(1)
+		case actionHistory:
+			m.openHistory()
+		case actionBookmarks:
+			m.openBookmarks()
+		case actionAddBookmark:
+			m.addBookmarkPrompt(nil)
+
+			// Selection
+		case actionMark:
+			p.toggleSelection()
+		case actionMarkPattern:
+			m.openSelectPrompt(true)
+		case actionUnmarkPattern:
+			m.openSelectPrompt(false)
+		case actionInvertMarks:
+			p.invertSelection()
+		case actionCancel:
+			// The transfer in the progress bar comes first, as the hint says
+			if j := m.jobs.progressJob(); j != nil {
+				m.jobs.cancelJob(j)
+				m.statusMsg = "Cancelling…"
+			} else if p.filter != nil {
+				p.clearFilter()
+			} else if p.search != nil && p.search.running {
+				p.cancelSearch()
+				m.statusMsg = "Search cancelled"
+			} else if p.search != nil {
+				p.closeSearch()
+				p.cursor = 0
+				return m, m.readDirCmd(m.activePanel)
 			}
-		case "backspace":
-			p.path = filepath.Dir(p.path)
-			p.cursor = 0
-			return m, m.readDirCmd(m.activePanel)
 
-			// File operations (temporary keys to avoid VSCode conflicts)
-		case "c": // Copy (instead of F5)
+		case actionView:
+			return m, m.viewFile()
+		case actionEdit:
+			return m, m.editFile()
+		case actionEditNew:
+			m.openEditNewPrompt()
+		case actionMkdir:
+			m.openCreatePrompt(true)
+		case actionNewFile:
+			m.openCreatePrompt(false)
+		case actionRename:
+			m.openRenamePrompt()
+		case actionBatchRename:
+			m.openBatchRename()
+		case actionUndoRename:
+			return m, m.undoRename()
+
+			// File operations
+		case actionCopy:
 			return m, m.handleFileOperation("copy")
-		case "r": // Move (instead of F6)
+		case actionMove:
 			return m, m.handleFileOperation("move")
-		case "d": // Delete (instead of F8)
+		case actionTrash:
+			return m, m.handleFileOperation("trash")
+		case actionDelete:
 			return m, m.handleFileOperation("delete")
+		case actionTrashView:
+			return m, m.openTrash()
+		case actionJobs:
+			m.openJobs()
+		case actionSortNext:
+			return m, m.changeSort(func(opts *fs.SortOptions) { opts.Mode = opts.Mode.Next() })
+		case actionSortReverse:
+			return m, m.changeSort(func(opts *fs.SortOptions) { opts.Descending = !opts.Descending })
+		case actionSortDirs:
+			return m, m.changeSort(func(opts *fs.SortOptions) { opts.MixDirs = !opts.MixDirs })
+		case actionListing:
+			m.cycleListing()
+		case actionColumns:
+			m.openColumnsPrompt()
+		case actionSymlinks:
+			m.symlinks = (m.symlinks + 1) % 3
+			m.statusMsg = fmt.Sprintf("Symlinks when copying: %s", m.symlinks)
+
+			// Command line
+		case actionCommandLine:
+			m.openCommandLine()
+		case actionOutput:
+			return m, m.toggleOutput()
 
 			// Toggle hidden files
-		case "h":
+		case actionHidden:
 			p.showHidden = !p.showHidden
+			p.ensureCursorVisible()
 			m.statusMsg = fmt.Sprintf("Hidden files: %s", map[bool]string{true: "ON", false: "OFF"}[p.showHidden])
 		}
 	}
 	return m, nil
 }
 
-// handleFileOperation handles file operations (copy, move, delete)
-func (m *model) handleFileOperation(op string) tea.Cmd {
+// viewFile opens the entry under the cursor in the built-in viewer
+func (m *model) viewFile() tea.Cmd {
 	p := &m.panels[m.activePanel]
-	inactivePanel := &m.panels[(m.activePanel+1)%2]
-
-	if len(p.entries) == 0 {
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) || p.entries[p.cursor].IsDir {
 		m.statusMsg = "No file selected"
 		return nil
 	}
-
 	entry := p.entries[p.cursor]
-	srcPath := filepath.Join(p.path, entry.Name)
-	dstPath := filepath.Join(inactivePanel.path, entry.Name)
-
-	switch op {
-	case "copy":
-		m.statusMsg = fmt.Sprintf("Copying: %s -> %s", entry.Name, inactivePanel.path)
-	case "move":
-		m.statusMsg = fmt.Sprintf("Moving: %s -> %s", entry.Name, inactivePanel.path)
-	case "delete":
-		m.statusMsg = fmt.Sprintf("Deleting: %s", entry.Name)
-	}
-
-	// Execute operation asynchronously
-	return func() tea.Msg {
-		var err error
-		switch op {
-		case "copy":
-			if entry.IsDir {
-				err = fs.CopyDir(srcPath, dstPath)
-			} else {
-				err = fs.Copy(srcPath, dstPath)
-			}
-		case "move":
-			err = fs.Move(srcPath, dstPath)
-		case "delete":
-			if entry.IsDir {
-				err = fs.DeleteDir(srcPath)
-			} else {
-				err = fs.Delete(srcPath)
-			}
-		}
-
-		if err != nil {
-			return fileOpResultMsg{op: op, entryName: entry.Name, inactivePanelPath: inactivePanel.path, err: err}
-		}
-
-		return fileOpResultMsg{op: op, entryName: entry.Name, inactivePanelPath: inactivePanel.path, err: nil}
-	}
+	return openViewerCmd(filepath.Join(p.path, entry.Name), entry.Size)
 }
 
-// fileOpResultMsg is sent when a file operation is completed
-type fileOpResultMsg struct {
-	op                string
-	entryName         string
-	inactivePanelPath string
-	err               error
+// viewerHeight returns the number of text rows available to the viewer
+func (m model) viewerHeight() int {
+	if m.height <= 0 {
+		return 20
+	}
+	// Header and help line
+	return m.height - 2
 }
 
 func (m model) renderPanel(index int) string {
@@ -238,13 +443,19 @@ func (m model) renderPanel(index int) string {
 		style = activePanelStyle
 	}
 
-	// Filter visible entries
+	// Filter visible entries and map the cursor to them
 	var visibleEntries []fs.FileEntry
-	for _, entry := range p.entries {
-		if !p.showHidden && strings.HasPrefix(entry.Name, ".") {
+	var visibleMarks []bool
+	visibleCursor := 0
+	for i, entry := range p.entries {
+		if !p.isVisible(entry) {
 			continue
 		}
+		if i <= p.cursor {
+			visibleCursor = len(visibleEntries)
+		}
 		visibleEntries = append(visibleEntries, entry)
+		visibleMarks = append(visibleMarks, p.isSelected(p.entryKey(i)))
 	}
 
 	// Viewport management
@@ -252,28 +463,27 @@ func (m model) renderPanel(index int) string {
 	if style.GetHeight() > 0 {
 		viewportHeight = style.GetHeight()
 	}
+	summary := p.selectionSummary()
+	if summary != "" {
+		// Reserve a line for the selection footer
+		viewportHeight--
+	}
+	if p.filter != nil {
+		// Reserve a line for the filter footer
+		viewportHeight--
+	}
+	// Without padding, the text area is two cells narrower than the panel
+	contentWidth := 38
+	if style.GetWidth() > 0 {
+		contentWidth = style.GetWidth() - 2
+	}
+	cols, nameWidth := layoutColumns(p.columnNames(), contentWidth)
+	if len(cols) > 0 {
+		// Reserve a line for the column titles
+		viewportHeight--
+	}
 
-	// Map cursor index to visible entries
-	visibleCursor := 0
-	if len(visibleEntries) > 0 {
-		if p.cursor < len(p.entries) {
-			// Find the visible position of the cursor
-			hiddenCount := 0
-			for i := 0; i <= p.cursor && i < len(p.entries); i++ {
-				if !p.showHidden && strings.HasPrefix(p.entries[i].Name, ".") {
-					hiddenCount++
-				}
-			}
-			visibleCursor = p.cursor - hiddenCount
-			if visibleCursor < 0 {
-				visibleCursor = 0
-			}
-			if visibleCursor >= len(visibleEntries) {
-				visibleCursor = len(visibleEntries) - 1
-			}
-		}
-	} else {
-		visibleCursor = 0
+	if len(visibleEntries) == 0 {
 		p.viewportOffset = 0
 	}
 
@@ -297,28 +507,47 @@ func (m model) renderPanel(index int) string {
 	}
 
 	var s strings.Builder
-	s.WriteString(fmt.Sprintf(" Path: %s", p.path))
+	if p.trash != nil {
+		s.WriteString(fmt.Sprintf(" Trash (%d items)", len(p.entries)))
+	} else if p.search != nil {
+		s.WriteString(fmt.Sprintf(" Search: %s in %s (%d found", p.search.pattern, p.search.root, len(visibleEntries)))
+		if p.search.running {
+			s.WriteString(", searching…")
+		}
+		s.WriteString(")")
+	} else {
+		s.WriteString(fmt.Sprintf(" Path: %s", p.path))
+	}
 	if p.showHidden {
 		s.WriteString(" (.*)")
 	}
 	s.WriteString("\n")
+	if len(cols) > 0 {
+		s.WriteString(renderColumnHeader(cols, nameWidth) + "\n")
+	}
 
 	// Display files in viewport
 	for i := p.viewportOffset; i < len(visibleEntries) && i < p.viewportOffset+viewportHeight; i++ {
 		entry := visibleEntries[i]
-		var prefix string
-		if entry.IsDir {
-			prefix = "📁 "
-		} else {
-			prefix = "📄 "
-		}
-
-		line := fmt.Sprintf("%s%s", prefix, entry.Name)
-		if i == visibleCursor && m.activePanel == index {
-			s.WriteString(selectedStyle.Render(line) + "\n")
-		} else {
-			s.WriteString(line + "\n")
+		marked := visibleMarks[i]
+		lineStyle := lipgloss.NewStyle()
+		switch {
+		case i == visibleCursor && m.activePanel == index && marked:
+			lineStyle = selectedStyle.Foreground(markedStyle.GetForeground())
+		case i == visibleCursor && m.activePanel == index:
+			lineStyle = selectedStyle
+		case marked:
+			lineStyle = markedStyle
+		case entry.Link == fs.BrokenLink:
+			lineStyle = brokenLinkStyle
+		case entry.Link != fs.NoLink:
+			lineStyle = symlinkStyle
+		case entry.IsDir:
+			lineStyle = directoryStyle
+		case entry.Mode.IsRegular() && entry.Mode&0111 != 0:
+			lineStyle = executableStyle
 		}
+		s.WriteString(renderEntryLine(entry, marked, cols, nameWidth, p.matchPositions(entry), lineStyle) + "\n")
 	}
 
 	// Render scrollbar
@@ -328,6 +557,13 @@ func (m model) renderPanel(index int) string {
 		s.WriteString(scrollBar)
 	}
 
+	if p.filter != nil {
+		s.WriteString("\n" + p.filterFooter())
+	}
+	if summary != "" {
+		s.WriteString("\n" + markedStyle.Render(summary))
+	}
+
 	return style.Render(s.String())
 }
 
@@ -357,24 +593,118 @@ func (m model) View() string {
 	if m.err != nil {
 		return fmt.Sprintf("Error: %v\nPress q to quit", m.err)
 	}
+	if m.viewer != nil {
+		return m.viewer.view(m.width)
+	}
+	if m.outputShown {
+		// Below the command output on the terminal screen
+		view := m.commands.input.view()
+		if m.statusMsg != "" {
+			view = statusStyle.Render(m.statusMsg) + "\n" + view
+		}
+		if m.dialog != nil {
+			view = m.dialog.view() + "\n" + view
+		}
+		return view
+	}
 
-	panels := lipgloss.JoinHorizontal(lipgloss.Top, m.renderPanel(0), m.renderPanel(1))
+	var panels string
+	if m.jobs.open {
+		panels = m.renderJobs()
+	} else {
+		panels = lipgloss.JoinHorizontal(lipgloss.Top, m.renderPanel(0), m.renderPanel(1))
+	}
 
-	// Display status message
+	// Display status message, the open prompt or command line, or the job progress
 	status := ""
-	if m.statusMsg != "" {
-		status = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).Render(m.statusMsg)
+	if m.prompt != nil {
+		status = m.prompt.view()
+	} else if m.commands.input != nil {
+		status = m.commands.input.view()
+	} else if progress := m.jobStatusLine(); progress != "" {
+		status = progress
+	} else if m.statusMsg != "" {
+		status = statusStyle.Render(m.statusMsg)
+	}
+
+	if m.popup != nil {
+		panels = overlay(panels, m.popup.view())
+	}
+	if m.rename != nil {
+		panels = overlay(panels, m.rename.view())
+	}
+	if m.dialog != nil {
+		panels = overlay(panels, m.dialog.view())
 	}
 
-	help := "\n Tab: Switch | ↑/↓: Navigate | PgUp/PgDn: Scroll | c: Copy | r: Move | d: Delete | h: Hidden | q: Quit"
+	help := "\n" + m.keys.helpLine()
+	if m.jobs.open {
+		help = "\n " + m.keys.hints(scopeJobs, jobHints)
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, " Min Commander ", panels, status, help)
 }
 
 func main() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error: %v", err)
-		os.Exit(1)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run starts Min Commander with the command-line arguments and returns the
+// exit code. It returns instead of exiting so that deferred cleanup runs.
+func run(args []string) int {
+	opts, err := parseArgs(args, os.Stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "min-commander: %v\n", err)
+		}
+		return exitCode(err)
+	}
+	if opts.version {
+		fmt.Printf("min-commander %s\n", version())
+		return 0
+	}
+	if opts.initShell != "" {
+		script, err := shellInit(opts.initShell)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "min-commander: %v\n", err)
+			return exitCode(err)
+		}
+		fmt.Print(script)
+		return 0
+	}
+
+	m := initialModel()
+	cfg, err := opts.loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	m.applyConfig(cfg)
+	opts.apply(&m)
+	if err := m.loadHistory(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Cannot load history: %v\n", err)
+	}
+	if err := m.loadBookmarks(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Cannot load bookmarks: %v\n", err)
+	}
+	m.watcher = fs.NewWatcher(fs.DefaultDebounce)
+	defer m.watcher.Close()
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if m, ok := final.(model); ok {
+		if err := m.saveHistory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot save history: %v\n", err)
+		}
+		if opts.lastDirFile != "" {
+			if err := m.writeLastDir(opts.lastDirFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot write last directory: %v\n", err)
+				return 1
+			}
+		}
 	}
+	return 0
 }
@@ -367,6 +367,28 @@ func TestRenderPanelWithEntries(t *testing.T) {
 	}
 }
 
+func TestRenderPanelWithLinks(t *testing.T) {
+	m := model{
+		panels: [2]panel{
+			{
+				path: "/test",
+				entries: []fs.FileEntry{
+					{Name: "docs", IsDir: true, Link: fs.DirLink, LinkTarget: "/usr/share/doc"},
+					{Name: "stale", Link: fs.BrokenLink, LinkTarget: "gone.txt"},
+				},
+			},
+		},
+	}
+
+	panelStr := m.renderPanel(0)
+	if !strings.Contains(panelStr, "docs -> /usr/share/doc") {
+		t.Error("Expected link target to be shown")
+	}
+	if !strings.Contains(panelStr, "stale -> gone.txt (broken)") {
+		t.Error("Expected broken link to be flagged")
+	}
+}
+
 func TestRenderPanelEmpty(t *testing.T) {
 	m := model{
 		width:  800,
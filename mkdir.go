@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// openCreatePrompt asks for the name of a new directory or, with dir false,
+// an empty file in the active panel's directory
+func (m *model) openCreatePrompt(dir bool) {
+	p := &m.panels[m.activePanel]
+	if p.isVirtual() {
+		m.statusMsg = "Not available here"
+		return
+	}
+	label := "New file:"
+	if dir {
+		label = "Make directory:"
+	}
+	index := m.activePanel
+	m.prompt = newPrompt(label, "", func(m *model, name string) tea.Cmd {
+		return m.createEntry(index, name, dir)
+	})
+}
+
+// createEntry creates name, relative to the directory of the panel at index,
+// and puts the cursor on it once the panel is read again. Directories are
+// created with their missing parents, so "a/b/c" selects "a".
+func (m *model) createEntry(index int, name string, dir bool) tea.Cmd {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	p := &m.panels[index]
+	path := filepath.Join(p.path, name)
+	create, kind := fs.CreateFile, "file"
+	if dir {
+		create, kind = fs.Mkdir, "directory"
+	}
+	if err := create(path); err != nil {
+		m.statusMsg = fmt.Sprintf("Cannot create %s %s: %v", kind, name, errorReason(err))
+		return nil
+	}
+
+	m.statusMsg = fmt.Sprintf("Created %s %s", kind, name)
+	// Names such as "../x" leave the panel's directory, nothing to select
+	rel, err := filepath.Rel(p.path, path)
+	first, _, _ := strings.Cut(rel, string(filepath.Separator))
+	if err == nil && first != ".." {
+		if p.isVisible(fs.FileEntry{Name: first}) {
+			p.selectName = first
+		} else {
+			m.statusMsg += ", hidden in this panel"
+		}
+	}
+	return tea.Batch(m.refreshCmd(0), m.refreshCmd(1))
+}
+
+// errorReason strips the operation and path from err, as the status line
+// names the entry already
+func errorReason(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
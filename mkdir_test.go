@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreatePrompt(t *testing.T) {
+	m, _ := fileOpsTestModel(t)
+	dir := m.panels[0].path
+	for _, name := range []string{"b", "d"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	m = readPanel(m, 0)
+
+	tests := []struct {
+		key    string
+		name   string
+		path   string // Created entry, relative to dir
+		isDir  bool
+		cursor string
+	}{
+		{"f7", "c", "c", true, "c"},
+		{"m", "a/x/y", "a/x/y", true, "a"},
+		{"n", "e.txt", "e.txt", false, "e.txt"},
+		{"n", "d/inner.txt", "d/inner.txt", false, "d"},
+		{"m", ".cache", ".cache", true, "d"}, // Hidden, the cursor stays
+	}
+	for _, tt := range tests {
+		m = pressKeys(t, m, tt.key)
+		m = pressKeys(t, typeText(t, m, tt.name), "enter")
+		info, err := os.Stat(filepath.Join(dir, tt.path))
+		if err != nil || info.IsDir() != tt.isDir {
+			t.Errorf("%s: expected it to be created (dir %v): %v", tt.name, tt.isDir, err)
+			continue
+		}
+		p := m.panels[0]
+		if got := p.entries[p.cursor].Name; got != tt.cursor {
+			t.Errorf("%s: expected the cursor on %s, got %s", tt.name, tt.cursor, got)
+		}
+	}
+	if !strings.Contains(m.statusMsg, "hidden") {
+		t.Errorf("Expected a note that the entry is hidden, got %q", m.statusMsg)
+	}
+}
+
+func TestCreatePrompt_Errors(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a.txt")
+	locked := filepath.Join(m.panels[0].path, "locked")
+	if err := os.Mkdir(locked, 0555); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	m = readPanel(m, 0)
+
+	tests := []struct {
+		key  string
+		name string
+		want string
+	}{
+		{"m", "a.txt", "Cannot create directory a.txt: file exists"},
+		{"n", "a.txt", "Cannot create file a.txt: file exists"},
+		{"n", "missing/b.txt", "Cannot create file missing/b.txt: no such file or directory"},
+	}
+	if os.Geteuid() != 0 {
+		// root may write anywhere
+		tests = append(tests, struct{ key, name, want string }{
+			"m", "locked/sub", "Cannot create directory locked/sub: permission denied",
+		})
+	}
+	for _, tt := range tests {
+		m = pressKeys(t, m, tt.key)
+		m = pressKeys(t, typeText(t, m, tt.name), "enter")
+		if m.statusMsg != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, m.statusMsg)
+		}
+	}
+
+	// Search results are not a directory to create entries in
+	m.panels[0].search = &search{}
+	m, _ = pressKey(m, "m")
+	if m.prompt != nil || m.statusMsg != "Not available here" {
+		t.Errorf("Expected no prompt on search results, got %q", m.statusMsg)
+	}
+}
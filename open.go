@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// opener is a rule of the [open] section of the configuration
+type opener struct {
+	pattern string // Wildcard for the name, or for the MIME type if it contains a "/"
+	command string // Shell command, see expandOpener
+}
+
+// matches reports whether the rule applies to a file. mimeType is computed
+// only for MIME rules.
+func (o opener) matches(name string, mimeType func() string) bool {
+	if strings.Contains(o.pattern, "/") {
+		return fs.MatchWildcard(o.pattern, mimeType())
+	}
+	return fs.MatchWildcard(o.pattern, name)
+}
+
+// handler is how a file is opened
+type handler struct {
+	command    string // Shell command with the file name filled in
+	background bool   // A graphical program, started without suspending the TUI
+}
+
+// resolveHandler picks the handler for the file at path: the first matching
+// rule of the configuration, then the desktop's opener, then the editor. It
+// reports false if there is none.
+func (m *model) resolveHandler(path string) (handler, bool) {
+	var mimeType string
+	detect := func() string {
+		if mimeType == "" {
+			mimeType, _ = fs.MimeType(path)
+		}
+		return mimeType
+	}
+	for _, o := range m.openers {
+		if o.matches(filepath.Base(path), detect) {
+			return expandOpener(o.command, path), true
+		}
+	}
+	if opener := desktopOpener(); opener != "" {
+		return handler{command: opener + " " + shellQuote(path), background: true}, true
+	}
+	if editor := m.editorCommand(); editor != "" {
+		return handler{command: editor + " " + shellQuote(path)}, true
+	}
+	return handler{}, false
+}
+
+// expandOpener fills the quoted path into command: %f is replaced with it,
+// %% with a percent sign, and without %f it is appended. A trailing & marks a
+// graphical program.
+func expandOpener(command, path string) handler {
+	var h handler
+	command = strings.TrimSpace(command)
+	if rest, ok := strings.CutSuffix(command, "&"); ok {
+		command = strings.TrimSpace(rest)
+		h.background = true
+	}
+	quoted := shellQuote(path)
+	if !strings.Contains(command, "%f") {
+		command += " " + quoted
+	}
+	h.command = strings.NewReplacer("%f", quoted, "%%", "%").Replace(command)
+	return h
+}
+
+// desktopOpener returns the program that opens files with the default
+// application, or "" without a graphical session
+func desktopOpener() string {
+	name := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		name = "open"
+	} else if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		return ""
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// openFile opens the entry under the cursor with its handler
+func (m *model) openFile() tea.Cmd {
+	p := &m.panels[m.activePanel]
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) || p.entries[p.cursor].IsDir {
+		return nil
+	}
+	name := p.entries[p.cursor].Name
+	h, ok := m.resolveHandler(filepath.Join(p.path, name))
+	if !ok {
+		m.statusMsg = fmt.Sprintf("No program to open %s, add one to [open] in the configuration", name)
+		return nil
+	}
+	m.statusMsg = ""
+	if h.background {
+		m.statusMsg = fmt.Sprintf("Opening %s", name)
+		return startBackgroundCmd(p.path, h.command)
+	}
+	return runCommandCmd(p.path, "", h.command)
+}
+
+// startBackgroundCmd starts a graphical program detached from the terminal,
+// so that its output does not disturb the panels
+func startBackgroundCmd(dir, command string) tea.Cmd {
+	return func() tea.Msg {
+		cmd := newShellCommand(dir, "", command).Cmd
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err := cmd.Start(); err != nil {
+			return commandDoneMsg{err: err}
+		}
+		go cmd.Wait()
+		return nil
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExpandOpener(t *testing.T) {
+	tests := []struct {
+		command    string
+		want       string
+		background bool
+	}{
+		{"less", "less '/tmp/my file.txt'", false},
+		{"vim +1 %f", "vim +1 '/tmp/my file.txt'", false},
+		{"zathura %f &", "zathura '/tmp/my file.txt'", true},
+		{"feh&", "feh '/tmp/my file.txt'", true},
+		{"printf '100%%' %f", "printf '100%' '/tmp/my file.txt'", false},
+	}
+	for _, tt := range tests {
+		h := expandOpener(tt.command, "/tmp/my file.txt")
+		if h.command != tt.want || h.background != tt.background {
+			t.Errorf("expandOpener(%q) = %+v, expected %q, %v", tt.command, h, tt.want, tt.background)
+		}
+	}
+}
+
+// noDesktop hides the graphical session and the editor from the fallbacks
+func noDesktop(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	t.Setenv("PATH", t.TempDir())
+}
+
+func TestResolveHandler(t *testing.T) {
+	noDesktop(t)
+	dir := t.TempDir()
+	files := map[string]string{"photo.JPG": "", "notes": "plain text\n", "data.bin": "\x00\x01"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	cfg, err := parseConfig("config.toml", []byte(`[open]
+"*.jpg" = "feh &"
+"text/*" = "less"
+"*" = "hexdump -C %f | less"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	m := model{}
+	m.applyConfig(cfg)
+	defer applyTheme(themes["default"])
+
+	tests := []struct {
+		name string
+		want handler
+	}{
+		{"photo.JPG", handler{command: "feh " + filepath.Join(dir, "photo.JPG"), background: true}},
+		{"notes", handler{command: "less " + filepath.Join(dir, "notes")}},
+		{"data.bin", handler{command: "hexdump -C " + filepath.Join(dir, "data.bin") + " | less"}},
+	}
+	for _, tt := range tests {
+		h, ok := m.resolveHandler(filepath.Join(dir, tt.name))
+		if !ok || h != tt.want {
+			t.Errorf("resolveHandler(%s) = %+v, %v, expected %+v", tt.name, h, ok, tt.want)
+		}
+	}
+
+	// Without rules the editor is the last resort
+	m.openers = nil
+	if _, ok := m.resolveHandler(filepath.Join(dir, "notes")); ok {
+		t.Error("Expected no handler without rules, desktop and editor")
+	}
+	t.Setenv("EDITOR", "nano")
+	if h, ok := m.resolveHandler(filepath.Join(dir, "notes")); !ok || h.command != "nano "+filepath.Join(dir, "notes") || h.background {
+		t.Errorf("Expected the editor, got %+v", h)
+	}
+	t.Setenv("VISUAL", "code -w")
+	if h, _ := m.resolveHandler(filepath.Join(dir, "notes")); h.command != "code -w "+filepath.Join(dir, "notes") {
+		t.Errorf("Expected $VISUAL before $EDITOR, got %+v", h)
+	}
+}
+
+func TestResolveHandler_Desktop(t *testing.T) {
+	noDesktop(t)
+	bin := os.Getenv("PATH")
+	for _, name := range []string{"xdg-open", "open"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	t.Setenv("EDITOR", "vi")
+	t.Setenv("DISPLAY", ":0")
+	h, ok := (&model{}).resolveHandler("/tmp/a.pdf")
+	if !ok || !h.background || (h.command != "xdg-open /tmp/a.pdf" && h.command != "open /tmp/a.pdf") {
+		t.Errorf("Expected the desktop opener, got %+v", h)
+	}
+}
+
+func TestOpenFile_Enter(t *testing.T) {
+	noDesktop(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	m := initialModel()
+	m.panels[0].path = dir
+	m = readPanel(m, 0)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if cmd != nil || m.statusMsg == "" {
+		t.Fatalf("Expected a message without a handler, got %q", m.statusMsg)
+	}
+
+	t.Setenv("PATH", "/usr/bin:/bin")
+
+	// A terminal program suspends the TUI
+	m.openers = []opener{{pattern: "*.txt", command: "cat"}}
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("Expected Enter to run the handler")
+	}
+
+	// A graphical program is started in the background
+	m.openers = []opener{{pattern: "*", command: "touch opened &"}}
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if cmd == nil || m.statusMsg != "Opening a.txt" {
+		t.Fatalf("Expected the handler to start, got %q", m.statusMsg)
+	}
+	if msg := cmd(); msg != nil {
+		t.Fatalf("Failed to start handler: %v", msg)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "opened")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the handler to run in the panel directory")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseConfig_Open(t *testing.T) {
+	cfg, err := parseConfig("config.toml", []byte("[open]\n\"*.md\" = 'glow -p'\n\"image/*\" = 'feh &'\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	want := []opener{{"*.md", "glow -p"}, {"image/*", "feh &"}}
+	if len(cfg.openers) != 2 || cfg.openers[0] != want[0] || cfg.openers[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, cfg.openers)
+	}
+	for _, data := range []string{"[open]\n'*.md' = ' & '", "[open]\n'*.md' = true"} {
+		if _, err := parseConfig("config.toml", []byte(data)); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var popupStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("#00AAAA")).
+	Padding(0, 1)
+
+const (
+	popupWidth  = 60
+	popupHeight = 12
+)
+
+// popup is a list to choose from, rendered over the panels like a dialog.
+// onChoose is called with the index of the chosen item. onKey, if set,
+// handles further keys first and reports whether it did.
+type popup struct {
+	title    string
+	items    []string
+	cursor   int
+	offset   int
+	help     string
+	onChoose func(m *model, index int) tea.Cmd
+	onKey    func(m *model, pp *popup, msg tea.KeyMsg) (handled bool, cmd tea.Cmd)
+}
+
+func newPopup(title string, items []string, onChoose func(m *model, index int) tea.Cmd) *popup {
+	return &popup{title: title, items: items, onChoose: onChoose}
+}
+
+// updatePopup handles a key press while the popup is open
+func (m *model) updatePopup(msg tea.KeyMsg) tea.Cmd {
+	pp := m.popup
+	if pp.onKey != nil {
+		if handled, cmd := pp.onKey(m, pp, msg); handled {
+			return cmd
+		}
+	}
+	switch m.keys.lookupIn(scopePopup, msg.String()) {
+	case actionUp:
+		pp.cursor = max(0, pp.cursor-1)
+	case actionDown:
+		pp.cursor = max(0, min(len(pp.items)-1, pp.cursor+1))
+	case actionTop:
+		pp.cursor = 0
+	case actionBottom:
+		pp.cursor = max(0, len(pp.items)-1)
+	case actionPageUp:
+		pp.cursor = max(0, pp.cursor-popupHeight)
+	case actionPageDown:
+		pp.cursor = max(0, min(len(pp.items)-1, pp.cursor+popupHeight))
+	case actionOpen:
+		m.popup = nil
+		if len(pp.items) == 0 {
+			return nil
+		}
+		return pp.onChoose(m, pp.cursor)
+	case actionClose:
+		m.popup = nil
+	case actionQuit:
+		m.popup = nil
+		return m.quit()
+	}
+	return nil
+}
+
+// setItems replaces the items, keeping the cursor in range
+func (pp *popup) setItems(items []string) {
+	pp.items = items
+	pp.cursor = max(0, min(pp.cursor, len(items)-1))
+}
+
+func (pp *popup) view() string {
+	if pp.cursor < pp.offset {
+		pp.offset = pp.cursor
+	} else if pp.cursor >= pp.offset+popupHeight {
+		pp.offset = pp.cursor - popupHeight + 1
+	}
+
+	lines := []string{dialogTitleStyle.Foreground(lipgloss.Color("#00AAAA")).Render(pp.title), ""}
+	if len(pp.items) == 0 {
+		lines = append(lines, padCell(" (empty)", popupWidth, false))
+	}
+	for i := pp.offset; i < len(pp.items) && i < pp.offset+popupHeight; i++ {
+		line := padCell(" "+ansi.Truncate(pp.items[i], popupWidth-2, "…"), popupWidth, false)
+		if i == pp.cursor {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if pp.help != "" {
+		lines = append(lines, "", columnHeaderStyle.Render(ansi.Truncate(pp.help, popupWidth, "…")))
+	}
+	return popupStyle.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+const progressBarWidth = 30
+
+var (
+	progressFillStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00AAAA"))
+	progressTextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+)
+
+// showsProgress reports whether op moves data worth a progress bar
+func showsProgress(op string) bool {
+	return op == "copy" || op == "move"
+}
+
+// renderProgress renders a progress bar for the copy or move of name with
+// counters, throughput and ETA
+func renderProgress(op, name string, p fs.Progress) string {
+	percent := progressPercent(p)
+
+	filled := percent * progressBarWidth / 100
+	bar := progressFillStyle.Render(strings.Repeat("█", filled)) + strings.Repeat("░", progressBarWidth-filled)
+
+	verb := map[string]string{"copy": "Copying", "move": "Moving"}[op]
+	current := name
+	if p.CurrentFile != "" {
+		current = filepath.Base(p.CurrentFile)
+	}
+
+	stats := fmt.Sprintf(" %3d%%  %s/%s  files %d/%d  %s/s",
+		percent, formatSize(p.BytesDone), formatSize(p.BytesTotal),
+		p.FilesDone, p.FilesTotal, formatSize(int64(p.Throughput())))
+	if eta := p.ETA(); eta > 0 {
+		stats += "  ETA " + formatDuration(eta)
+	}
+	return progressTextStyle.Render(fmt.Sprintf("%s %s ", verb, current)) + bar + stats
+}
+
+// progressPercent returns the completed share by bytes, or by files for
+// operations without data
+func progressPercent(p fs.Progress) int {
+	percent := 0
+	if p.BytesTotal > 0 {
+		percent = int(p.BytesDone * 100 / p.BytesTotal)
+	} else if p.FilesTotal > 0 {
+		percent = p.FilesDone * 100 / p.FilesTotal
+	}
+	return max(0, min(percent, 100))
+}
+
+// formatDuration renders a duration as m:ss or h:mm:ss
+func formatDuration(d time.Duration) string {
+	total := int(d.Round(time.Second).Seconds())
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestRenderProgress(t *testing.T) {
+	view := renderProgress("copy", "big.iso", fs.Progress{
+		BytesDone:   512 * 1024,
+		BytesTotal:  1024 * 1024,
+		FilesDone:   1,
+		FilesTotal:  3,
+		CurrentFile: "/src/big.iso",
+		Elapsed:     2 * time.Second,
+	})
+
+	for _, expected := range []string{"Copying big.iso", " 50%", "512.0 KiB/1.0 MiB", "files 1/3", "256.0 KiB/s", "ETA 0:02"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected %q in progress view: %q", expected, view)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	testCases := map[time.Duration]string{
+		5 * time.Second:                 "0:05",
+		90 * time.Second:                "1:30",
+		2*time.Hour + 3*time.Minute + 4: "2:03:00",
+	}
+	for d, expected := range testCases {
+		if got := formatDuration(d); got != expected {
+			t.Errorf("formatDuration(%v) = %q, expected %q", d, got, expected)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	promptLabelStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FFFF00")).
+				Bold(true)
+
+	promptCursorStyle = lipgloss.NewStyle().
+				Reverse(true)
+)
+
+// prompt is a single-line text input shown below the panels.
+// onSubmit is called with the entered text when Enter is pressed. complete,
+// if set, completes the text on Tab and returns it with the candidates when
+// there are several.
+type prompt struct {
+	label    string
+	value    []rune
+	pos      int
+	onSubmit func(m *model, value string) tea.Cmd
+	complete func(value string) (string, []string)
+	hint     string // Candidates of the last completion
+}
+
+func newPrompt(label, initial string, onSubmit func(m *model, value string) tea.Cmd) *prompt {
+	value := []rune(initial)
+	return &prompt{label: label, value: value, pos: len(value), onSubmit: onSubmit}
+}
+
+// update edits the input. It reports whether the prompt was submitted or
+// cancelled.
+func (p *prompt) update(msg tea.KeyMsg) (submitted, cancelled bool) {
+	p.hint = ""
+	switch msg.Type {
+	case tea.KeyEnter:
+		return true, false
+	case tea.KeyEsc:
+		return false, true
+	case tea.KeyLeft:
+		p.pos = max(0, p.pos-1)
+	case tea.KeyRight:
+		p.pos = min(len(p.value), p.pos+1)
+	case tea.KeyHome, tea.KeyCtrlA:
+		p.pos = 0
+	case tea.KeyEnd, tea.KeyCtrlE:
+		p.pos = len(p.value)
+	case tea.KeyBackspace:
+		if p.pos > 0 {
+			p.value = append(p.value[:p.pos-1], p.value[p.pos:]...)
+			p.pos--
+		}
+	case tea.KeyDelete:
+		if p.pos < len(p.value) {
+			p.value = append(p.value[:p.pos], p.value[p.pos+1:]...)
+		}
+	case tea.KeyCtrlU:
+		p.value = p.value[p.pos:]
+		p.pos = 0
+	case tea.KeyRunes, tea.KeySpace:
+		p.insert(msg.Runes)
+	case tea.KeyTab:
+		if p.complete != nil {
+			value, candidates := p.complete(p.text())
+			p.value = []rune(value)
+			p.pos = len(p.value)
+			p.hint = strings.Join(candidates, "  ")
+		}
+	}
+	return false, false
+}
+
+func (p *prompt) insert(runes []rune) {
+	value := make([]rune, 0, len(p.value)+len(runes))
+	value = append(value, p.value[:p.pos]...)
+	value = append(value, runes...)
+	value = append(value, p.value[p.pos:]...)
+	p.value = value
+	p.pos += len(runes)
+}
+
+// text returns the current input
+func (p *prompt) text() string {
+	return string(p.value)
+}
+
+func (p *prompt) view() string {
+	var s strings.Builder
+	s.WriteString(promptLabelStyle.Render(p.label))
+	s.WriteString(" ")
+	s.WriteString(string(p.value[:p.pos]))
+	cursor := " "
+	if p.pos < len(p.value) {
+		cursor = string(p.value[p.pos])
+	}
+	s.WriteString(promptCursorStyle.Render(cursor))
+	if p.pos < len(p.value) {
+		s.WriteString(string(p.value[p.pos+1:]))
+	}
+	if p.hint != "" {
+		s.WriteString("  ")
+		s.WriteString(columnHeaderStyle.Render(p.hint))
+	}
+	return s.String()
+}
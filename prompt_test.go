@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPrompt_Editing(t *testing.T) {
+	p := newPrompt("Name:", "ac", nil)
+
+	p.update(tea.KeyMsg{Type: tea.KeyLeft})
+	p.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	if p.text() != "abc" {
+		t.Errorf("Expected %q, got %q", "abc", p.text())
+	}
+
+	p.update(tea.KeyMsg{Type: tea.KeyEnd})
+	p.update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	p.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("de")})
+	if p.text() != "abc de" {
+		t.Errorf("Expected %q, got %q", "abc de", p.text())
+	}
+
+	p.update(tea.KeyMsg{Type: tea.KeyBackspace})
+	p.update(tea.KeyMsg{Type: tea.KeyHome})
+	p.update(tea.KeyMsg{Type: tea.KeyDelete})
+	if p.text() != "bc d" {
+		t.Errorf("Expected %q, got %q", "bc d", p.text())
+	}
+}
+
+func TestPrompt_SubmitAndCancel(t *testing.T) {
+	p := newPrompt("Name:", "", nil)
+
+	if submitted, cancelled := p.update(tea.KeyMsg{Type: tea.KeyEnter}); !submitted || cancelled {
+		t.Error("Enter should submit the prompt")
+	}
+	if submitted, cancelled := p.update(tea.KeyMsg{Type: tea.KeyEsc}); submitted || !cancelled {
+		t.Error("Esc should cancel the prompt")
+	}
+}
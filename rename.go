@@ -0,0 +1,572 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+const (
+	renameWidth       = 72
+	renamePreviewRows = 12
+)
+
+// renamePair is one entry of a rename, both names relative to the directory
+type renamePair struct {
+	from, to string
+}
+
+// renameBatch is a set of renames in one directory, applied and undone as a
+// whole
+type renameBatch struct {
+	dir   string
+	pairs []renamePair
+}
+
+// reverse returns the batch that undoes b
+func (b renameBatch) reverse() renameBatch {
+	r := renameBatch{dir: b.dir, pairs: make([]renamePair, len(b.pairs))}
+	for i, pair := range b.pairs {
+		r.pairs[i] = renamePair{from: pair.to, to: pair.from}
+	}
+	return r
+}
+
+// renameProblems checks the new names of pairs before anything is touched
+// and returns the problem of each pair, or "" if it can be renamed. Pairs
+// that keep their name take part, as their name stays taken.
+func renameProblems(dir string, pairs []renamePair) []string {
+	problems := make([]string, len(pairs))
+	from := make(map[string]bool, len(pairs))
+	count := make(map[string]int, len(pairs))
+	for _, pair := range pairs {
+		from[pair.from] = true
+		count[pair.to]++
+	}
+	for i, pair := range pairs {
+		switch {
+		case pair.to == "":
+			problems[i] = "empty name"
+		case pair.to == "." || pair.to == ".." || strings.ContainsAny(pair.to, "/\x00"):
+			problems[i] = "invalid name"
+		case count[pair.to] > 1:
+			problems[i] = "duplicate name"
+		case pair.to != pair.from && !from[pair.to]:
+			problems[i] = takenBy(dir, pair)
+		}
+	}
+	return problems
+}
+
+// takenBy returns "already exists" if the new name of pair is taken by
+// another entry. On case-insensitive filesystems a case-only rename finds
+// its own source under the new name, which is no collision.
+func takenBy(dir string, pair renamePair) string {
+	target, err := os.Lstat(filepath.Join(dir, pair.to))
+	if err != nil {
+		return ""
+	}
+	if source, err := os.Lstat(filepath.Join(dir, pair.from)); err == nil && os.SameFile(source, target) {
+		return ""
+	}
+	return "already exists"
+}
+
+// check returns the first problem of renameProblems as an error
+func (b renameBatch) check() error {
+	for i, problem := range renameProblems(b.dir, b.pairs) {
+		if problem != "" {
+			return fmt.Errorf("%s: %s", b.pairs[i].to, problem)
+		}
+	}
+	return nil
+}
+
+// restoreError is a failed rollback rename, which leaves an entry of the
+// batch under another name
+type restoreError struct {
+	name string // Name of the entry before the batch
+	left string // Name it was left under
+	err  error
+}
+
+func (e *restoreError) Error() string {
+	return fmt.Sprintf("restore %s: %v", e.name, e.err)
+}
+
+func (e *restoreError) Unwrap() error { return e.err }
+
+// apply renames the entries of b. Every entry is first moved to a temporary
+// name, so that names can be swapped or shifted along. On failure the
+// entries renamed so far get their old names back; entries that cannot be
+// restored are reported as restoreErrors joined to the original error.
+func (b renameBatch) apply() error {
+	type step struct {
+		entry    int
+		from, to string
+	}
+	var done []step
+	rollback := func(err error) error {
+		errs := []error{err}
+		failed := make(map[int]bool)
+		for i := len(done) - 1; i >= 0; i-- {
+			s := done[i]
+			if failed[s.entry] {
+				// Its earlier steps cannot be undone either
+				continue
+			}
+			if rerr := os.Rename(s.to, s.from); rerr != nil {
+				failed[s.entry] = true
+				errs = append(errs, &restoreError{name: b.pairs[s.entry].from, left: filepath.Base(s.to), err: rerr})
+			}
+		}
+		return errors.Join(errs...)
+	}
+	rename := func(entry int, from, to string) error {
+		if _, err := os.Lstat(to); err == nil {
+			// os.Rename would replace it
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrExist}
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		done = append(done, step{entry, from, to})
+		return nil
+	}
+
+	temps := make([]string, len(b.pairs))
+	for i, pair := range b.pairs {
+		temps[i] = filepath.Join(b.dir, fmt.Sprintf(".min-commander-rename-%d-%d", os.Getpid(), i))
+		if err := rename(i, filepath.Join(b.dir, pair.from), temps[i]); err != nil {
+			return rollback(err)
+		}
+	}
+	for i, pair := range b.pairs {
+		if err := rename(i, temps[i], filepath.Join(b.dir, pair.to)); err != nil {
+			return rollback(err)
+		}
+	}
+	return nil
+}
+
+// renameFailure describes an error of apply for the status line, naming the
+// entries the rollback could not restore
+func renameFailure(err error) string {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	msg := fmt.Sprintf("Cannot rename: %v", errs[0])
+	var lost []string
+	for _, e := range errs[1:] {
+		var restoreErr *restoreError
+		if errors.As(e, &restoreErr) {
+			lost = append(lost, fmt.Sprintf("%s (left as %s)", restoreErr.name, restoreErr.left))
+		}
+	}
+	if len(lost) > 0 {
+		msg += "; could not restore " + strings.Join(lost, ", ")
+	}
+	return msg
+}
+
+// runRename checks and applies batch, remembers it for undo and refreshes
+// both panels with the cursor on selectName. It reports whether the entries
+// were renamed.
+func (m *model) runRename(batch renameBatch, index int, selectName string) (bool, tea.Cmd) {
+	if err := batch.check(); err != nil {
+		m.statusMsg = fmt.Sprintf("Cannot rename %v", err)
+		return false, nil
+	}
+	if err := batch.apply(); err != nil {
+		m.statusMsg = renameFailure(err)
+		return false, tea.Batch(m.refreshCmd(0), m.refreshCmd(1))
+	}
+	m.lastRename = &batch
+	if p := &m.panels[index]; !p.isVirtual() && p.path == batch.dir {
+		p.selectName = selectName
+	}
+	return true, tea.Batch(m.refreshCmd(0), m.refreshCmd(1))
+}
+
+// openRenamePrompt asks for the new name of the entry under the cursor, with
+// the input cursor before the extension
+func (m *model) openRenamePrompt() {
+	p := &m.panels[m.activePanel]
+	if p.isVirtual() {
+		m.statusMsg = "Not available here"
+		return
+	}
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+		m.statusMsg = "No file selected"
+		return
+	}
+	entry := p.entries[p.cursor]
+	index, dir := m.activePanel, p.path
+	m.prompt = newPrompt("Rename to:", entry.Name, func(m *model, name string) tea.Cmd {
+		if name = strings.TrimSpace(name); name == "" || name == entry.Name {
+			return nil
+		}
+		batch := renameBatch{dir: dir, pairs: []renamePair{{from: entry.Name, to: name}}}
+		ok, cmd := m.runRename(batch, index, name)
+		if ok {
+			m.statusMsg = fmt.Sprintf("Renamed %s to %s", entry.Name, name)
+		}
+		return cmd
+	})
+	if !entry.IsDir {
+		base, _ := splitExt(entry.Name)
+		m.prompt.pos = len([]rune(base))
+	}
+}
+
+// undoRename reverts the last rename
+func (m *model) undoRename() tea.Cmd {
+	if m.lastRename == nil {
+		m.statusMsg = "Nothing to undo"
+		return nil
+	}
+	batch := m.lastRename.reverse()
+	ok, cmd := m.runRename(batch, m.activePanel, m.panels[m.activePanel].renamedCursor(batch))
+	if ok {
+		m.lastRename = nil
+		m.statusMsg = fmt.Sprintf("Undid rename of %s", describeRenames(batch))
+	}
+	return cmd
+}
+
+// renamedCursor returns the name of the cursor entry after batch
+func (p *panel) renamedCursor(batch renameBatch) string {
+	if len(p.entries) == 0 {
+		return ""
+	}
+	name := p.entries[p.cursor].Name
+	for _, pair := range batch.pairs {
+		if pair.from == name {
+			return pair.to
+		}
+	}
+	return name
+}
+
+// describeRenames names the entry of a single rename, or counts the entries
+func describeRenames(batch renameBatch) string {
+	if len(batch.pairs) == 1 {
+		return batch.pairs[0].from
+	}
+	return fmt.Sprintf("%d entries", len(batch.pairs))
+}
+
+// splitExt splits a name into base and extension with its dot. Names of
+// hidden files without a further dot have no extension.
+func splitExt(name string) (base, ext string) {
+	ext = filepath.Ext(name)
+	if ext == name {
+		return name, ""
+	}
+	return strings.TrimSuffix(name, ext), ext
+}
+
+// expandRenameTemplate builds a new name for entry from template:
+//
+//	[N]      name without extension
+//	[E]      extension with its dot
+//	[C]      counter, [C:3] padded to three digits
+//	[D]      modification date, [D:YYYYMMDD-hhmmss] in that layout
+//	[U] [L]  upper or lower case for the text that follows
+//	[T]      title case for the text that follows
+//	[[       a literal [
+func expandRenameTemplate(template string, entry fs.FileEntry, counter int) (string, error) {
+	base, ext := entry.Name, ""
+	if !entry.IsDir {
+		base, ext = splitExt(entry.Name)
+	}
+	var s strings.Builder
+	convert := func(text string) string { return text }
+	for template != "" {
+		open := strings.IndexByte(template, '[')
+		if open < 0 {
+			s.WriteString(convert(template))
+			break
+		}
+		s.WriteString(convert(template[:open]))
+		template = template[open+1:]
+		if strings.HasPrefix(template, "[") {
+			s.WriteString("[")
+			template = template[1:]
+			continue
+		}
+		token, rest, ok := strings.Cut(template, "]")
+		if !ok {
+			return "", errors.New("unclosed [")
+		}
+		template = rest
+		name, arg, hasArg := strings.Cut(token, ":")
+		switch {
+		case token == "N":
+			s.WriteString(convert(base))
+		case token == "E":
+			s.WriteString(convert(ext))
+		case name == "C":
+			width := 1
+			if hasArg {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 || n > 9 {
+					return "", fmt.Errorf("[%s]: width must be 1 to 9", token)
+				}
+				width = n
+			}
+			s.WriteString(fmt.Sprintf("%0*d", width, counter))
+		case name == "D":
+			layout := "YYYY-MM-DD"
+			if hasArg {
+				layout = arg
+			}
+			s.WriteString(formatRenameDate(layout, entry))
+		case token == "U":
+			convert = strings.ToUpper
+		case token == "L":
+			convert = strings.ToLower
+		case token == "T":
+			convert = titleCase
+		default:
+			return "", fmt.Errorf("unknown token [%s]", token)
+		}
+	}
+	return s.String(), nil
+}
+
+// formatRenameDate writes the modification time of entry in layout, where
+// YYYY, MM, DD, hh, mm and ss stand for its parts
+func formatRenameDate(layout string, entry fs.FileEntry) string {
+	t := entry.ModTime
+	return strings.NewReplacer(
+		"YYYY", fmt.Sprintf("%04d", t.Year()),
+		"MM", fmt.Sprintf("%02d", t.Month()),
+		"DD", fmt.Sprintf("%02d", t.Day()),
+		"hh", fmt.Sprintf("%02d", t.Hour()),
+		"mm", fmt.Sprintf("%02d", t.Minute()),
+		"ss", fmt.Sprintf("%02d", t.Second()),
+	).Replace(layout)
+}
+
+// titleCase upper-cases the first letter of each word and lower-cases the rest
+func titleCase(text string) string {
+	runes := []rune(text)
+	start := true
+	for i, r := range runes {
+		if start {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+		start = !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	return string(runes)
+}
+
+// Fields of the batch rename dialog
+const (
+	renameFieldTemplate = iota
+	renameFieldSearch
+	renameFieldReplace
+	renameFieldCount
+)
+
+// batchRename is the dialog renaming several entries at once. The preview is
+// computed on every change, and Enter only renames if it has no problems.
+type batchRename struct {
+	index    int // Panel whose entries are renamed
+	dir      string
+	entries  []fs.FileEntry
+	fields   [renameFieldCount]*prompt
+	focus    int
+	offset   int // First preview row shown
+	pairs    []renamePair
+	problems []string
+	err      error // Invalid template or search expression
+	keys     keymap
+}
+
+// renameHints are the keys listed in the batch rename dialog
+var renameHints = []keyHint{
+	{[]action{actionNextField}, "Field"},
+	{[]action{actionUp, actionDown}, "Scroll"},
+	{[]action{actionRename}, "Rename"},
+	{[]action{actionCancel}, "Cancel"},
+}
+
+// openBatchRename opens the batch rename dialog for the marked entries, or
+// the entry under the cursor
+func (m *model) openBatchRename() {
+	p := &m.panels[m.activePanel]
+	if p.isVirtual() {
+		m.statusMsg = "Not available here"
+		return
+	}
+	entries := p.operationTargets()
+	if len(entries) == 0 {
+		m.statusMsg = "No file selected"
+		return
+	}
+	br := &batchRename{index: m.activePanel, dir: p.path, entries: entries, keys: m.keys}
+	br.fields[renameFieldTemplate] = newPrompt("Name:   ", "[N][E]", nil)
+	br.fields[renameFieldSearch] = newPrompt("Search: ", "", nil)
+	br.fields[renameFieldReplace] = newPrompt("Replace:", "", nil)
+	br.preview()
+	m.rename = br
+}
+
+// preview computes the new names and their problems
+func (br *batchRename) preview() {
+	br.pairs, br.problems, br.err = nil, nil, nil
+	var search *regexp.Regexp
+	if expr := br.fields[renameFieldSearch].text(); expr != "" {
+		var err error
+		if search, err = regexp.Compile(expr); err != nil {
+			br.err = fmt.Errorf("search: %v", err)
+			return
+		}
+	}
+	replace := br.fields[renameFieldReplace].text()
+	for i, entry := range br.entries {
+		name, err := expandRenameTemplate(br.fields[renameFieldTemplate].text(), entry, i+1)
+		if err != nil {
+			br.err = fmt.Errorf("name: %v", err)
+			return
+		}
+		if search != nil {
+			name = search.ReplaceAllString(name, replace)
+		}
+		br.pairs = append(br.pairs, renamePair{from: entry.Name, to: name})
+	}
+	br.problems = renameProblems(br.dir, br.pairs)
+}
+
+// batch returns the pairs whose name changes
+func (br *batchRename) batch() renameBatch {
+	batch := renameBatch{dir: br.dir}
+	for _, pair := range br.pairs {
+		if pair.from != pair.to {
+			batch.pairs = append(batch.pairs, pair)
+		}
+	}
+	return batch
+}
+
+// updateBatchRename handles a key press while the batch rename dialog is open
+func (m *model) updateBatchRename(msg tea.KeyMsg) tea.Cmd {
+	br := m.rename
+	switch m.keys.lookupIn(scopeRename, msg.String()) {
+	case actionNextField:
+		br.focus = (br.focus + 1) % renameFieldCount
+	case actionPrevField:
+		br.focus = (br.focus + renameFieldCount - 1) % renameFieldCount
+	case actionUp:
+		br.offset = max(0, br.offset-1)
+	case actionDown:
+		br.offset = max(0, min(len(br.pairs)-renamePreviewRows, br.offset+1))
+	case actionPageUp:
+		br.offset = max(0, br.offset-renamePreviewRows)
+	case actionPageDown:
+		br.offset = max(0, min(len(br.pairs)-renamePreviewRows, br.offset+renamePreviewRows))
+	case actionRename:
+		return m.applyBatchRename()
+	case actionCancel:
+		m.rename = nil
+	case actionQuit:
+		m.rename = nil
+		return m.quit()
+	default:
+		// Enter and Esc only submit or cancel through their bindings
+		br.fields[br.focus].update(msg)
+		br.preview()
+		br.offset = max(0, min(br.offset, len(br.pairs)-renamePreviewRows))
+	}
+	return nil
+}
+
+// applyBatchRename renames the entries if the preview has no problems
+func (m *model) applyBatchRename() tea.Cmd {
+	br := m.rename
+	if br.err != nil {
+		return nil
+	}
+	for _, problem := range br.problems {
+		if problem != "" {
+			return nil
+		}
+	}
+	batch := br.batch()
+	m.rename = nil
+	if len(batch.pairs) == 0 {
+		m.statusMsg = "No names changed"
+		return nil
+	}
+
+	ok, cmd := m.runRename(batch, br.index, m.panels[br.index].renamedCursor(batch))
+	if ok {
+		m.statusMsg = fmt.Sprintf("Renamed %s", describeRenames(batch))
+		if keys := m.keys.keysFor(actionUndoRename); len(keys) > 0 {
+			m.statusMsg += fmt.Sprintf(", %s undoes", displayKey(keys[0]))
+		}
+	}
+	return cmd
+}
+
+func (br *batchRename) view() string {
+	title := fmt.Sprintf("Rename %d entries", len(br.entries))
+	if len(br.entries) == 1 {
+		title = "Rename " + br.entries[0].Name
+	}
+	lines := []string{dialogTitleStyle.Foreground(lipgloss.Color("#00AAAA")).Render(title), ""}
+	for i, field := range br.fields {
+		if i == br.focus {
+			lines = append(lines, field.view())
+		} else {
+			lines = append(lines, promptLabelStyle.Render(field.label)+" "+field.text())
+		}
+	}
+	lines = append(lines, "")
+
+	conflicts := 0
+	for _, problem := range br.problems {
+		if problem != "" {
+			conflicts++
+		}
+	}
+	for i := br.offset; i < len(br.pairs) && i < br.offset+renamePreviewRows; i++ {
+		pair, problem := br.pairs[i], br.problems[i]
+		line := ansi.Truncate(" "+pair.from+" → "+pair.to, renameWidth-2, "…")
+		switch {
+		case problem != "":
+			line = brokenLinkStyle.Render(ansi.Truncate(line+"  ("+problem+")", renameWidth-2, "…"))
+		case pair.from == pair.to:
+			line = columnHeaderStyle.Render(line)
+		}
+		lines = append(lines, padCell(line, renameWidth, false))
+	}
+
+	status := fmt.Sprintf("%d to rename", len(br.batch().pairs))
+	switch {
+	case br.err != nil:
+		status = br.err.Error()
+	case conflicts > 0:
+		status = fmt.Sprintf("Conflicts: %d, fix them to rename", conflicts)
+	}
+	help := "[N] name [E] ext [C:3] counter [D] date [U]/[L]/[T] case"
+	lines = append(lines, "", statusStyle.Render(ansi.Truncate(status, renameWidth, "…")),
+		columnHeaderStyle.Render(help),
+		columnHeaderStyle.Render(br.keys.hints(scopeRename, renameHints)))
+	return popupStyle.Render(strings.Join(lines, "\n"))
+}
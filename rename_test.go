@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestExpandRenameTemplate(t *testing.T) {
+	file := fs.FileEntry{Name: "My Photo.JPG", ModTime: time.Date(2026, 3, 7, 9, 5, 1, 0, time.Local)}
+	tests := []struct {
+		template string
+		entry    fs.FileEntry
+		want     string
+		wantErr  bool
+	}{
+		{"[N][E]", file, "My Photo.JPG", false},
+		{"[N]_[C:3][E]", file, "My Photo_007.JPG", false},
+		{"[D]_[C][L][E]", file, "2026-03-07_7.jpg", false},
+		{"[D:YYYYMMDD-hhmmss]", file, "20260307-090501", false},
+		{"[U][N][L][E]", file, "MY PHOTO.jpg", false},
+		{"[T]the best-of[E]", file, "The Best-Of.Jpg", false},
+		{"[[[N]]", file, "[My Photo]", false},
+		{"[N][E]", fs.FileEntry{Name: ".bashrc"}, ".bashrc", false},
+		{"[N]-old", fs.FileEntry{Name: "src.d", IsDir: true}, "src.d-old", false},
+		{"[N", file, "", true},
+		{"[X]", file, "", true},
+		{"[C:0]", file, "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandRenameTemplate(tt.template, tt.entry, 7)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("expandRenameTemplate(%q, %s) = %q, %v, expected %q", tt.template, tt.entry.Name, got, err, tt.want)
+		}
+	}
+}
+
+// dirNames lists the names in dir, sorted
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestRenameProblems(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a", "b", "c", "other")
+	dir := m.panels[0].path
+	pairs := []renamePair{
+		{"a", "b"},     // Taken by b, which is renamed too
+		{"b", "other"}, // Taken by a file outside the batch
+		{"c", "c"},     // Unchanged, keeps its name taken
+		{"d", "c"},
+		{"e", ""},
+		{"f", "x/y"},
+		{"g", ".."},
+	}
+	want := []string{"", "already exists", "duplicate name", "duplicate name", "empty name", "invalid name", "invalid name"}
+	if got := renameProblems(dir, pairs); !slices.Equal(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestRenameProblems_CaseOnly(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "foo", "Bar.txt")
+	dir := m.panels[0].path
+
+	// On case-insensitive filesystems the new names find the entries themselves
+	batch := renameBatch{dir: dir, pairs: []renamePair{{"foo", "FOO"}, {"Bar.txt", "bar.txt"}}}
+	if got := renameProblems(dir, batch.pairs); !slices.Equal(got, []string{"", ""}) {
+		t.Fatalf("Expected no problems for a case-only rename, got %q", got)
+	}
+	if err := batch.apply(); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"FOO", "bar.txt"}) {
+		t.Errorf("Expected the names in their new case, got %v", got)
+	}
+}
+
+func TestRenameFailure(t *testing.T) {
+	err := errors.Join(os.ErrExist, &restoreError{name: "a", left: ".tmp-0", err: os.ErrPermission})
+	want := "Cannot rename: file already exists; could not restore a (left as .tmp-0)"
+	if got := renameFailure(err); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := renameFailure(os.ErrExist); got != "Cannot rename: file already exists" {
+		t.Errorf("Expected the plain error, got %q", got)
+	}
+}
+
+func TestRenameBatch_Apply(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a", "b", "c")
+	dir := m.panels[0].path
+
+	// Swapping and shifting names needs the temporary names
+	batch := renameBatch{dir: dir, pairs: []renamePair{{"a", "b"}, {"b", "a"}, {"c", "d"}}}
+	if err := batch.apply(); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	for from, to := range map[string]string{"a": "b", "b": "a", "c": "d"} {
+		if data, err := os.ReadFile(filepath.Join(dir, to)); err != nil || string(data) != "new "+from {
+			t.Errorf("Expected %s in %s, got %q, %v", from, to, data, err)
+		}
+	}
+	if err := batch.reverse().apply(); err != nil {
+		t.Fatalf("Failed to undo: %v", err)
+	}
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("Expected the old names back, got %v", got)
+	}
+
+	// A target that appears after the check rolls everything back
+	if err := os.WriteFile(filepath.Join(dir, "z"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	batch = renameBatch{dir: dir, pairs: []renamePair{{"a", "x"}, {"b", "y"}, {"c", "z"}}}
+	if err := batch.apply(); !errors.Is(err, os.ErrExist) {
+		t.Errorf("Expected os.ErrExist, got %v", err)
+	}
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"a", "b", "c", "z"}) {
+		t.Errorf("Expected nothing renamed, got %v", got)
+	}
+}
+
+func cursorName(m model) string {
+	p := m.panels[m.activePanel]
+	return p.entries[p.cursor].Name
+}
+
+func TestRenamePrompt(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "notes.txt", "taken.txt")
+	dir := m.panels[0].path
+
+	m, _ = pressKey(m, "R")
+	if m.prompt == nil || m.prompt.text() != "notes.txt" || m.prompt.pos != len("notes") {
+		t.Fatal("Expected a prompt with the name and the cursor before the extension")
+	}
+	m = pressKeys(t, typeText(t, m, "-old"), "enter")
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"notes-old.txt", "taken.txt"}) {
+		t.Fatalf("Expected notes.txt renamed, got %v", got)
+	}
+	if cursorName(m) != "notes-old.txt" || m.statusMsg != "Renamed notes.txt to notes-old.txt" {
+		t.Errorf("Expected the cursor on the new name, got %s, %q", cursorName(m), m.statusMsg)
+	}
+
+	// Existing names are not replaced
+	m, _ = pressKey(m, "R")
+	m.prompt = newPrompt("", "", m.prompt.onSubmit)
+	m = pressKeys(t, typeText(t, m, "taken.txt"), "enter")
+	if m.statusMsg != "Cannot rename taken.txt: already exists" {
+		t.Errorf("Expected a collision, got %q", m.statusMsg)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "taken.txt")); string(data) != "new taken.txt" {
+		t.Errorf("Expected taken.txt untouched, got %q", data)
+	}
+
+	m = pressKeys(t, m, "u")
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"notes.txt", "taken.txt"}) {
+		t.Errorf("Expected the rename undone, got %v", got)
+	}
+	if cursorName(m) != "notes.txt" {
+		t.Errorf("Expected the cursor on the old name, got %s", cursorName(m))
+	}
+	if m, _ = pressKey(m, "u"); m.statusMsg != "Nothing to undo" {
+		t.Errorf("Expected nothing left to undo, got %q", m.statusMsg)
+	}
+}
+
+func TestBatchRename(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a.txt", "b.txt", "c.log", "img_2.txt")
+	dir := m.panels[0].path
+	m.panels[0].setSelected("a.txt", true)
+	m.panels[0].setSelected("b.txt", true)
+	m.panels[0].setSelected("c.log", true)
+
+	m = pressKeys(t, m, "alt+r")
+	br := m.rename
+	if br == nil || len(br.entries) != 3 {
+		t.Fatal("Expected the dialog for the marked entries")
+	}
+
+	// img_2.txt is outside the batch, so the second name collides
+	br.fields[renameFieldTemplate] = newPrompt("", "img_[C][E]", nil)
+	m = pressKeys(t, m, "end")
+	if want := []string{"", "already exists", ""}; !slices.Equal(br.problems, want) {
+		t.Fatalf("Expected %q, got %q", want, br.problems)
+	}
+	if !strings.Contains(br.view(), "a.txt → img_1.txt") {
+		t.Errorf("Expected the preview in the dialog:\n%s", br.view())
+	}
+	m = pressKeys(t, m, "enter")
+	if m.rename == nil || len(dirNames(t, dir)) != 4 || !slices.Contains(dirNames(t, dir), "a.txt") {
+		t.Fatal("Expected Enter to do nothing while there are conflicts")
+	}
+
+	// An invalid expression is shown instead of the preview
+	m = pressKeys(t, m, "tab", "(")
+	if br.err == nil || !strings.Contains(br.view(), "search:") {
+		t.Errorf("Expected an error for the search expression, got %v", br.err)
+	}
+	m = pressKeys(t, m, "backspace")
+
+	// Search and replace runs on the result of the template
+	br.fields[renameFieldSearch] = newPrompt("", `img_(\d)`, nil)
+	br.fields[renameFieldReplace] = newPrompt("", "photo-$1", nil)
+	m = pressKeys(t, m, "end")
+	if br.err != nil || br.pairs[1].to != "photo-2.txt" || slices.ContainsFunc(br.problems, func(p string) bool { return p != "" }) {
+		t.Fatalf("Expected a clean preview, got %v %v %q", br.err, br.pairs, br.problems)
+	}
+	m = pressKeys(t, m, "enter")
+	want := []string{"img_2.txt", "photo-1.txt", "photo-2.txt", "photo-3.log"}
+	if got := dirNames(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if m.rename != nil || m.statusMsg != "Renamed 3 entries, u undoes" {
+		t.Errorf("Expected the dialog closed, got %q", m.statusMsg)
+	}
+
+	m = pressKeys(t, m, "u")
+	if got := dirNames(t, dir); !slices.Equal(got, []string{"a.txt", "b.txt", "c.log", "img_2.txt"}) {
+		t.Errorf("Expected the whole batch undone, got %v", got)
+	}
+	if m.statusMsg != "Undid rename of 3 entries" {
+		t.Errorf("Expected the undo reported, got %q", m.statusMsg)
+	}
+
+	// Esc closes the dialog without renaming
+	if m = pressKeys(t, m, "alt+r", "esc"); m.rename != nil {
+		t.Error("Expected Esc to close the dialog")
+	}
+}
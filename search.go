@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+const (
+	searchBatchSize     = 64
+	searchFlushInterval = 100 * time.Millisecond
+)
+
+// search is a running or finished recursive file search. While a panel holds
+// a search, its entries are the hits with names relative to root.
+type search struct {
+	id         int
+	root       string
+	pattern    string
+	running    bool
+	cancel     context.CancelFunc
+	results    <-chan searchResultsMsg
+	origin     string // Directory the panel showed before the search
+	originName string // Entry under the cursor there
+}
+
+// searchResultsMsg delivers a batch of hits of the search with the given id
+type searchResultsMsg struct {
+	id      int
+	entries []fs.FileEntry
+	done    bool
+	err     error
+}
+
+// parseSearchQuery splits a query such as "src/*.go" into the directory to
+// search and the name pattern. Relative directories are resolved against
+// base. A pattern without wildcards matches names containing it.
+func parseSearchQuery(base, query string) (root, pattern string) {
+	query = strings.TrimSpace(query)
+	if query == "~" || strings.HasPrefix(query, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			query = home + query[1:]
+		}
+	}
+
+	dir, pattern := filepath.Split(query)
+	switch {
+	case dir == "":
+		root = base
+	case filepath.IsAbs(dir):
+		root = filepath.Clean(dir)
+	default:
+		root = filepath.Join(base, dir)
+	}
+
+	if pattern == "" {
+		pattern = "*"
+	} else if !fs.HasWildcard(pattern) {
+		pattern = "*" + pattern + "*"
+	}
+	return root, pattern
+}
+
+// startSearch walks root in a background goroutine. Hits are batched and
+// delivered through the returned search's results channel, which is closed
+// once the walk ends or the search is cancelled.
+func startSearch(id int, root, pattern string, showHidden bool) *search {
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan searchResultsMsg)
+
+	go func() {
+		defer close(results)
+
+		var batch []fs.FileEntry
+		lastFlush := time.Now()
+		flush := func(done bool, err error) bool {
+			msg := searchResultsMsg{id: id, entries: batch, done: done, err: err}
+			batch = nil
+			lastFlush = time.Now()
+			select {
+			case results <- msg:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		err := fs.Walk(ctx, root, func(path string, entry fs.FileEntry) error {
+			if !showHidden && strings.HasPrefix(entry.Name, ".") {
+				if entry.IsDir {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if fs.MatchWildcard(pattern, entry.Name) {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				entry.Name = rel
+				batch = append(batch, entry)
+			}
+			if len(batch) >= searchBatchSize || (len(batch) > 0 && time.Since(lastFlush) >= searchFlushInterval) {
+				if !flush(false, nil) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		flush(true, err)
+	}()
+
+	return &search{id: id, root: root, pattern: pattern, running: true, cancel: cancel, results: results}
+}
+
+// waitForSearchCmd waits for the next batch of search results
+func waitForSearchCmd(results <-chan searchResultsMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-results
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// openSearchPrompt asks for a search query for the active panel
+func (m *model) openSearchPrompt() {
+	m.prompt = newPrompt("Search:", "", func(m *model, query string) tea.Cmd {
+		if strings.TrimSpace(query) == "" {
+			return nil
+		}
+		return m.beginSearch(query)
+	})
+}
+
+// beginSearch turns the active panel into a search results panel
+func (m *model) beginSearch(query string) tea.Cmd {
+	p := &m.panels[m.activePanel]
+	// A new search from the results still returns to where the first began
+	origin, originName := p.path, ""
+	if len(p.entries) > 0 {
+		originName = p.entries[p.cursor].Name
+	}
+	if p.search != nil {
+		origin, originName = p.search.origin, p.search.originName
+	}
+	root, pattern := parseSearchQuery(p.path, query)
+	p.closeSearch()
+
+	m.searchSeq++
+	p.search = startSearch(m.searchSeq, root, pattern, p.showHidden)
+	p.search.origin, p.search.originName = origin, originName
+	p.path = root
+	p.entries = nil
+	p.cursor = 0
+	p.viewportOffset = 0
+	p.filter = nil
+	m.statusMsg = ""
+	return waitForSearchCmd(p.search.results)
+}
+
+// handleSearchResults appends a batch of hits to the panel owning the search
+func (m *model) handleSearchResults(msg searchResultsMsg) tea.Cmd {
+	for i := range m.panels {
+		p := &m.panels[i]
+		if p.search == nil || p.search.id != msg.id {
+			continue
+		}
+		p.entries = append(p.entries, msg.entries...)
+		p.ensureCursorVisible()
+		if !msg.done {
+			return waitForSearchCmd(p.search.results)
+		}
+		p.search.running = false
+		if msg.err != nil {
+			m.statusMsg = "Search error: " + msg.err.Error()
+		}
+	}
+	return nil
+}
+
+// cancelSearch stops a running search but keeps the hits found so far
+func (p *panel) cancelSearch() {
+	if p.search != nil && p.search.running {
+		p.search.cancel()
+		p.search.running = false
+	}
+}
+
+// closeSearch cancels the search and leaves the results panel for the
+// directory it was started from, with the cursor back on its entry once the
+// directory is read
+func (p *panel) closeSearch() {
+	if p.search == nil {
+		return
+	}
+	p.cancelSearch()
+	p.path = p.search.origin
+	p.selectName = p.search.originName
+	p.search = nil
+}
+
+// jumpToSearchHit leaves the results panel and opens the directory containing
+// the hit under the cursor, with the cursor on the hit
+func (m *model) jumpToSearchHit() tea.Cmd {
+	p := &m.panels[m.activePanel]
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+		return nil
+	}
+	hit := p.entries[p.cursor].Name
+	root := p.search.root
+	p.closeSearch()
+	return m.changeDir(m.activePanel, filepath.Join(root, filepath.Dir(hit)), filepath.Base(hit))
+}
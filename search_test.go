@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	testCases := []struct {
+		query   string
+		root    string
+		pattern string
+	}{
+		{"*.go", "/base", "*.go"},
+		{"main", "/base", "*main*"},
+		{"src/*.go", "/base/src", "*.go"},
+		{"/etc/*.conf", "/etc", "*.conf"},
+		{"../x?", "/", "x?"},
+		{"/tmp/", "/tmp", "*"},
+	}
+
+	for _, tc := range testCases {
+		root, pattern := parseSearchQuery("/base", tc.query)
+		if root != tc.root || pattern != tc.pattern {
+			t.Errorf("parseSearchQuery(%q) = (%q, %q), expected (%q, %q)", tc.query, root, pattern, tc.root, tc.pattern)
+		}
+	}
+}
+
+// runSearch feeds all search results back into the model
+func runSearch(t *testing.T, m model, cmd tea.Cmd) model {
+	t.Helper()
+	for cmd != nil {
+		msg := cmd()
+		if msg == nil {
+			break
+		}
+		updated, next := m.Update(msg)
+		m = updated.(model)
+		cmd = next
+	}
+	return m
+}
+
+func TestSearch_ResultsAndJump(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, f := range []string{"a/notes.TXT", "a/b/todo.txt", "c/readme.md", ".hidden/secret.txt"} {
+		path := filepath.Join(tmpDir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	m := initialModel()
+	m.panels[0].path = tmpDir
+
+	m.openSearchPrompt()
+	if m.prompt == nil {
+		t.Fatal("Search prompt should be open")
+	}
+	cmd := m.prompt.onSubmit(&m, "*.txt")
+	m.prompt = nil
+	m = runSearch(t, m, cmd)
+
+	p := m.panels[0]
+	if p.search == nil || p.search.running {
+		t.Fatal("Search should have finished")
+	}
+	var names []string
+	for _, entry := range p.entries {
+		names = append(names, entry.Name)
+	}
+	sort.Strings(names)
+	if len(names) != 2 || names[0] != filepath.Join("a", "b", "todo.txt") || names[1] != filepath.Join("a", "notes.TXT") {
+		t.Fatalf("Unexpected hits: %v", names)
+	}
+
+	// Jump to the hit under the cursor
+	for i, entry := range p.entries {
+		if entry.Name == filepath.Join("a", "b", "todo.txt") {
+			m.panels[0].cursor = i
+		}
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	p = m.panels[0]
+	if p.search != nil {
+		t.Error("Search panel should be closed after jumping")
+	}
+	if p.path != filepath.Join(tmpDir, "a", "b") {
+		t.Errorf("Expected path %s, got %s", filepath.Join(tmpDir, "a", "b"), p.path)
+	}
+	if p.entries[p.cursor].Name != "todo.txt" {
+		t.Errorf("Expected cursor on todo.txt, got %s", p.entries[p.cursor].Name)
+	}
+}
+
+func TestSearch_CancelAndClose(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m := initialModel()
+	m.panels[0].path = tmpDir
+	m.beginSearch("*")
+	if !m.panels[0].search.running {
+		t.Fatal("Search should be running")
+	}
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if m.panels[0].search == nil || m.panels[0].search.running {
+		t.Fatal("First Esc should stop the search and keep the results")
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	if m.panels[0].search != nil {
+		t.Error("Second Esc should close the results panel")
+	}
+	if m.panels[0].path != tmpDir {
+		t.Errorf("Expected path %s, got %s", tmpDir, m.panels[0].path)
+	}
+	if cmd == nil {
+		t.Error("Closing the results panel should reload the directory")
+	}
+}
+
+func TestSearch_CloseReturnsToOrigin(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"a/b", "c"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	m := initialModel()
+	m.panels[0].path = tmpDir
+	m = readPanel(m, 0)
+	m.panels[0].cursor, _ = m.panels[0].lookup("c")
+
+	// Searching elsewhere and then again from the results, relative to them
+	m = runSearch(t, m, m.beginSearch("a/*"))
+	m = runSearch(t, m, m.beginSearch("b*"))
+	if p := m.panels[0]; p.path != filepath.Join(tmpDir, "a") || len(p.entries) != 1 {
+		t.Fatalf("Expected the hits of the second search, got %s %v", p.path, p.entries)
+	}
+
+	m = pressKeys(t, m, "esc")
+	if p := m.panels[0]; p.search != nil || p.path != tmpDir || p.entries[p.cursor].Name != "c" {
+		t.Errorf("Expected the panel back in %s on c, got %s", tmpDir, p.path)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// isVisible reports whether the entry is shown with the current hidden-file
+// setting and quick filter
+func (p *panel) isVisible(entry fs.FileEntry) bool {
+	if !p.showHidden && strings.HasPrefix(entry.Name, ".") {
+		return false
+	}
+	if p.filter != nil {
+		_, ok := p.filter.match(entry.Name)
+		return ok
+	}
+	return true
+}
+
+// setEntries replaces the listing. The cursor moves to selectName if it is
+// set; otherwise, with keep, it stays on the same entry in the same row of
+// the viewport. If the entry is gone, the cursor keeps its index.
+func (p *panel) setEntries(entries []fs.FileEntry, keep bool) {
+	name, row := p.selectName, -1
+	if name == "" && keep && p.cursor < len(p.entries) {
+		name = p.entries[p.cursor].Name
+		row = p.visibleIndex(p.cursor) - p.viewportOffset
+	}
+	p.entries = entries
+	p.selectName = ""
+	if i, ok := p.lookup(name); ok && name != "" {
+		p.cursor = i
+	}
+	p.pruneSelection()
+	// Limit cursor to valid value
+	if p.cursor >= len(p.entries) {
+		p.cursor = max(0, len(p.entries)-1)
+	}
+	p.ensureCursorVisible()
+	if row >= 0 {
+		p.viewportOffset = max(0, p.visibleIndex(p.cursor)-row)
+	}
+}
+
+// visibleIndex returns the position of the entry at index i among the
+// visible entries
+func (p *panel) visibleIndex(i int) int {
+	n := 0
+	for j := 0; j < i && j < len(p.entries); j++ {
+		if p.isVisible(p.entries[j]) {
+			n++
+		}
+	}
+	return n
+}
+
+// moveCursor moves the cursor by delta visible entries, stopping at the
+// first and last visible entry
+func (p *panel) moveCursor(delta int) {
+	step := 1
+	if delta < 0 {
+		step, delta = -1, -delta
+	}
+	for i := p.cursor + step; delta > 0 && i >= 0 && i < len(p.entries); i += step {
+		if p.isVisible(p.entries[i]) {
+			p.cursor = i
+			delta--
+		}
+	}
+}
+
+// ensureCursorVisible moves the cursor off entries that are hidden or
+// filtered out, to the next visible entry or else the previous one
+func (p *panel) ensureCursorVisible() {
+	if p.cursor >= len(p.entries) || p.isVisible(p.entries[p.cursor]) {
+		return
+	}
+	for i := p.cursor + 1; i < len(p.entries); i++ {
+		if p.isVisible(p.entries[i]) {
+			p.cursor = i
+			return
+		}
+	}
+	for i := p.cursor - 1; i >= 0; i-- {
+		if p.isVisible(p.entries[i]) {
+			p.cursor = i
+			return
+		}
+	}
+}
+
+// entryKey identifies the entry at index i for marks: its name, or for trash
+// items the file inside the trash, as several can share an original path
+func (p *panel) entryKey(i int) string {
+	if p.trash != nil && i < len(p.trash.items) {
+		item := p.trash.items[i]
+		return filepath.Join(item.TrashDir, "files", item.Name)
+	}
+	return p.entries[i].Name
+}
+
+// isSelected reports whether the entry with the given key is marked
+func (p *panel) isSelected(key string) bool {
+	return p.selected[key]
+}
+
+// setSelected marks or unmarks the entry with the given key
+func (p *panel) setSelected(key string, on bool) {
+	if !on {
+		delete(p.selected, key)
+		return
+	}
+	if p.selected == nil {
+		p.selected = make(map[string]bool)
+	}
+	p.selected[key] = true
+}
+
+// toggleSelection flips the mark of the cursor entry and moves the cursor down
+func (p *panel) toggleSelection() {
+	if len(p.entries) == 0 {
+		return
+	}
+	key := p.entryKey(p.cursor)
+	p.setSelected(key, !p.isSelected(key))
+	for i := p.cursor + 1; i < len(p.entries); i++ {
+		if p.isVisible(p.entries[i]) {
+			p.cursor = i
+			break
+		}
+	}
+}
+
+// selectMatching marks or unmarks all visible entries matching the wildcard
+// pattern and returns how many entries were affected
+func (p *panel) selectMatching(pattern string, on bool) int {
+	count := 0
+	for i, entry := range p.entries {
+		if p.isVisible(entry) && fs.MatchWildcard(pattern, entry.Name) && p.isSelected(p.entryKey(i)) != on {
+			p.setSelected(p.entryKey(i), on)
+			count++
+		}
+	}
+	return count
+}
+
+// invertSelection flips the mark of every visible entry
+func (p *panel) invertSelection() {
+	for i, entry := range p.entries {
+		if p.isVisible(entry) {
+			key := p.entryKey(i)
+			p.setSelected(key, !p.isSelected(key))
+		}
+	}
+}
+
+// clearSelection unmarks all entries
+func (p *panel) clearSelection() {
+	p.selected = nil
+}
+
+// pruneSelection drops marks of entries that no longer exist
+func (p *panel) pruneSelection() {
+	if len(p.selected) == 0 {
+		return
+	}
+	present := make(map[string]bool, len(p.entries))
+	for i := range p.entries {
+		present[p.entryKey(i)] = true
+	}
+	for key := range p.selected {
+		if !present[key] {
+			delete(p.selected, key)
+		}
+	}
+}
+
+// selectedEntries returns the marked entries in listing order
+func (p *panel) selectedEntries() []fs.FileEntry {
+	var entries []fs.FileEntry
+	for i, entry := range p.entries {
+		if p.isSelected(p.entryKey(i)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// operationTargets returns the marked entries, or the cursor entry if
+// nothing is marked
+func (p *panel) operationTargets() []fs.FileEntry {
+	if entries := p.selectedEntries(); len(entries) > 0 {
+		return entries
+	}
+	if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+		return nil
+	}
+	return []fs.FileEntry{p.entries[p.cursor]}
+}
+
+// selectionSummary describes the marked entries for the panel footer
+func (p *panel) selectionSummary() string {
+	entries := p.selectedEntries()
+	if len(entries) == 0 {
+		return ""
+	}
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir {
+			total += entry.Size
+		}
+	}
+	return fmt.Sprintf(" Selected: %d, %s", len(entries), formatSize(total))
+}
+
+// formatSize renders a byte count in human-readable units
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// openSelectPrompt asks for a wildcard pattern to mark (on) or unmark entries
+func (m *model) openSelectPrompt(on bool) {
+	label, verb := "Select:", "Selected"
+	if !on {
+		label, verb = "Unselect:", "Unselected"
+	}
+	m.prompt = newPrompt(label, "*", func(m *model, pattern string) tea.Cmd {
+		if pattern == "" {
+			return nil
+		}
+		count := m.panels[m.activePanel].selectMatching(pattern, on)
+		m.statusMsg = fmt.Sprintf("%s %d entries", verb, count)
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func selectionTestPanel() panel {
+	return panel{
+		path: "/test",
+		entries: []fs.FileEntry{
+			{Name: "dir", IsDir: true},
+			{Name: ".hidden", Size: 1},
+			{Name: "a.txt", Size: 100},
+			{Name: "b.txt", Size: 200},
+			{Name: "c.go", Size: 300},
+		},
+	}
+}
+
+func TestPanel_ToggleSelection(t *testing.T) {
+	p := selectionTestPanel()
+	p.cursor = 0
+
+	p.toggleSelection()
+	if !p.isSelected("dir") {
+		t.Error("dir should be selected")
+	}
+	// The cursor skips the hidden entry
+	if p.entries[p.cursor].Name != "a.txt" {
+		t.Errorf("Expected cursor on a.txt, got %s", p.entries[p.cursor].Name)
+	}
+
+	p.cursor = 0
+	p.toggleSelection()
+	if p.isSelected("dir") {
+		t.Error("dir should be unselected after second toggle")
+	}
+}
+
+func TestPanel_SelectMatching(t *testing.T) {
+	p := selectionTestPanel()
+
+	if count := p.selectMatching("*.TXT", true); count != 2 {
+		t.Errorf("Expected 2 entries selected, got %d", count)
+	}
+	if count := p.selectMatching("*", true); count != 2 {
+		t.Errorf("Expected 2 more visible entries selected, got %d", count)
+	}
+	if p.isSelected(".hidden") {
+		t.Error("Hidden entries should not be selected while hidden")
+	}
+	if count := p.selectMatching("a*", false); count != 1 {
+		t.Errorf("Expected 1 entry unselected, got %d", count)
+	}
+}
+
+func TestPanel_InvertSelection(t *testing.T) {
+	p := selectionTestPanel()
+	p.setSelected("a.txt", true)
+
+	p.invertSelection()
+
+	var names []string
+	for _, entry := range p.selectedEntries() {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "dir,b.txt,c.go" {
+		t.Errorf("Unexpected selection after invert: %v", names)
+	}
+}
+
+func TestPanel_SelectionSummaryAndTargets(t *testing.T) {
+	p := selectionTestPanel()
+	p.cursor = 4
+
+	if targets := p.operationTargets(); len(targets) != 1 || targets[0].Name != "c.go" {
+		t.Errorf("Without selection the cursor entry should be the target, got %v", targets)
+	}
+	if p.selectionSummary() != "" {
+		t.Error("Summary should be empty without selection")
+	}
+
+	p.setSelected("dir", true)
+	p.setSelected("a.txt", true)
+	p.setSelected("b.txt", true)
+	if targets := p.operationTargets(); len(targets) != 3 {
+		t.Errorf("Expected 3 targets, got %d", len(targets))
+	}
+	if summary := p.selectionSummary(); summary != " Selected: 3, 300 B" {
+		t.Errorf("Unexpected summary: %q", summary)
+	}
+}
+
+func TestPanel_PruneSelection(t *testing.T) {
+	p := selectionTestPanel()
+	p.setSelected("a.txt", true)
+	p.setSelected("gone.txt", true)
+
+	p.pruneSelection()
+
+	if !p.isSelected("a.txt") || p.isSelected("gone.txt") {
+		t.Errorf("Unexpected selection after prune: %v", p.selected)
+	}
+}
+
+func numberedEntries(names ...string) []fs.FileEntry {
+	var entries []fs.FileEntry
+	for _, name := range names {
+		entries = append(entries, fs.FileEntry{Name: name})
+	}
+	return entries
+}
+
+func TestPanel_SetEntries(t *testing.T) {
+	var names []string
+	for i := 10; i < 40; i++ {
+		names = append(names, fmt.Sprintf("f%d", i))
+	}
+	p := panel{entries: numberedEntries(names...), cursor: 20, viewportOffset: 15}
+
+	// Five new entries before the cursor entry f30
+	p.setEntries(numberedEntries(append([]string{"a", "b", "c", "d", "e"}, names...)...), true)
+	if p.entries[p.cursor].Name != "f30" {
+		t.Errorf("Expected cursor on f30, got %s", p.entries[p.cursor].Name)
+	}
+	if p.viewportOffset != 20 {
+		t.Errorf("Expected the cursor to stay in row 5 with offset 20, got %d", p.viewportOffset)
+	}
+
+	// A removed cursor entry keeps the index
+	p.setEntries(numberedEntries("a", "b", "c"), true)
+	p.cursor = 1
+	p.setEntries(numberedEntries("a", "c"), true)
+	if p.entries[p.cursor].Name != "c" {
+		t.Errorf("Expected cursor on the following entry c, got %s", p.entries[p.cursor].Name)
+	}
+
+	// selectName wins, a new listing without keep starts from the index
+	p.selectName = "a"
+	p.setEntries(numberedEntries("c", "b", "a"), false)
+	if p.entries[p.cursor].Name != "a" || p.selectName != "" {
+		t.Errorf("Expected cursor on a, got %s", p.entries[p.cursor].Name)
+	}
+}
+
+func TestBackspace_SelectsLeftDirectory(t *testing.T) {
+	parent := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.Mkdir(filepath.Join(parent, name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	m := initialModel()
+	m.panels[0].path = filepath.Join(parent, "c")
+	m.panels[0].cursor = 0
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = updated.(model)
+	updated, _ = m.Update(cmd())
+	m = updated.(model)
+
+	p := &m.panels[0]
+	if p.path != parent {
+		t.Fatalf("Expected panel in %s, got %s", parent, p.path)
+	}
+	if p.entries[p.cursor].Name != "c" {
+		t.Errorf("Expected cursor on the directory left, got %s", p.entries[p.cursor].Name)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for size, expected := range testCases {
+		if got := formatSize(size); got != expected {
+			t.Errorf("formatSize(%d) = %q, expected %q", size, got, expected)
+		}
+	}
+}
+
+func TestHandleFileOperation_CopiesSelection(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+	for _, name := range []string{"one.txt", "two.txt", "three.txt"} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	m := initialModel()
+	m.panels[0].path = srcDir
+	m.panels[0].entries, _ = fs.ReadDir(srcDir)
+	m.panels[1].path = dstDir
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyInsert})
+	updated, _ = updated.(model).Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m = updated.(model)
+	if len(m.panels[0].selectedEntries()) != 2 {
+		t.Fatalf("Expected 2 selected entries, got %d", len(m.panels[0].selectedEntries()))
+	}
+
+	m.handleFileOperation("copy")
+	m, cmd := acceptDestination(t, m)
+	if len(m.panels[0].selected) != 0 {
+		t.Error("Selection should be cleared after dispatching the operation")
+	}
+	m, msg := finishOperation(t, m, cmd)
+	if msg.err != nil {
+		t.Fatalf("Copy failed: %v", msg.err)
+	}
+	if msg.entryName != "2 entries" {
+		t.Errorf("Expected entryName '2 entries', got %q", msg.entryName)
+	}
+
+	copied, _ := fs.ReadDir(dstDir)
+	if len(copied) != 2 {
+		t.Errorf("Expected 2 copied files, got %d", len(copied))
+	}
+}
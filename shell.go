@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// shellInits are the wrapper functions printed by --init. The wrapper runs
+// min-commander with --print-last-dir and changes to the directory it wrote,
+// so quitting leaves the shell where the active panel was.
+var shellInits = map[string]string{
+	"bash": posixInit,
+	"zsh":  posixInit,
+	"fish": fishInit,
+}
+
+// posixInit is for bash and zsh. It avoids $status, which is read-only in zsh.
+const posixInit = `# Min Commander: change to the active panel's directory on exit.
+# Add to your shell's rc file: eval "$(min-commander --init SHELL)"
+min-commander() {
+    local last_dir_file last_dir ret
+    last_dir_file=$(mktemp -t min-commander.XXXXXX) || return
+    command min-commander --print-last-dir "$last_dir_file" "$@"
+    ret=$?
+    last_dir=$(cat -- "$last_dir_file" 2>/dev/null)
+    rm -f -- "$last_dir_file"
+    if [ -n "$last_dir" ] && [ -d "$last_dir" ] && [ "$last_dir" != "$PWD" ]; then
+        cd -- "$last_dir" || return
+    fi
+    return $ret
+}
+`
+
+const fishInit = `# Min Commander: change to the active panel's directory on exit.
+# Add to ~/.config/fish/config.fish: min-commander --init fish | source
+function min-commander --description 'Min Commander, changing to its last directory on exit'
+    set -l last_dir_file (mktemp -t min-commander.XXXXXX); or return
+    command min-commander --print-last-dir $last_dir_file $argv
+    set -l ret $status
+    set -l last_dir (cat -- $last_dir_file 2>/dev/null)
+    rm -f -- $last_dir_file
+    if test -n "$last_dir" -a -d "$last_dir" -a "$last_dir" != "$PWD"
+        cd $last_dir
+    end
+    return $ret
+end
+`
+
+// shellInit returns the wrapper function for shell
+func shellInit(shell string) (string, error) {
+	script, ok := shellInits[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %q, expected one of %s", shell, strings.Join(shellNames(), ", "))
+	}
+	return strings.ReplaceAll(script, "SHELL", shell), nil
+}
+
+func shellNames() []string {
+	var names []string
+	for name := range shellInits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
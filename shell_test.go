@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellInit(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		script, err := shellInit(shell)
+		if err != nil {
+			t.Fatalf("shellInit(%s): %v", shell, err)
+		}
+		if !strings.Contains(script, "command min-commander --print-last-dir") {
+			t.Errorf("Expected %s wrapper to pass --print-last-dir, got:\n%s", shell, script)
+		}
+		if strings.Contains(script, "SHELL") {
+			t.Errorf("Expected the shell name in the %s wrapper, got:\n%s", shell, script)
+		}
+	}
+	if _, err := shellInit("tcsh"); err == nil || !strings.Contains(err.Error(), "bash, fish, zsh") {
+		t.Errorf("Expected an error listing the shells, got %v", err)
+	}
+	if _, err := parseArgs([]string{"--init", "csh"}, os.Stderr); err == nil {
+		t.Error("Expected --init to reject an unknown shell")
+	}
+}
+
+// The bash wrapper runs a stub min-commander and changes to the directory it
+// writes, keeping its exit status
+func TestShellInit_Bash(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	bin, target := t.TempDir(), t.TempDir()
+	stub := `#!/bin/sh
+[ "$1" = --print-last-dir ] || exit 3
+printf '%s\n' "$TARGET" > "$2"
+exit "$STUB_STATUS"
+`
+	if err := os.WriteFile(filepath.Join(bin, "min-commander"), []byte(stub), 0755); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	script, err := shellInit("bash")
+	if err != nil {
+		t.Fatalf("Failed to get the bash wrapper: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status string
+		want   string
+	}{
+		{"Changes directory", target, "0", target + " 0"},
+		{"Keeps exit status", target, "1", target + " 1"},
+		{"Missing directory", filepath.Join(target, "gone"), "0", "/ 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(bash, "--norc", "-c", script+`cd /; min-commander arg; echo "$PWD $?"`)
+			cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"), "TARGET="+tt.target, "STUB_STATUS="+tt.status)
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash failed: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRun_InitUnsupportedShell(t *testing.T) {
+	if code := run([]string{"--init", "tcsh"}); code == 0 {
+		t.Error("Expected a failing exit code for an unsupported shell")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// describeSort summarizes sort options for status messages
+func describeSort(opts fs.SortOptions) string {
+	s := opts.Mode.String()
+	if opts.Descending {
+		s += ", descending"
+	}
+	if opts.MixDirs {
+		s += ", directories mixed"
+	}
+	return s
+}
+
+// changeSort applies change to the sort options of the active panel and
+// re-sorts its listing, keeping the cursor on the same entry. The options
+// stay with the panel when it changes directory.
+func (m *model) changeSort(change func(opts *fs.SortOptions)) tea.Cmd {
+	p := &m.panels[m.activePanel]
+	change(&p.sort)
+	m.statusMsg = fmt.Sprintf("Sort: %s", describeSort(p.sort))
+	if p.isVirtual() || len(p.entries) == 0 {
+		return nil
+	}
+
+	if p.sort.Mode == fs.SortUnsorted {
+		// The file system order is gone, read it again
+		return m.refreshCmd(m.activePanel)
+	}
+	entries := append([]fs.FileEntry(nil), p.entries...)
+	fs.SortEntries(entries, p.sort)
+	p.setEntries(entries, true)
+	return nil
+}
+
+// indexOf returns the index of the entry called name, or 0
+func (p *panel) indexOf(name string) int {
+	i, _ := p.lookup(name)
+	return i
+}
+
+// lookup returns the index of the entry called name and whether it exists
+func (p *panel) lookup(name string) (int, bool) {
+	for i, entry := range p.entries {
+		if entry.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
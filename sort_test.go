@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestChangeSort_KeepsCursorEntry(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old.txt", "new.txt", "mid.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		mtime := now.Add(time.Duration(i) * time.Hour)
+		if name == "new.txt" {
+			mtime = now.Add(10 * time.Hour)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+
+	m := initialModel()
+	m.panels[0].path = dir
+	m.panels[0].entries, _ = fs.ReadDir(dir)
+	m.panels[0].cursor = m.panels[0].indexOf("old.txt")
+
+	// name -> natural -> extension -> size -> time
+	for i := 0; i < 4; i++ {
+		m, _ = pressKey(m, "s")
+	}
+	m, _ = pressKey(m, "S")
+	p := &m.panels[0]
+	if p.sort.Mode != fs.SortModTime || !p.sort.Descending {
+		t.Fatalf("Expected descending time sort, got %+v", p.sort)
+	}
+	if p.entries[0].Name != "new.txt" {
+		t.Errorf("Newest file should come first, got %s", p.entries[0].Name)
+	}
+	if p.entries[p.cursor].Name != "old.txt" {
+		t.Errorf("Cursor should stay on old.txt, got %s", p.entries[p.cursor].Name)
+	}
+
+	// The order is remembered for the next directory read
+	msg := m.readDirCmd(0)().(readDirMsg)
+	if msg.entries[0].Name != "new.txt" {
+		t.Errorf("Reading the directory again should keep the sort, got %s first", msg.entries[0].Name)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme holds the colours of the interface. A colour is "#RGB", "#RRGGBB",
+// an ANSI colour number or name, or "" for the terminal default.
+type theme struct {
+	border       string
+	activeBorder string
+	cursorFg     string
+	cursorBg     string
+	marked       string
+	directory    string
+	executable   string
+	symlink      string
+	brokenLink   string
+	status       string
+}
+
+// themes are the built-in themes by name
+var themes = map[string]theme{
+	"default": {
+		border:       "#FFFFFF",
+		activeBorder: "#FFFF00",
+		cursorFg:     "#000000",
+		cursorBg:     "#00AAAA",
+		marked:       "#FFFF00",
+		brokenLink:   "#FF5555",
+		status:       "#00FF00",
+	},
+	// No colours at all, the cursor is shown in reverse video
+	"mono": {},
+}
+
+// themeFields maps the names used in the configuration to the theme fields
+var themeFields = map[string]func(t *theme) *string{
+	"border":        func(t *theme) *string { return &t.border },
+	"active_border": func(t *theme) *string { return &t.activeBorder },
+	"cursor_fg":     func(t *theme) *string { return &t.cursorFg },
+	"cursor_bg":     func(t *theme) *string { return &t.cursorBg },
+	"marked":        func(t *theme) *string { return &t.marked },
+	"directory":     func(t *theme) *string { return &t.directory },
+	"executable":    func(t *theme) *string { return &t.executable },
+	"symlink":       func(t *theme) *string { return &t.symlink },
+	"broken_link":   func(t *theme) *string { return &t.brokenLink },
+	"status":        func(t *theme) *string { return &t.status },
+}
+
+// ansiColors are the names of the 16 basic terminal colours
+var ansiColors = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright_black", "bright_red", "bright_green", "bright_yellow",
+	"bright_blue", "bright_magenta", "bright_cyan", "bright_white",
+}
+
+// parseColor validates a colour and returns it in the form lipgloss takes
+func parseColor(value string) (string, error) {
+	switch {
+	case value == "":
+		return "", nil
+	case strings.HasPrefix(value, "#"):
+		if len(value) != 4 && len(value) != 7 {
+			return "", fmt.Errorf("invalid colour %q, expected #RGB or #RRGGBB", value)
+		}
+		if _, err := strconv.ParseUint(value[1:], 16, 32); err != nil {
+			return "", fmt.Errorf("invalid colour %q, expected #RGB or #RRGGBB", value)
+		}
+		return value, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 || n > 255 {
+			return "", fmt.Errorf("colour number %d out of range 0-255", n)
+		}
+		return value, nil
+	}
+	for i, name := range ansiColors {
+		if strings.EqualFold(strings.ReplaceAll(value, "-", "_"), name) {
+			return strconv.Itoa(i), nil
+		}
+	}
+	return "", fmt.Errorf("unknown colour %q", value)
+}
+
+// Styles of entries by type, set by applyTheme
+var (
+	directoryStyle  = lipgloss.NewStyle()
+	executableStyle = lipgloss.NewStyle()
+	symlinkStyle    = lipgloss.NewStyle()
+	statusStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00"))
+)
+
+// foreground returns a style with colour c, or no colour for ""
+func foreground(c string) lipgloss.Style {
+	if c == "" {
+		return lipgloss.NewStyle()
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(c))
+}
+
+// applyTheme sets the styles of the interface from t
+func applyTheme(t theme) {
+	borderColor := func(s lipgloss.Style, c string) lipgloss.Style {
+		if c == "" {
+			return s.UnsetBorderForeground()
+		}
+		return s.BorderForeground(lipgloss.Color(c))
+	}
+	panelStyle = borderColor(panelStyle, t.border)
+	activePanelStyle = borderColor(activePanelStyle, t.activeBorder)
+
+	selectedStyle = lipgloss.NewStyle()
+	if t.cursorFg == "" && t.cursorBg == "" {
+		selectedStyle = selectedStyle.Reverse(true)
+	}
+	if t.cursorFg != "" {
+		selectedStyle = selectedStyle.Foreground(lipgloss.Color(t.cursorFg))
+	}
+	if t.cursorBg != "" {
+		selectedStyle = selectedStyle.Background(lipgloss.Color(t.cursorBg))
+	}
+
+	markedStyle = foreground(t.marked).Bold(true)
+	brokenLinkStyle = foreground(t.brokenLink)
+	directoryStyle = foreground(t.directory)
+	executableStyle = foreground(t.executable)
+	symlinkStyle = foreground(t.symlink)
+	statusStyle = foreground(t.status)
+}
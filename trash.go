@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// trashView is the trash listing shown in a panel instead of its directory.
+// The panel entries are named after the original paths of the items.
+type trashView struct {
+	items []fs.TrashItem
+}
+
+// trashListMsg is sent when the trash has been read for the panel at index
+type trashListMsg struct {
+	index int
+	items []fs.TrashItem
+	err   error
+}
+
+func listTrashCmd(index int) tea.Cmd {
+	return func() tea.Msg {
+		items, err := fs.ListTrash()
+		return trashListMsg{index: index, items: items, err: err}
+	}
+}
+
+// openTrash shows the trash in the active panel
+func (m *model) openTrash() tea.Cmd {
+	p := &m.panels[m.activePanel]
+	p.closeSearch()
+	p.trash = &trashView{}
+	p.entries = nil
+	p.cursor = 0
+	p.viewportOffset = 0
+	p.clearSelection()
+	p.filter = nil
+	return listTrashCmd(m.activePanel)
+}
+
+// closeTrash leaves the trash view and reloads the panel directory
+func (m *model) closeTrash() tea.Cmd {
+	p := &m.panels[m.activePanel]
+	p.trash = nil
+	p.cursor = 0
+	p.clearSelection()
+	p.filter = nil
+	return m.readDirCmd(m.activePanel)
+}
+
+// handleTrashList fills the trash view of a panel
+func (m *model) handleTrashList(msg trashListMsg) {
+	p := &m.panels[msg.index]
+	if p.trash == nil {
+		return
+	}
+	if msg.err != nil {
+		m.statusMsg = fmt.Sprintf("Cannot read trash: %v", msg.err)
+		return
+	}
+	key := ""
+	if p.cursor < len(p.trash.items) {
+		key = p.entryKey(p.cursor)
+	}
+	p.trash.items = msg.items
+	entries := make([]fs.FileEntry, len(msg.items))
+	for i, item := range msg.items {
+		entries[i] = fs.FileEntry{Name: item.OriginalPath, IsDir: item.IsDir, Size: item.Size}
+	}
+	p.setEntries(entries, true)
+	// Items trashed from the same path share their name, the cursor stays on
+	// the same item in the trash
+	for i := range p.trash.items {
+		if p.entryKey(i) == key {
+			p.cursor = i
+			p.ensureCursorVisible()
+			break
+		}
+	}
+}
+
+// updateTrash handles the keys that behave differently in the trash view.
+// It reports false for keys that work as in a normal panel.
+func (m *model) updateTrash(msg tea.KeyMsg) (bool, tea.Cmd) {
+	// The restore keys work in addition to the open key
+	if m.keys.lookupIn(scopeTrash, msg.String()) == actionRestore {
+		return true, m.trashAction("restore")
+	}
+	switch m.keys.lookup(msg.String()) {
+	case actionOpen:
+		return true, m.trashAction("restore")
+	case actionTrash, actionDelete:
+		items := m.trashTargets()
+		if len(items) == 0 {
+			m.noTrashTargets()
+			return true, nil
+		}
+		m.dialog = newConfirmDialog("Delete permanently", fmt.Sprintf("Permanently delete %s from trash?", trashItemsName(items)), func(m *model) tea.Cmd {
+			return m.trashAction("purge")
+		})
+		return true, nil
+	case actionCancel, actionParent, actionTrashView:
+		return true, m.closeTrash()
+	case actionCopy, actionMove, actionView, actionSearch, actionEdit, actionEditNew,
+		actionMkdir, actionNewFile, actionRename, actionBatchRename, actionUndoRename:
+		m.statusMsg = "Not available in the trash, restore the entry first"
+		return true, nil
+	}
+	return false, nil
+}
+
+// trashTargets returns the marked trash items, or the one under the cursor
+// unless the quick filter hides it
+func (m *model) trashTargets() []fs.TrashItem {
+	p := &m.panels[m.activePanel]
+	if len(p.selected) == 0 {
+		if len(p.entries) == 0 || !p.isVisible(p.entries[p.cursor]) {
+			return nil
+		}
+		return []fs.TrashItem{p.trash.items[p.cursor]}
+	}
+	var items []fs.TrashItem
+	for i, item := range p.trash.items {
+		if p.isSelected(p.entryKey(i)) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// noTrashTargets reports why there is nothing to restore or purge
+func (m *model) noTrashTargets() {
+	if len(m.panels[m.activePanel].entries) == 0 {
+		m.statusMsg = "Trash is empty"
+	} else {
+		m.statusMsg = "No file selected"
+	}
+}
+
+func trashItemsName(items []fs.TrashItem) string {
+	if len(items) == 1 {
+		return items[0].OriginalPath
+	}
+	return fmt.Sprintf("%d entries", len(items))
+}
+
+// trashAction restores or purges the trash targets asynchronously
+func (m *model) trashAction(op string) tea.Cmd {
+	items := m.trashTargets()
+	if len(items) == 0 {
+		m.noTrashTargets()
+		return nil
+	}
+	name := trashItemsName(items)
+	m.panels[m.activePanel].clearSelection()
+
+	return func() tea.Msg {
+		var errs []error
+		for _, item := range items {
+			var err error
+			if op == "restore" {
+				err = fs.RestoreFromTrash(item)
+			} else {
+				err = fs.PurgeFromTrash(item)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", item.OriginalPath, err))
+			}
+		}
+		return fileOpResultMsg{op: op, entryName: name, err: errors.Join(errs...)}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestTrash_DeleteRestoreWorkflow(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, _ := fileOpsTestModel(t, "keep.txt")
+	path := filepath.Join(m.panels[0].path, "keep.txt")
+
+	m, _ = pressKey(m, "d")
+	if m.dialog == nil {
+		t.Fatal("Moving to trash should ask for confirmation")
+	}
+	m, cmd := pressKey(m, "y")
+	m, msg := finishOperation(t, m, cmd)
+	if msg.err != nil {
+		t.Fatalf("Move to trash failed: %v", msg.err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("File should have been moved to the trash")
+	}
+
+	m, cmd = pressKey(m, "t")
+	if m.panels[0].trash == nil {
+		t.Fatal("t should open the trash view")
+	}
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	if len(m.panels[0].entries) != 1 || m.panels[0].entries[0].Name != path {
+		t.Fatalf("Trash view should list the original path, got %v", m.panels[0].entries)
+	}
+
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, msg = finishOperation(t, updated.(model), cmd)
+	if msg.err != nil {
+		t.Fatalf("Restore failed: %v", msg.err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("File should be restored: %v", err)
+	}
+
+	// Operations that need a real directory are refused in the trash view
+	m, cmd = pressKey(m, "c")
+	if cmd != nil || m.dialog != nil {
+		t.Error("Copy should not be available in the trash view")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if updated.(model).panels[0].trash != nil {
+		t.Error("Esc should leave the trash view")
+	}
+}
+
+func TestTrash_MarksSameOriginalPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, _ := fileOpsTestModel(t)
+	path := filepath.Join(m.panels[0].path, "twice.txt")
+	for _, data := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := fs.MoveToTrash(path); err != nil {
+			t.Fatalf("Failed to move to trash: %v", err)
+		}
+	}
+
+	m, cmd := pressKey(m, "t")
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	if len(m.panels[0].entries) != 2 {
+		t.Fatalf("Expected both items in the trash view, got %v", m.panels[0].entries)
+	}
+
+	// Marking one item leaves the other with the same original path alone
+	m, _ = pressKey(m, " ")
+	if got := m.panels[0].selectedEntries(); len(got) != 1 {
+		t.Fatalf("Expected one marked item, got %v", got)
+	}
+	kept := m.panels[0].trash.items[1]
+	m, _ = pressKey(m, "d")
+	m, cmd = pressKey(m, "y")
+	if _, msg := finishOperation(t, m, cmd); msg.err != nil {
+		t.Fatalf("Purge failed: %v", msg.err)
+	}
+	items, err := fs.ListTrash()
+	if err != nil || len(items) != 1 || items[0].Name != kept.Name {
+		t.Errorf("Expected only %s left in the trash, got %v, %v", kept.Name, items, err)
+	}
+}
+
+func TestTrash_FilteredCursorIsNoTarget(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, _ := fileOpsTestModel(t, "hidden-by-filter.txt")
+	if err := fs.MoveToTrash(filepath.Join(m.panels[0].path, "hidden-by-filter.txt")); err != nil {
+		t.Fatalf("Failed to move to trash: %v", err)
+	}
+	m, cmd := pressKey(m, "t")
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+
+	// Nothing matches the filter, so the cursor entry is out of sight
+	m = typeText(t, pressKeys(t, m, "ctrl+f"), "zzz")
+	m = pressKeys(t, m, "enter", "d")
+	if m.dialog != nil || m.statusMsg != "No file selected" {
+		t.Fatalf("Expected no purge of the filtered-out item, got %q", m.statusMsg)
+	}
+	if items, err := fs.ListTrash(); err != nil || len(items) != 1 {
+		t.Errorf("Expected the item kept in the trash, got %v, %v", items, err)
+	}
+}
+
+func TestTrash_CursorStaysOnItem(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	m, _ := fileOpsTestModel(t)
+	path := filepath.Join(m.panels[0].path, "twice.txt")
+	for _, data := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		if err := fs.MoveToTrash(path); err != nil {
+			t.Fatalf("Failed to move to trash: %v", err)
+		}
+	}
+
+	m, cmd := pressKey(m, "t")
+	updated, _ := m.Update(cmd())
+	m = updated.(model)
+	m, _ = pressKey(m, "down")
+	want := m.panels[0].trash.items[1].Name
+
+	// A reload finds the item by its name in the trash, not its original path
+	updated, _ = m.Update(listTrashCmd(0)())
+	m = updated.(model)
+	if p := m.panels[0]; p.cursor != 1 || p.trash.items[p.cursor].Name != want {
+		t.Errorf("Expected the cursor on %s, got %d", want, p.cursor)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+const (
+	hexBytesPerRow = 16
+	// maxViewerWindow caps how much is read for one screen of very long lines
+	maxViewerWindow = 1 << 20
+	tabWidth        = 4
+)
+
+var viewerHeaderStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#000000")).
+	Background(lipgloss.Color("#00AAAA"))
+
+// viewer is the full-screen file viewer. Only the visible window of the file
+// is held in memory; it is re-read from disk in a command whenever the viewer
+// scrolls, and the previous window stays on screen until it arrives.
+type viewer struct {
+	path      string
+	size      int64
+	hex       bool
+	offsets   []int64 // line start offsets, text mode only
+	top       int     // first visible line (text) or row (hex)
+	left      int     // horizontal scroll in text mode
+	height    int
+	lines     []string
+	truncated bool // The window was cut at maxViewerWindow
+	err       error
+	keys      keymap
+	seq       int // Number of the latest window requested
+}
+
+// viewerHints are the keys listed at the bottom of the viewer
+var viewerHints = []keyHint{
+	{[]action{actionUp, actionDown}, "Scroll"},
+	{[]action{actionPageUp, actionPageDown}, "Page"},
+	{[]action{actionTop, actionBottom}, "Top/Bottom"},
+	{[]action{actionScrollLeft, actionScrollRight}, "Pan"},
+	{[]action{actionClose}, "Close"},
+}
+
+// viewerOpenedMsg is sent when the file to view has been indexed
+type viewerOpenedMsg struct {
+	viewer *viewer
+	err    error
+}
+
+// openViewerCmd sniffs and indexes the file asynchronously
+func openViewerCmd(path string, size int64) tea.Cmd {
+	return func() tea.Msg {
+		binary, err := fs.IsBinary(path)
+		if err != nil {
+			return viewerOpenedMsg{err: err}
+		}
+		v := &viewer{path: path, size: size, hex: binary}
+		if !binary {
+			v.offsets, err = fs.LineOffsets(path)
+			if err != nil {
+				return viewerOpenedMsg{err: err}
+			}
+		}
+		return viewerOpenedMsg{viewer: v}
+	}
+}
+
+// viewerChunkMsg delivers a window of the viewer read from disk
+type viewerChunkMsg struct {
+	seq       int
+	lines     []string
+	truncated bool
+	err       error
+}
+
+// rows returns the number of scrollable rows in the current mode
+func (v *viewer) rows() int {
+	if v.hex {
+		return int((v.size + hexBytesPerRow - 1) / hexBytesPerRow)
+	}
+	return len(v.offsets)
+}
+
+// resize sets the number of visible rows and reloads the window
+func (v *viewer) resize(height int) tea.Cmd {
+	v.height = max(1, height)
+	return v.scrollTo(v.top)
+}
+
+// scrollTo moves the first visible row, clamped to the file, and reloads
+func (v *viewer) scrollTo(top int) tea.Cmd {
+	v.top = max(0, min(top, v.rows()-v.height))
+	return v.load()
+}
+
+// load reads the visible window from disk in a command. It reads from a copy
+// of the viewer, which keeps scrolling meanwhile.
+func (v *viewer) load() tea.Cmd {
+	v.seq++
+	w := *v
+	return func() tea.Msg {
+		msg := viewerChunkMsg{seq: w.seq}
+		if w.hex {
+			msg.lines, msg.err = w.loadHex()
+		} else {
+			msg.lines, msg.truncated, msg.err = w.loadText()
+		}
+		return msg
+	}
+}
+
+// setChunk shows a window read by load unless a later one has been requested
+func (v *viewer) setChunk(msg viewerChunkMsg) {
+	if msg.seq != v.seq {
+		return
+	}
+	v.lines, v.truncated, v.err = msg.lines, msg.truncated, msg.err
+}
+
+// loadText reads the lines of the window. It reports whether the window was
+// cut at maxViewerWindow, which leaves out the rest of its lines.
+func (v *viewer) loadText() ([]string, bool, error) {
+	if v.top >= len(v.offsets) {
+		return nil, false, nil
+	}
+	end := min(v.top+v.height, len(v.offsets))
+	start := v.offsets[v.top]
+	stop := v.size
+	if end < len(v.offsets) {
+		stop = v.offsets[end]
+	}
+	truncated := stop-start > maxViewerWindow
+	if truncated {
+		stop = start + maxViewerWindow
+	}
+	data, err := fs.ReadRange(v.path, start, int(stop-start))
+	if err != nil {
+		return nil, false, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = printable(strings.ReplaceAll(strings.TrimSuffix(line, "\r"), "\t", strings.Repeat(" ", tabWidth)))
+	}
+	return lines, truncated, nil
+}
+
+// printable shows control characters in caret notation like ^[ for escape,
+// so that a file cannot move the cursor or change the terminal, and C1
+// controls as ·
+func printable(line string) string {
+	if !strings.ContainsFunc(line, unicode.IsControl) {
+		return line
+	}
+	var s strings.Builder
+	for _, r := range line {
+		switch {
+		case r < 0x20:
+			s.WriteByte('^')
+			s.WriteRune(r + '@')
+		case r == 0x7f:
+			s.WriteString("^?")
+		case unicode.IsControl(r):
+			s.WriteRune('·')
+		default:
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+func (v *viewer) loadHex() ([]string, error) {
+	start := int64(v.top) * hexBytesPerRow
+	data, err := fs.ReadRange(v.path, start, v.height*hexBytesPerRow)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for i := 0; i < len(data); i += hexBytesPerRow {
+		lines = append(lines, hexDumpRow(start+int64(i), data[i:min(i+hexBytesPerRow, len(data))]))
+	}
+	return lines, nil
+}
+
+// hexDumpRow formats one row like `hexdump -C`
+func hexDumpRow(offset int64, row []byte) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%08x  ", offset)
+	for i := 0; i < hexBytesPerRow; i++ {
+		if i < len(row) {
+			fmt.Fprintf(&s, "%02x ", row[i])
+		} else {
+			s.WriteString("   ")
+		}
+		if i == hexBytesPerRow/2-1 {
+			s.WriteString(" ")
+		}
+	}
+	s.WriteString(" |")
+	for _, b := range row {
+		if b >= 0x20 && b < 0x7f {
+			s.WriteByte(b)
+		} else {
+			s.WriteByte('.')
+		}
+	}
+	s.WriteString("|")
+	return s.String()
+}
+
+// update handles key presses while the viewer is open. It reports false
+// once the viewer should be closed, and returns the command loading the
+// window after scrolling.
+func (v *viewer) update(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch v.keys.lookupIn(scopeViewer, msg.String()) {
+	case actionClose:
+		return false, nil
+	case actionUp:
+		return true, v.scrollTo(v.top - 1)
+	case actionDown:
+		return true, v.scrollTo(v.top + 1)
+	case actionPageUp:
+		return true, v.scrollTo(v.top - v.height)
+	case actionPageDown:
+		return true, v.scrollTo(v.top + v.height)
+	case actionTop:
+		return true, v.scrollTo(0)
+	case actionBottom:
+		return true, v.scrollTo(v.rows())
+	case actionScrollLeft:
+		if !v.hex {
+			v.left = max(0, v.left-tabWidth)
+		}
+	case actionScrollRight:
+		if !v.hex {
+			v.left += tabWidth
+		}
+	}
+	return true, nil
+}
+
+// view renders the viewer over the whole terminal
+func (v *viewer) view(width int) string {
+	mode := "Text"
+	if v.hex {
+		mode = "Hex"
+	}
+	percent := 100
+	if rows := v.rows(); rows > v.height {
+		percent = (v.top + v.height) * 100 / rows
+	}
+	header := fmt.Sprintf(" %s  [%s]  %d bytes  %d%%", filepath.Base(v.path), mode, v.size, min(percent, 100))
+
+	var s strings.Builder
+	s.WriteString(viewerHeaderStyle.Width(max(width, lipgloss.Width(header))).Render(header))
+	s.WriteString("\n")
+	if v.err != nil {
+		s.WriteString(fmt.Sprintf("Error: %v\n", v.err))
+	}
+	for i := 0; i < v.height; i++ {
+		switch {
+		case i < len(v.lines):
+			s.WriteString(v.clip(v.lines[i], width))
+		case i == len(v.lines) && v.truncated:
+			s.WriteString(columnHeaderStyle.Render(fmt.Sprintf("… cut after %d MiB, scroll on for the next lines", maxViewerWindow>>20)))
+		}
+		s.WriteString("\n")
+	}
+	s.WriteString(" " + v.keys.hints(scopeViewer, viewerHints))
+	return s.String()
+}
+
+// clip applies the horizontal scroll offset and cuts the line to width,
+// both counted in terminal cells so that wide characters keep the columns
+// in place
+func (v *viewer) clip(line string, width int) string {
+	if width <= 0 {
+		return ansi.TruncateLeft(line, v.left, "")
+	}
+	return ansi.Cut(line, v.left, v.left+width)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// openTestViewer runs openViewerCmd synchronously
+func openTestViewer(t *testing.T, path string) *viewer {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	msg, ok := openViewerCmd(path, info.Size())().(viewerOpenedMsg)
+	if !ok {
+		t.Fatal("Expected viewerOpenedMsg")
+	}
+	if msg.err != nil {
+		t.Fatalf("openViewerCmd failed: %v", msg.err)
+	}
+	return msg.viewer
+}
+
+// loadWindow runs the command loading the viewer window and shows the result
+func loadWindow(t *testing.T, v *viewer, cmd tea.Cmd) {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("Expected a command loading the window")
+	}
+	v.setChunk(cmd().(viewerChunkMsg))
+}
+
+// scroll presses key in the viewer and loads the window it moves to
+func scroll(t *testing.T, v *viewer, key tea.KeyMsg) {
+	t.Helper()
+	_, cmd := v.update(key)
+	loadWindow(t, v, cmd)
+}
+
+func TestViewer_TextMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "text.txt")
+	var content strings.Builder
+	for i := 0; i < 50; i++ {
+		content.WriteString("line ")
+		content.WriteString(string(rune('A' + i%26)))
+		content.WriteString("\n")
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	v := openTestViewer(t, path)
+	if v.hex {
+		t.Fatal("Text file should open in text mode")
+	}
+	loadWindow(t, v, v.resize(10))
+
+	if len(v.lines) != 10 || v.lines[0] != "line A" {
+		t.Fatalf("Unexpected first window: %q", v.lines)
+	}
+
+	scroll(t, v, tea.KeyMsg{Type: tea.KeyPgDown})
+	if v.top != 10 || v.lines[0] != "line K" {
+		t.Errorf("Expected window to start at line 10, got top=%d first=%q", v.top, v.lines[0])
+	}
+
+	scroll(t, v, tea.KeyMsg{Type: tea.KeyEnd})
+	if v.top != 40 {
+		t.Errorf("Expected last page to start at 40, got %d", v.top)
+	}
+
+	scroll(t, v, tea.KeyMsg{Type: tea.KeyHome})
+	if v.top != 0 {
+		t.Errorf("Expected top 0 after Home, got %d", v.top)
+	}
+}
+
+func TestViewer_LoadsInCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "text.txt")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	v := openTestViewer(t, path)
+	loadWindow(t, v, v.resize(2))
+
+	// Scrolling keeps the old window until its command has read the new one
+	_, first := v.update(tea.KeyMsg{Type: tea.KeyDown})
+	_, second := v.update(tea.KeyMsg{Type: tea.KeyDown})
+	if v.top != 2 || v.lines[0] != "a" {
+		t.Fatalf("Expected the old window while loading, got top=%d %q", v.top, v.lines)
+	}
+	stale := first()
+	loadWindow(t, v, second)
+	v.setChunk(stale.(viewerChunkMsg))
+	if v.lines[0] != "c" {
+		t.Errorf("Expected a stale window to be dropped, got %q", v.lines)
+	}
+}
+
+func TestViewer_TruncatedWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "long.txt")
+	content := strings.Repeat("x", maxViewerWindow+10) + "\nnext\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	v := openTestViewer(t, path)
+	loadWindow(t, v, v.resize(5))
+	if !v.truncated || len(v.lines) != 1 {
+		t.Fatalf("Expected the window cut in the long line, got %d lines", len(v.lines))
+	}
+	if !strings.Contains(v.view(80), "… cut after 1 MiB") {
+		t.Error("Expected a marker where the window was cut")
+	}
+}
+
+func TestViewer_ControlCharacters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "esc.txt")
+	if err := os.WriteFile(path, []byte("\x1b[2Jred\x7f\u009b1m\ttab\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	v := openTestViewer(t, path)
+	loadWindow(t, v, v.resize(5))
+	if want := "^[[2Jred^?·1m    tab"; len(v.lines) != 1 || v.lines[0] != want {
+		t.Errorf("Expected %q, got %q", want, v.lines)
+	}
+}
+
+func TestViewer_ClipCountsCells(t *testing.T) {
+	v := &viewer{}
+	line := "日本語abc"
+	if got := v.clip(line, 4); got != "日本" {
+		t.Errorf("Expected two wide characters in 4 cells, got %q", got)
+	}
+	v.left = 2
+	if got := v.clip(line, 5); got != "本語a" {
+		t.Errorf("Expected the scroll to skip one wide character, got %q", got)
+	}
+	v.left = 8
+	if got := v.clip(line, 5); got != "c" {
+		t.Errorf("Expected the end of the line, got %q", got)
+	}
+}
+
+func TestViewer_HexMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "data.bin")
+	if err := os.WriteFile(path, []byte("AB\x00CDEFGHIJKLMNOPQRS"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	v := openTestViewer(t, path)
+	if !v.hex {
+		t.Fatal("Binary file should open in hex mode")
+	}
+	loadWindow(t, v, v.resize(10))
+
+	if v.rows() != 2 || len(v.lines) != 2 {
+		t.Fatalf("Expected 2 hex rows, got rows=%d lines=%d", v.rows(), len(v.lines))
+	}
+	if !strings.HasPrefix(v.lines[0], "00000000  41 42 00 43") {
+		t.Errorf("Unexpected hex row: %q", v.lines[0])
+	}
+	if !strings.HasSuffix(v.lines[0], "|AB.CDEFGHIJKLMNO|") {
+		t.Errorf("Unexpected ASCII column: %q", v.lines[0])
+	}
+}
+
+func TestViewer_CloseKeys(t *testing.T) {
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyEsc},
+		{Type: tea.KeyRunes, Runes: []rune{'q'}},
+		{Type: tea.KeyF3},
+	} {
+		v := &viewer{}
+		if open, _ := v.update(key); open {
+			t.Errorf("Key %q should close the viewer", key.String())
+		}
+	}
+
+	// The close keys follow the configuration
+	v := &viewer{}
+	if err := v.keys.bind(scopeViewer, actionClose, []string{"x"}); err != nil {
+		t.Fatalf("Failed to bind: %v", err)
+	}
+	escOpen, _ := v.update(tea.KeyMsg{Type: tea.KeyEsc})
+	xOpen, _ := v.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	if !escOpen || xOpen {
+		t.Error("Expected x instead of Esc to close the viewer")
+	}
+}
+
+func TestHexDumpRow_ShortRow(t *testing.T) {
+	row := hexDumpRow(32, []byte("hi"))
+	if !strings.HasPrefix(row, "00000020  68 69 ") || !strings.HasSuffix(row, "|hi|") {
+		t.Errorf("Unexpected hex row: %q", row)
+	}
+}
+
+func TestModel_ViewFileOpensViewer(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "readme.txt"), []byte("hello viewer\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	m := initialModel()
+	m.panels[0].path = tmpDir
+	m.panels[0].entries, _ = fs.ReadDir(tmpDir)
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyF3})
+	if cmd == nil {
+		t.Fatal("F3 on a file should return a command")
+	}
+	updated, cmd = updated.(model).Update(cmd())
+	m = runCmd(updated.(model), cmd)
+	if m.viewer == nil {
+		t.Fatal("Viewer should be open")
+	}
+	if !strings.Contains(m.View(), "hello viewer") {
+		t.Error("View should render the file content")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if updated.(model).viewer != nil {
+		t.Error("Esc should close the viewer")
+	}
+}
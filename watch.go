@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/karstenflache/commander-1/fs"
+)
+
+// dirChangedMsg reports that a watched directory changed
+type dirChangedMsg struct {
+	path string
+}
+
+// waitForDirChangeCmd waits for the next change reported by the watcher
+func waitForDirChangeCmd(w *fs.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return dirChangedMsg{path: <-w.Changes}
+	}
+}
+
+// watchPanels points the watcher at the directories shown in the panels
+func (m *model) watchPanels() {
+	if m.watcher == nil {
+		return
+	}
+	var paths []string
+	for i := range m.panels {
+		if !m.panels[i].isVirtual() {
+			paths = append(paths, m.panels[i].path)
+		}
+	}
+	m.watcher.Watch(paths...)
+}
+
+// handleDirChanged refreshes the panels showing the changed directory
+func (m *model) handleDirChanged(msg dirChangedMsg) tea.Cmd {
+	cmds := []tea.Cmd{waitForDirChangeCmd(m.watcher)}
+	for i := range m.panels {
+		if !m.panels[i].isVirtual() && m.panels[i].path == msg.path {
+			cmds = append(cmds, m.refreshCmd(i))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleRefreshError leaves a directory that disappeared for its closest
+// existing parent. Other errors are only reported, the listing is kept.
+func (m *model) handleRefreshError(msg readDirMsg) tea.Cmd {
+	if !errors.Is(msg.err, os.ErrNotExist) {
+		m.statusMsg = fmt.Sprintf("Cannot refresh %s: %v", msg.path, msg.err)
+		return nil
+	}
+	m.statusMsg = fmt.Sprintf("%s was removed", msg.path)
+	return m.changeDir(msg.index, fs.ExistingDir(msg.path), "")
+}
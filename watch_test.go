@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karstenflache/commander-1/fs"
+)
+
+func TestDirChanged_KeepsCursorByName(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "b.txt", "c.txt", "d.txt")
+	p := &m.panels[0]
+	p.cursor = p.indexOf("c.txt")
+
+	// A new entry sorting before the cursor shifts the indices
+	if err := os.WriteFile(filepath.Join(p.path, "a.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	updated, cmd := m.Update(dirChangedMsg{path: p.path})
+	m = updated.(model)
+	if cmd == nil {
+		t.Fatal("Expected a refresh for the changed directory")
+	}
+	updated, _ = m.Update(m.refreshCmd(0)())
+	m = updated.(model)
+
+	p = &m.panels[0]
+	if len(p.entries) != 4 {
+		t.Fatalf("Expected 4 entries after the refresh, got %d", len(p.entries))
+	}
+	if name := p.entries[p.cursor].Name; name != "c.txt" {
+		t.Errorf("Expected cursor to stay on c.txt, got %s", name)
+	}
+}
+
+func TestDirChanged_OtherDirectory(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a.txt")
+	m.panels[1].path = t.TempDir()
+	_, cmd := m.Update(dirChangedMsg{path: "/somewhere/else"})
+	if cmd != nil {
+		t.Error("Expected no refresh for a directory no panel shows")
+	}
+}
+
+func TestReadDirMsg_StalePathIgnored(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a.txt")
+	msg := readDirCmd(0, "/old/path", fs.SortOptions{}, true)()
+	updated, _ := m.Update(msg)
+	m = updated.(model)
+	if m.err != nil || len(m.panels[0].entries) != 1 {
+		t.Error("Expected a listing of a directory the panel has left to be ignored")
+	}
+}
+
+func TestRefresh_RemovedDirectory(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "sub", "gone")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	m := initialModel()
+	m.panels[0].path = dir
+	if err := os.RemoveAll(filepath.Join(parent, "sub")); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+
+	updated, cmd := m.Update(m.refreshCmd(0)())
+	m = updated.(model)
+	if m.err != nil {
+		t.Fatalf("Expected no error screen, got %v", m.err)
+	}
+	if m.panels[0].path != parent {
+		t.Errorf("Expected panel to move to %s, got %s", parent, m.panels[0].path)
+	}
+	if cmd == nil {
+		t.Error("Expected the parent directory to be read")
+	}
+}
+
+func TestWatchPanels(t *testing.T) {
+	m, _ := fileOpsTestModel(t, "a.txt")
+	m.watcher = fs.NewWatcher(10 * time.Millisecond)
+	defer m.watcher.Close()
+
+	updated, _ := m.Update(m.readDirCmd(0)())
+	m = updated.(model)
+	watched := m.watcher.Watched()
+	if len(watched) != 2 {
+		t.Fatalf("Expected both panel directories to be watched, got %v", watched)
+	}
+
+	if err := os.WriteFile(filepath.Join(m.panels[0].path, "b.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	msg, ok := waitForDirChangeCmd(m.watcher)().(dirChangedMsg)
+	if !ok || msg.path != m.panels[0].path {
+		t.Errorf("Expected a change in %s, got %v", m.panels[0].path, msg)
+	}
+}